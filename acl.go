@@ -0,0 +1,514 @@
+package standalone
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/match"
+	"github.com/weedge/pkg/driver"
+	"github.com/weedge/pkg/utils"
+)
+
+const (
+	DefaultAclUserName = "default"
+	// all cmd category
+	AclCategoryAll = "all"
+
+	aclLogMaxLen = 128
+	// same acl log entry in the group time window is merged
+	aclLogGroupWindow = 60 * time.Second
+)
+
+const (
+	AclLogReasonCmd  = "command"
+	AclLogReasonKey  = "key"
+	AclLogReasonAuth = "auth"
+)
+
+// AclUser redis style acl user, more detail reference:
+// https://redis.io/docs/management/security/acl/
+// user is immutable after created, acl SetUser replace it with a new one
+type AclUser struct {
+	Name    string
+	Enabled bool
+	NoPass  bool
+	// password sha256 hex
+	Passwords []string
+	AllKeys   bool
+	// key glob-style patterns
+	KeyPatterns []string
+	// ordered cmd rules, e.g. +@all -@slot +slotsinfo;
+	// the last matched rule decides whether the cmd is allowed
+	CmdRules []string
+}
+
+func NewAclUser(name string) *AclUser {
+	return &AclUser{Name: name, CmdRules: []string{"-@" + AclCategoryAll}}
+}
+
+func (u *AclUser) clone() *AclUser {
+	nu := *u
+	nu.Passwords = append([]string{}, u.Passwords...)
+	nu.KeyPatterns = append([]string{}, u.KeyPatterns...)
+	nu.CmdRules = append([]string{}, u.CmdRules...)
+	return &nu
+}
+
+func aclPasswordHash(pwd string) string {
+	h := sha256.Sum256(utils.String2Bytes(pwd))
+	return hex.EncodeToString(h[:])
+}
+
+// CheckPassword check the password whether match user
+func (u *AclUser) CheckPassword(pwd string) bool {
+	if u.NoPass {
+		return true
+	}
+	hash := aclPasswordHash(pwd)
+	for _, p := range u.Passwords {
+		if p == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// CanRunCmd check the cmd whether can be run by user with cmd category
+func (u *AclUser) CanRunCmd(cmd, category string) (allowed bool) {
+	for _, rule := range u.CmdRules {
+		name := rule[1:]
+		matched := false
+		if strings.HasPrefix(name, "@") {
+			name = name[1:]
+			matched = name == AclCategoryAll || name == category
+		} else {
+			matched = name == cmd
+		}
+		if matched {
+			allowed = rule[0] == '+'
+		}
+	}
+	return
+}
+
+// CanAccessKey check the key whether can be accessed by user key patterns
+func (u *AclUser) CanAccessKey(key []byte) bool {
+	if u.AllKeys {
+		return true
+	}
+	for _, pattern := range u.KeyPatterns {
+		if match.Match(utils.Bytes2String(key), pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRule apply one acl rule to user
+func (u *AclUser) SetRule(rule string) (err error) {
+	lowRule := strings.ToLower(rule)
+	switch {
+	case lowRule == "on":
+		u.Enabled = true
+	case lowRule == "off":
+		u.Enabled = false
+	case lowRule == "nopass":
+		u.NoPass = true
+		u.Passwords = u.Passwords[:0]
+	case lowRule == "resetpass":
+		u.NoPass = false
+		u.Passwords = u.Passwords[:0]
+	case lowRule == "allkeys":
+		u.AllKeys = true
+		u.KeyPatterns = u.KeyPatterns[:0]
+	case lowRule == "resetkeys":
+		u.AllKeys = false
+		u.KeyPatterns = u.KeyPatterns[:0]
+	case lowRule == "allcommands":
+		u.CmdRules = []string{"+@" + AclCategoryAll}
+	case lowRule == "nocommands":
+		u.CmdRules = []string{"-@" + AclCategoryAll}
+	case lowRule == "reset":
+		*u = *NewAclUser(u.Name)
+	case rule[0] == '>':
+		u.addPasswordHash(aclPasswordHash(rule[1:]))
+	case rule[0] == '#':
+		if _, err = hex.DecodeString(rule[1:]); err != nil || len(rule[1:]) != sha256.Size*2 {
+			return fmt.Errorf("ERR Error in ACL SETUSER modifier '%s': The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters", rule)
+		}
+		u.addPasswordHash(strings.ToLower(rule[1:]))
+	case rule[0] == '<':
+		u.delPasswordHash(aclPasswordHash(rule[1:]))
+	case rule[0] == '!':
+		u.delPasswordHash(strings.ToLower(rule[1:]))
+	case rule[0] == '~':
+		if rule == "~*" {
+			u.AllKeys = true
+			u.KeyPatterns = u.KeyPatterns[:0]
+			break
+		}
+		if !u.AllKeys {
+			u.KeyPatterns = append(u.KeyPatterns, rule[1:])
+		}
+	case rule[0] == '+' || rule[0] == '-':
+		return u.setCmdRule(lowRule)
+	default:
+		return fmt.Errorf("ERR Error in ACL SETUSER modifier '%s': Syntax error", rule)
+	}
+
+	return nil
+}
+
+func (u *AclUser) setCmdRule(rule string) error {
+	name := rule[1:]
+	if strings.HasPrefix(name, "@") {
+		category := name[1:]
+		if category == AclCategoryAll {
+			u.CmdRules = []string{rule}
+			return nil
+		}
+		if _, ok := driver.RegisteredCmdSet[category]; !ok {
+			return fmt.Errorf("ERR Error in ACL SETUSER modifier '%s': Unknown command or category name in ACL", rule)
+		}
+	} else if _, ok := cmdCategory(name); !ok {
+		return fmt.Errorf("ERR Error in ACL SETUSER modifier '%s': Unknown command or category name in ACL", rule)
+	}
+
+	u.CmdRules = append(u.CmdRules, rule)
+	return nil
+}
+
+func (u *AclUser) addPasswordHash(hash string) {
+	u.NoPass = false
+	for _, p := range u.Passwords {
+		if p == hash {
+			return
+		}
+	}
+	u.Passwords = append(u.Passwords, hash)
+}
+
+func (u *AclUser) delPasswordHash(hash string) {
+	for i, p := range u.Passwords {
+		if p == hash {
+			u.Passwords = append(u.Passwords[:i], u.Passwords[i+1:]...)
+			return
+		}
+	}
+}
+
+// Flags user flags for ACL GETUSER
+func (u *AclUser) Flags() []string {
+	flags := []string{"off"}
+	if u.Enabled {
+		flags[0] = "on"
+	}
+	if u.NoPass {
+		flags = append(flags, "nopass")
+	}
+	return flags
+}
+
+// KeysRule user keys rule, e.g. ~* or ~obj:* ~tmp:*
+func (u *AclUser) KeysRule() string {
+	if u.AllKeys {
+		return "~*"
+	}
+	rules := make([]string, 0, len(u.KeyPatterns))
+	for _, pattern := range u.KeyPatterns {
+		rules = append(rules, "~"+pattern)
+	}
+	return strings.Join(rules, " ")
+}
+
+// String user acl rules description for ACL LIST and acl file
+func (u *AclUser) String() string {
+	rules := []string{"user", u.Name}
+	rules = append(rules, u.Flags()...)
+	for _, p := range u.Passwords {
+		rules = append(rules, "#"+p)
+	}
+	if keysRule := u.KeysRule(); len(keysRule) > 0 {
+		rules = append(rules, keysRule)
+	} else {
+		rules = append(rules, "resetkeys")
+	}
+	rules = append(rules, u.CmdRules...)
+	return strings.Join(rules, " ")
+}
+
+// AclLogEntry acl denied log for ACL LOG
+type AclLogEntry struct {
+	Count      int
+	Reason     string
+	Context    string
+	Object     string
+	UserName   string
+	ClientInfo string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Acl manage acl users and acl denied logs
+type Acl struct {
+	mu    sync.RWMutex
+	users map[string]*AclUser
+	// newest log is at the front
+	logs []*AclLogEntry
+}
+
+func NewAcl(defaultPwd string) *Acl {
+	acl := &Acl{users: map[string]*AclUser{}}
+	acl.users[DefaultAclUserName] = newDefaultAclUser(defaultPwd)
+	return acl
+}
+
+func newDefaultAclUser(pwd string) *AclUser {
+	u := NewAclUser(DefaultAclUserName)
+	u.Enabled = true
+	u.AllKeys = true
+	u.CmdRules = []string{"+@" + AclCategoryAll}
+	if len(pwd) == 0 {
+		u.NoPass = true
+	} else {
+		u.addPasswordHash(aclPasswordHash(pwd))
+	}
+	return u
+}
+
+var (
+	cmdCategoriesOnce sync.Once
+	// cmd -> category which cmd registered with driver.CmdType*
+	cmdCategories map[string]string
+)
+
+// cmdCategory get cmd category which cmd registered with driver.CmdType*,
+// the cmds are registered in init, so the map is built once on the first lookup
+func cmdCategory(cmd string) (string, bool) {
+	cmdCategoriesOnce.Do(func() {
+		cmdCategories = make(map[string]string)
+		for category, cmds := range driver.RegisteredCmdSet {
+			for _, name := range cmds {
+				cmdCategories[name] = category
+			}
+		}
+	})
+	category, ok := cmdCategories[cmd]
+	return category, ok
+}
+
+// Categories registered cmd categories
+func (a *Acl) Categories() []string {
+	categories := make([]string, 0, len(driver.RegisteredCmdSet))
+	for category := range driver.RegisteredCmdSet {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+func (a *Acl) GetUser(name string) *AclUser {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.users[name]
+}
+
+// SetUser create or modify user with acl rules
+func (a *Acl) SetUser(name string, rules ...string) (err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	u, ok := a.users[name]
+	if ok {
+		u = u.clone()
+	} else {
+		u = NewAclUser(name)
+	}
+	for _, rule := range rules {
+		if len(rule) == 0 {
+			continue
+		}
+		if err = u.SetRule(rule); err != nil {
+			return
+		}
+	}
+	a.users[name] = u
+
+	return
+}
+
+// DelUsers delete users, return deleted user names
+func (a *Acl) DelUsers(names ...string) (deleted []string, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, name := range names {
+		if name == DefaultAclUserName {
+			return nil, ErrAclDelDefaultUser
+		}
+	}
+	for _, name := range names {
+		if _, ok := a.users[name]; ok {
+			delete(a.users, name)
+			deleted = append(deleted, name)
+		}
+	}
+
+	return
+}
+
+// Users sorted by name
+func (a *Acl) Users() []*AclUser {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	users := make([]*AclUser, 0, len(a.users))
+	for _, u := range a.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Name < users[j].Name })
+	return users
+}
+
+// AddLog add acl denied log, merge with the same entry in group window
+func (a *Acl) AddLog(reason, object, userName, clientInfo string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	for _, entry := range a.logs {
+		if entry.Reason == reason && entry.Object == object && entry.UserName == userName &&
+			now.Sub(entry.UpdatedAt) < aclLogGroupWindow {
+			entry.Count++
+			entry.ClientInfo = clientInfo
+			entry.UpdatedAt = now
+			return
+		}
+	}
+
+	entry := &AclLogEntry{
+		Count:      1,
+		Reason:     reason,
+		Context:    "toplevel",
+		Object:     object,
+		UserName:   userName,
+		ClientInfo: clientInfo,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	a.logs = append([]*AclLogEntry{entry}, a.logs...)
+	if len(a.logs) > aclLogMaxLen {
+		a.logs = a.logs[:aclLogMaxLen]
+	}
+}
+
+// Logs get the latest n acl denied logs, n<0 to get all
+func (a *Acl) Logs(n int) []AclLogEntry {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if n < 0 || n > len(a.logs) {
+		n = len(a.logs)
+	}
+	logs := make([]AclLogEntry, n)
+	for i := 0; i < n; i++ {
+		logs[i] = *a.logs[i]
+	}
+	return logs
+}
+
+func (a *Acl) ResetLogs() {
+	a.mu.Lock()
+	a.logs = nil
+	a.mu.Unlock()
+}
+
+// CheckCmdPerm check user permissions to run the cmd and access the cmd keys,
+// denied cmd is recorded to acl log with client info
+func (a *Acl) CheckCmdPerm(userName string, clientInfo func() string, cmd string, cmdParams [][]byte) error {
+	u := a.GetUser(userName)
+	if u == nil {
+		return ErrNoAuth
+	}
+
+	category, _ := cmdCategory(cmd)
+	if !u.CanRunCmd(cmd, category) {
+		a.AddLog(AclLogReasonCmd, cmd, userName, clientInfo())
+		return fmt.Errorf("NOPERM this user has no permissions to run the '%s' command", cmd)
+	}
+
+	for _, key := range cmdKeys(cmd, cmdParams) {
+		if !u.CanAccessKey(key) {
+			a.AddLog(AclLogReasonKey, string(key), userName, clientInfo())
+			return ErrAclNoPermKey
+		}
+	}
+
+	return nil
+}
+
+// LoadFile load acl users from acl file, each line is like:
+// user <username> ... acl rules ...
+// the users in file replace all current users
+func (a *Acl) LoadFile(file string) (err error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+
+	users := map[string]*AclUser{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "user" {
+			return fmt.Errorf("ERR %s:%d should start with user keyword", file, lineNum)
+		}
+		u := NewAclUser(fields[1])
+		for _, rule := range fields[2:] {
+			if err = u.SetRule(rule); err != nil {
+				return fmt.Errorf("%s:%d %s", file, lineNum, err.Error())
+			}
+		}
+		users[u.Name] = u
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// keep default user if not in acl file
+	if _, ok := users[DefaultAclUserName]; !ok {
+		users[DefaultAclUserName] = a.users[DefaultAclUserName]
+	}
+	a.users = users
+
+	return
+}
+
+// SaveFile save all acl users to acl file
+func (a *Acl) SaveFile(file string) (err error) {
+	buf := &bytes.Buffer{}
+	for _, u := range a.Users() {
+		buf.WriteString(u.String())
+		buf.WriteString("\n")
+	}
+
+	tmpFile := file + ".tmp"
+	if err = os.WriteFile(tmpFile, buf.Bytes(), 0o600); err != nil {
+		return
+	}
+	return os.Rename(tmpFile, file)
+}
@@ -0,0 +1,36 @@
+package standalone
+
+import (
+	"testing"
+
+	"github.com/weedge/pkg/driver"
+)
+
+func TestAclUserRules(t *testing.T) {
+	acl := NewAcl("")
+	err := acl.SetUser("app", "on", ">app_pwd", "~app:*", "+@all", "-@"+driver.CmdTypeSlot, "+slotsinfo")
+	if err != nil {
+		t.Fatalf("set user err: %s", err.Error())
+	}
+
+	u := acl.GetUser("app")
+	if !u.Enabled || !u.CheckPassword("app_pwd") || u.CheckPassword("bad") {
+		t.Fatalf("user flags or password check fail: %s", u.String())
+	}
+	if !u.CanRunCmd("get", driver.CmdTypeString) || !u.CanRunCmd("slotsinfo", driver.CmdTypeSlot) {
+		t.Fatalf("user should run get and slotsinfo: %s", u.String())
+	}
+	if u.CanRunCmd("slotsmgrtslot", driver.CmdTypeSlot) {
+		t.Fatalf("user should not run slotsmgrtslot: %s", u.String())
+	}
+	if !u.CanAccessKey([]byte("app:1")) || u.CanAccessKey([]byte("other:1")) {
+		t.Fatalf("user key patterns check fail: %s", u.String())
+	}
+
+	if err = acl.SetUser("app", "bad-rule"); err == nil {
+		t.Fatalf("set user with bad rule should fail")
+	}
+	if _, err = acl.DelUsers(DefaultAclUserName); err != ErrAclDelDefaultUser {
+		t.Fatalf("del default user err: %v", err)
+	}
+}
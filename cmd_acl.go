@@ -0,0 +1,225 @@
+package standalone
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/redcon"
+	"github.com/weedge/pkg/driver"
+	"github.com/weedge/pkg/utils"
+)
+
+// cmd more detail reference:
+// https://redis.io/commands/acl/
+
+func init() {
+	driver.RegisterCmd(driver.CmdTypeSrv, "acl", aclCmd)
+}
+
+// ACL <subcommand> [arg [arg ...]]
+func aclCmd(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) < 1 {
+		return nil, ErrCmdParams
+	}
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+
+	acl := conn.srv.acl
+	op := strings.ToLower(utils.Bytes2String(cmdParams[0]))
+	args := cmdParams[1:]
+	switch op {
+	case "setuser":
+		return aclSetUser(acl, args)
+	case "getuser":
+		return aclGetUser(acl, args)
+	case "deluser":
+		return aclDelUser(conn, args)
+	case "list":
+		if len(args) != 0 {
+			return nil, ErrCmdParams
+		}
+		users := acl.Users()
+		data := make([]any, 0, len(users))
+		for _, u := range users {
+			data = append(data, u.String())
+		}
+		res = data
+	case "users":
+		if len(args) != 0 {
+			return nil, ErrCmdParams
+		}
+		users := acl.Users()
+		data := make([]any, 0, len(users))
+		for _, u := range users {
+			data = append(data, u.Name)
+		}
+		res = data
+	case "whoami":
+		if len(args) != 0 {
+			return nil, ErrCmdParams
+		}
+		res = conn.UserName()
+	case "cat":
+		return aclCat(acl, args)
+	case "log":
+		return aclLog(acl, args)
+	case "load":
+		if len(conn.srv.opts.AclFile) == 0 {
+			return nil, ErrAclNoFile
+		}
+		if err = acl.LoadFile(conn.srv.opts.AclFile); err != nil {
+			return nil, err
+		}
+		res = OK
+	case "save":
+		if len(conn.srv.opts.AclFile) == 0 {
+			return nil, ErrAclNoFile
+		}
+		if err = acl.SaveFile(conn.srv.opts.AclFile); err != nil {
+			return nil, fmt.Errorf("ERR There was an error trying to save the ACLs. Please check the server logs for more information")
+		}
+		res = OK
+	default:
+		return nil, fmt.Errorf("ERR unknown subcommand '%s'. Try ACL HELP", op)
+	}
+
+	return
+}
+
+// ACL SETUSER username [rule [rule ...]]
+func aclSetUser(acl *Acl, args [][]byte) (res interface{}, err error) {
+	if len(args) < 1 {
+		return nil, ErrCmdParams
+	}
+
+	rules := make([]string, 0, len(args)-1)
+	for _, arg := range args[1:] {
+		rules = append(rules, string(arg))
+	}
+	if err = acl.SetUser(string(args[0]), rules...); err != nil {
+		return
+	}
+
+	res = OK
+	return
+}
+
+// ACL GETUSER username
+func aclGetUser(acl *Acl, args [][]byte) (res interface{}, err error) {
+	if len(args) != 1 {
+		return nil, ErrCmdParams
+	}
+
+	u := acl.GetUser(utils.Bytes2String(args[0]))
+	if u == nil {
+		return nil, nil
+	}
+
+	res = []any{
+		"flags", u.Flags(),
+		"passwords", u.Passwords,
+		"commands", strings.Join(u.CmdRules, " "),
+		"keys", u.KeysRule(),
+	}
+	return
+}
+
+// ACL DELUSER username [username ...]
+// the connections authenticated as deleted users are killed
+func aclDelUser(conn *RespCmdConn, args [][]byte) (res interface{}, err error) {
+	if len(args) < 1 {
+		return nil, ErrCmdParams
+	}
+
+	names := make([]string, 0, len(args))
+	for _, arg := range args {
+		names = append(names, string(arg))
+	}
+	deleted, err := conn.srv.acl.DelUsers(names...)
+	if err != nil {
+		return
+	}
+
+	delUsers := map[string]struct{}{}
+	for _, name := range deleted {
+		delUsers[name] = struct{}{}
+	}
+	conn.srv.KillRespCmdConns(func(c *RespCmdConn) bool {
+		_, ok := delUsers[c.UserName()]
+		return ok
+	})
+
+	res = redcon.SimpleInt(len(deleted))
+	return
+}
+
+// ACL CAT [category]
+func aclCat(acl *Acl, args [][]byte) (res interface{}, err error) {
+	if len(args) > 1 {
+		return nil, ErrCmdParams
+	}
+
+	if len(args) == 0 {
+		categories := acl.Categories()
+		data := make([]any, 0, len(categories)+1)
+		data = append(data, AclCategoryAll)
+		for _, category := range categories {
+			data = append(data, category)
+		}
+		res = data
+		return
+	}
+
+	category := strings.ToLower(utils.Bytes2String(args[0]))
+	cmds, ok := driver.RegisteredCmdSet[category]
+	if !ok {
+		return nil, fmt.Errorf("ERR Unknown category '%s'", category)
+	}
+	data := make([]any, 0, len(cmds))
+	for _, cmd := range cmds {
+		data = append(data, cmd)
+	}
+	res = data
+	return
+}
+
+// ACL LOG [count | RESET]
+func aclLog(acl *Acl, args [][]byte) (res interface{}, err error) {
+	if len(args) > 1 {
+		return nil, ErrCmdParams
+	}
+
+	n := 10
+	if len(args) == 1 {
+		arg := utils.Bytes2String(args[0])
+		if strings.ToLower(arg) == "reset" {
+			acl.ResetLogs()
+			return OK, nil
+		}
+		if n, err = strconv.Atoi(arg); err != nil || n < 0 {
+			return nil, ErrValue
+		}
+	}
+
+	now := time.Now()
+	logs := acl.Logs(n)
+	data := make([]any, 0, len(logs))
+	for _, entry := range logs {
+		data = append(data, []any{
+			"count", redcon.SimpleInt(entry.Count),
+			"reason", entry.Reason,
+			"context", entry.Context,
+			"object", entry.Object,
+			"username", entry.UserName,
+			"age-seconds", fmt.Sprintf("%.3f", now.Sub(entry.CreatedAt).Seconds()),
+			"client-info", entry.ClientInfo,
+		})
+	}
+	res = data
+	return
+}
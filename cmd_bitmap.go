@@ -60,8 +60,10 @@ func bitop(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res int
 	destKey := cmdParams[1]
 	srcKeys := cmdParams[2:]
 
-	res, err = c.Db().DBBitmap().BitOP(ctx, op, destKey, srcKeys...)
-
+	if res, err = c.Db().DBBitmap().BitOP(ctx, op, destKey, srcKeys...); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyString, "set", destKey)
 	return
 }
 
@@ -113,6 +115,9 @@ func setbit(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res in
 		return
 	}
 
-	res, err = c.Db().DBBitmap().SetBit(ctx, cmdParams[0], offset, value)
+	if res, err = c.Db().DBBitmap().SetBit(ctx, cmdParams[0], offset, value); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyString, "setbit", cmdParams[0])
 	return
 }
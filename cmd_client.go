@@ -0,0 +1,389 @@
+package standalone
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/redcon"
+	"github.com/weedge/pkg/driver"
+	"github.com/weedge/pkg/utils"
+)
+
+// cmd more detail reference:
+// https://redis.io/commands/?group=connection
+
+type clientReplyMode int
+
+const (
+	clientReplyOn clientReplyMode = iota
+	clientReplyOff
+	clientReplySkip
+)
+
+// CLIENT <subcommand> [arg [arg ...]]
+func client(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) < 1 {
+		return nil, ErrCmdParams
+	}
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+
+	op := strings.ToLower(utils.Bytes2String(cmdParams[0]))
+	args := cmdParams[1:]
+	switch op {
+	case "getname":
+		if len(args) != 0 {
+			return nil, ErrCmdParams
+		}
+		if name := c.Name(); len(name) > 0 {
+			res = name
+		}
+	case "setname":
+		if len(args) != 1 {
+			return nil, ErrCmdParams
+		}
+		for _, ch := range args[0] {
+			if ch < '!' || ch > '~' {
+				return nil, ErrClientName
+			}
+		}
+		c.SetConnName(string(args[0]))
+		res = OK
+	case "id":
+		if len(args) != 0 {
+			return nil, ErrCmdParams
+		}
+		res = conn.ID()
+	case "info":
+		if len(args) != 0 {
+			return nil, ErrCmdParams
+		}
+		res = conn.clientInfo() + "\n"
+	case "list":
+		return clientList(conn, args)
+	case "kill":
+		return clientKill(conn, args)
+	case "pause":
+		return clientPauseCmd(conn, args)
+	case "unpause":
+		if len(args) != 0 {
+			return nil, ErrCmdParams
+		}
+		conn.srv.pause.Unpause()
+		res = OK
+	case "reply":
+		return clientReply(conn, args)
+	case "tracking":
+		return clientTracking(conn, args)
+	case "caching":
+		return clientCachingCmd(conn, args)
+	case "getredir":
+		if len(args) != 0 {
+			return nil, ErrCmdParams
+		}
+		res = int64(-1)
+		if opts := conn.tracking.Load(); opts != nil {
+			res = opts.redirect
+		}
+	case "trackinginfo":
+		if len(args) != 0 {
+			return nil, ErrCmdParams
+		}
+		res = conn.trackingInfo()
+	default:
+		return nil, fmt.Errorf("ERR unknown subcommand '%s'. Try CLIENT HELP", op)
+	}
+
+	return
+}
+
+// CLIENT LIST [TYPE NORMAL] [ID client-id [client-id ...]]
+func clientList(conn *RespCmdConn, args [][]byte) (res interface{}, err error) {
+	var ids map[int64]struct{}
+	for i := 0; i < len(args); i++ {
+		switch strings.ToLower(utils.Bytes2String(args[i])) {
+		case "type":
+			if i+1 >= len(args) || strings.ToLower(utils.Bytes2String(args[i+1])) != "normal" {
+				return nil, ErrSyntax
+			}
+			i++
+		case "id":
+			if i+1 >= len(args) {
+				return nil, ErrSyntax
+			}
+			ids = map[int64]struct{}{}
+			for i++; i < len(args); i++ {
+				id, err := strconv.ParseInt(utils.Bytes2String(args[i]), 10, 64)
+				if err != nil || id <= 0 {
+					return nil, fmt.Errorf("ERR Invalid client ID")
+				}
+				ids[id] = struct{}{}
+			}
+		default:
+			return nil, ErrSyntax
+		}
+	}
+
+	var b strings.Builder
+	for _, c := range conn.srv.RespCmdConns() {
+		if _, ok := ids[c.ID()]; ids != nil && !ok {
+			continue
+		}
+		b.WriteString(c.clientInfo())
+		b.WriteByte('\n')
+	}
+	res = b.String()
+	return
+}
+
+// CLIENT KILL ip:port
+// CLIENT KILL <[ID client-id] | [USER username] | [ADDR ip:port] | [LADDR ip:port] | [SKIPME yes/no]> ...
+func clientKill(conn *RespCmdConn, args [][]byte) (res interface{}, err error) {
+	if len(args) == 0 {
+		return nil, ErrCmdParams
+	}
+
+	// old form, kill the client by addr
+	if len(args) == 1 {
+		addr := utils.Bytes2String(args[0])
+		if n := killClients(conn, func(c *RespCmdConn) bool { return c.GetRemoteAddr() == addr }); n == 0 {
+			return nil, ErrClientNoSuch
+		}
+		return OK, nil
+	}
+	if len(args)%2 != 0 {
+		return nil, ErrSyntax
+	}
+
+	var filters []func(c *RespCmdConn) bool
+	skipMe := true
+	for i := 0; i < len(args); i += 2 {
+		val := string(args[i+1])
+		switch strings.ToLower(utils.Bytes2String(args[i])) {
+		case "id":
+			id, err := strconv.ParseInt(val, 10, 64)
+			if err != nil || id <= 0 {
+				return nil, ErrClientID
+			}
+			filters = append(filters, func(c *RespCmdConn) bool { return c.ID() == id })
+		case "user":
+			filters = append(filters, func(c *RespCmdConn) bool { return c.UserName() == val })
+		case "addr":
+			filters = append(filters, func(c *RespCmdConn) bool { return c.GetRemoteAddr() == val })
+		case "laddr":
+			filters = append(filters, func(c *RespCmdConn) bool { return c.LocalAddr() == val })
+		case "skipme":
+			switch strings.ToLower(val) {
+			case "yes":
+				skipMe = true
+			case "no":
+				skipMe = false
+			default:
+				return nil, ErrClientKillSkip
+			}
+		default:
+			return nil, ErrSyntax
+		}
+	}
+
+	n := killClients(conn, func(c *RespCmdConn) bool {
+		if skipMe && c == conn {
+			return false
+		}
+		for _, filter := range filters {
+			if !filter(c) {
+				return false
+			}
+		}
+		return true
+	})
+	res = redcon.SimpleInt(n)
+	return
+}
+
+// killClients kill the clients which match filter,
+// the current client is closed after the reply is flushed
+func killClients(conn *RespCmdConn, filter func(c *RespCmdConn) bool) (n int) {
+	killMe := false
+	n = conn.srv.KillRespCmdConns(func(c *RespCmdConn) bool {
+		if c == conn {
+			killMe = filter(c)
+			return false
+		}
+		return filter(c)
+	})
+	if killMe {
+		conn.closeAfterReply = true
+		n++
+	}
+	return
+}
+
+// CLIENT PAUSE timeout [WRITE | ALL]
+func clientPauseCmd(conn *RespCmdConn, args [][]byte) (res interface{}, err error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, ErrCmdParams
+	}
+
+	timeout, err := strconv.ParseInt(utils.Bytes2String(args[0]), 10, 64)
+	if err != nil || timeout < 0 {
+		return nil, ErrClientTimeout
+	}
+	all := true
+	if len(args) == 2 {
+		switch strings.ToLower(utils.Bytes2String(args[1])) {
+		case "write":
+			all = false
+		case "all":
+		default:
+			return nil, ErrSyntax
+		}
+	}
+
+	conn.srv.pause.Pause(all, time.Duration(timeout)*time.Millisecond)
+	res = OK
+	return
+}
+
+// CLIENT REPLY ON | OFF | SKIP
+func clientReply(conn *RespCmdConn, args [][]byte) (res interface{}, err error) {
+	if len(args) != 1 {
+		return nil, ErrCmdParams
+	}
+
+	switch strings.ToLower(utils.Bytes2String(args[0])) {
+	case "on":
+		conn.setReplyMode(clientReplyOn)
+		res = OK
+	case "off":
+		conn.setReplyMode(clientReplyOff)
+		err = ErrNoops
+	case "skip":
+		conn.setReplyMode(clientReplySkip)
+		err = ErrNoops
+	default:
+		return nil, ErrSyntax
+	}
+	return
+}
+
+// CLIENT TRACKING ON|OFF [REDIRECT client-id] [PREFIX prefix [PREFIX prefix ...]]
+// [BCAST] [OPTIN] [OPTOUT] [NOLOOP]
+func clientTracking(conn *RespCmdConn, args [][]byte) (res interface{}, err error) {
+	if len(args) < 1 {
+		return nil, ErrCmdParams
+	}
+
+	on := false
+	switch strings.ToLower(utils.Bytes2String(args[0])) {
+	case "on":
+		on = true
+	case "off":
+	default:
+		return nil, ErrSyntax
+	}
+
+	opts := &clientTrackingOpts{}
+	for i := 1; i < len(args); i++ {
+		switch strings.ToLower(utils.Bytes2String(args[i])) {
+		case "redirect":
+			if i+1 >= len(args) {
+				return nil, ErrSyntax
+			}
+			i++
+			if opts.redirect, err = strconv.ParseInt(utils.Bytes2String(args[i]), 10, 64); err != nil {
+				return nil, ErrValue
+			}
+		case "prefix":
+			if i+1 >= len(args) {
+				return nil, ErrSyntax
+			}
+			i++
+			opts.prefixes = append(opts.prefixes, string(args[i]))
+		case "bcast":
+			opts.bcast = true
+		case "optin":
+			opts.optin = true
+		case "optout":
+			opts.optout = true
+		case "noloop":
+			opts.noloop = true
+		default:
+			return nil, ErrSyntax
+		}
+	}
+
+	if !on {
+		conn.srv.tracking.disable(conn)
+		conn.trackingCaching = clientCachingNone
+		return OK, nil
+	}
+	if len(opts.prefixes) > 0 && !opts.bcast {
+		return nil, ErrTrackingPrefixNoBcast
+	}
+	if opts.optin && opts.optout {
+		return nil, ErrTrackingOptInOptOut
+	}
+	if opts.bcast && (opts.optin || opts.optout) {
+		return nil, ErrTrackingOptBcast
+	}
+	if opts.redirect != 0 && opts.redirect != conn.id && !conn.srv.trackingRedirectExists(opts.redirect) {
+		return nil, ErrTrackingRedirect
+	}
+	if opts.redirect == conn.id {
+		opts.redirect = 0
+	}
+	if cur := conn.tracking.Load(); cur != nil {
+		if cur.bcast != opts.bcast {
+			return nil, ErrTrackingSwitchBcast
+		}
+		if cur.optin != opts.optin || cur.optout != opts.optout {
+			return nil, ErrTrackingSwitchOpt
+		}
+		// the prefixes are added to the ones registered before like redis
+		added := make(map[string]struct{}, len(opts.prefixes))
+		for _, prefix := range opts.prefixes {
+			added[prefix] = struct{}{}
+		}
+		for _, prefix := range cur.prefixes {
+			if _, ok := added[prefix]; !ok {
+				opts.prefixes = append(opts.prefixes, prefix)
+			}
+		}
+	}
+
+	conn.srv.tracking.enable(conn, opts)
+	return OK, nil
+}
+
+// CLIENT CACHING YES|NO
+func clientCachingCmd(conn *RespCmdConn, args [][]byte) (res interface{}, err error) {
+	if len(args) != 1 {
+		return nil, ErrCmdParams
+	}
+	opts := conn.tracking.Load()
+	if opts == nil || (!opts.optin && !opts.optout) {
+		return nil, ErrTrackingCachingNoOpt
+	}
+
+	switch strings.ToLower(utils.Bytes2String(args[0])) {
+	case "yes":
+		if !opts.optin {
+			return nil, ErrTrackingCachingYesOpt
+		}
+		conn.trackingCaching = clientCachingYes
+	case "no":
+		if !opts.optout {
+			return nil, ErrTrackingCachingNoOptOut
+		}
+		conn.trackingCaching = clientCachingNo
+	default:
+		return nil, ErrSyntax
+	}
+	return OK, nil
+}
@@ -0,0 +1,78 @@
+package standalone
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/kitex/pkg/klog"
+	"github.com/weedge/pkg/driver"
+	"github.com/weedge/pkg/utils"
+)
+
+// cmd more detail reference:
+// https://redis.io/commands/config-get/
+
+func init() {
+	driver.RegisterCmd(driver.CmdTypeSrv, "config", configCmd)
+}
+
+// CONFIG GET parameter [parameter ...]
+// CONFIG SET parameter value [parameter value ...]
+// CONFIG RESETSTAT
+// CONFIG REWRITE
+func configCmd(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) < 1 {
+		return nil, ErrCmdParams
+	}
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+
+	srv := conn.srv
+	op := strings.ToLower(utils.Bytes2String(cmdParams[0]))
+	args := make([]string, 0, len(cmdParams)-1)
+	for _, arg := range cmdParams[1:] {
+		args = append(args, string(arg))
+	}
+	switch op {
+	case "get":
+		if len(args) < 1 {
+			return nil, ErrCmdParams
+		}
+		pairs := srv.ConfigGet(args...)
+		data := make(RespMap, 0, len(pairs))
+		for _, item := range pairs {
+			data = append(data, item)
+		}
+		res = data
+	case "set":
+		if err = srv.ConfigSet(args...); err != nil {
+			return
+		}
+		res = OK
+	case "resetstat":
+		if len(args) != 0 {
+			return nil, ErrCmdParams
+		}
+		srv.stats.Reset()
+		res = OK
+	case "rewrite":
+		if len(args) != 0 {
+			return nil, ErrCmdParams
+		}
+		if err = srv.ConfigRewrite(); err != nil {
+			if err == ErrConfigNoFile {
+				return
+			}
+			klog.Errorf("config rewrite %s err: %s", srv.configFile, err.Error())
+			return nil, fmt.Errorf("ERR Rewriting config file: %s", err.Error())
+		}
+		res = OK
+	default:
+		return nil, fmt.Errorf("ERR unknown subcommand '%s'. Try CONFIG HELP", op)
+	}
+
+	return
+}
@@ -0,0 +1,694 @@
+package standalone
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/match"
+	"github.com/tidwall/redcon"
+	"github.com/weedge/pkg/driver"
+	"github.com/weedge/pkg/utils"
+)
+
+// cmd more detail reference:
+// https://redis.io/commands/?group=generic
+
+func init() {
+	// generic cmds for all type keys, registered in string category as before for acl rules;
+	// the type prefixed cmds (e.g. lmclear, httl) are kept for compatibility
+	driver.RegisterCmd(driver.CmdTypeString, "del", del)
+	driver.RegisterCmd(driver.CmdTypeString, "exists", exists)
+	driver.RegisterCmd(driver.CmdTypeString, "expire", expire)
+	driver.RegisterCmd(driver.CmdTypeString, "expireat", expireat)
+	driver.RegisterCmd(driver.CmdTypeString, "persist", persist)
+	driver.RegisterCmd(driver.CmdTypeString, "ttl", ttl)
+	driver.RegisterCmd(driver.CmdTypeString, "pexpire", pexpire)
+	driver.RegisterCmd(driver.CmdTypeString, "pexpireat", pexpireat)
+	driver.RegisterCmd(driver.CmdTypeString, "pttl", pttl)
+	driver.RegisterCmd(driver.CmdTypeString, "expiretime", expiretime)
+	driver.RegisterCmd(driver.CmdTypeString, "pexpiretime", pexpiretime)
+	driver.RegisterCmd(driver.CmdTypeString, "type", typeCmd)
+	driver.RegisterCmd(driver.CmdTypeString, "keys", keys)
+	driver.RegisterCmd(driver.CmdTypeString, "randomkey", randomkey)
+	driver.RegisterCmd(driver.CmdTypeString, "rename", rename)
+	driver.RegisterCmd(driver.CmdTypeString, "renamenx", renamenx)
+	driver.RegisterCmd(driver.CmdTypeString, "copy", copyCmd)
+	driver.RegisterCmd(driver.CmdTypeString, "move", move)
+}
+
+// scan batch size of KEYS and RANDOMKEY
+const keysScanBatch = 1024
+
+// keyType the type store of the keys with the type name for TYPE
+type keyType struct {
+	name string
+	cmd  driver.ICommonCmd
+}
+
+// keyTypes the type stores of db in TYPE lookup order,
+// bitmap keys are in string store
+func keyTypes(db driver.IDB) []keyType {
+	return []keyType{
+		{"string", db.DBString()},
+		{"list", db.DBList()},
+		{"hash", db.DBHash()},
+		{"set", db.DBSet()},
+		{"zset", db.DBZSet()},
+	}
+}
+
+// keyTypeOf the type store which the key exists in, ok is false if the key doesn't exist
+func keyTypeOf(ctx context.Context, db driver.IDB, key []byte) (kt keyType, ok bool, err error) {
+	for _, kt = range keyTypes(db) {
+		n, err := kt.cmd.Exists(ctx, key)
+		if err != nil {
+			return kt, false, err
+		}
+		if n > 0 {
+			return kt, true, nil
+		}
+	}
+	return keyType{}, false, nil
+}
+
+// eachKeyType run fn on all type stores of db,
+// return 1 if fn returns n > 0 for any of them, the key may exist in several stores
+func eachKeyType(db driver.IDB, fn func(cmd driver.ICommonCmd) (int64, error)) (n int64, err error) {
+	for _, kt := range keyTypes(db) {
+		cn, err := fn(kt.cmd)
+		if err != nil {
+			return 0, err
+		}
+		if cn > 0 {
+			n = 1
+		}
+	}
+	return
+}
+
+// delAllTypes del the keys in all type stores, return the deleted keys num
+func delAllTypes(db driver.IDB) func(ctx context.Context, keys ...[]byte) (int64, error) {
+	return func(ctx context.Context, keys ...[]byte) (n int64, err error) {
+		for _, key := range keys {
+			cn, err := eachKeyType(db, func(cmd driver.ICommonCmd) (int64, error) {
+				return cmd.Del(ctx, key)
+			})
+			if err != nil {
+				return n, err
+			}
+			n += cn
+		}
+		return
+	}
+}
+
+// DEL key [key ...]
+func del(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) == 0 {
+		err = ErrCmdParams
+		return
+	}
+
+	res, err = delKeys(ctx, c, delAllTypes(c.Db()), cmdParams)
+	return
+}
+
+// EXISTS key [key ...]
+// the same key repeated is counted multiple times like redis
+func exists(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) == 0 {
+		err = ErrCmdParams
+		return
+	}
+
+	n := int64(0)
+	for _, key := range cmdParams {
+		_, ok, err := keyTypeOf(ctx, c.Db(), key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			n++
+		}
+	}
+
+	res = n
+	return
+}
+
+// pexpireCmd type store which supports the millisecond ttl,
+// the ttl is in seconds (rounded up) if the type store doesn't implement it,
+// e.g. SET lock token NX PX 100 holds the lock for 1s and PTTL replies multiples of 1000;
+// INFO server ttl_precision shows it
+type pexpireCmd interface {
+	PExpire(ctx context.Context, key []byte, duration int64) (int64, error)
+	PTTL(ctx context.Context, key []byte) (int64, error)
+}
+
+// ttlPrecision the ttl precision of the db: ms if all type stores support the millisecond ttl, otherwise s
+func ttlPrecision(db driver.IDB) string {
+	for _, kt := range keyTypes(db) {
+		if _, ok := kt.cmd.(pexpireCmd); !ok {
+			return "s"
+		}
+	}
+	return "ms"
+}
+
+// keyPTTL the millisecond ttl of the key in the type store, -1 if the key exists without ttl
+func keyPTTL(ctx context.Context, cmd driver.ICommonCmd, key []byte) (int64, error) {
+	if pc, ok := cmd.(pexpireCmd); ok {
+		return pc.PTTL(ctx, key)
+	}
+	ttl, err := cmd.TTL(ctx, key)
+	if err != nil || ttl < 0 {
+		return ttl, err
+	}
+	return ttl * 1000, nil
+}
+
+// keyPExpire set the millisecond ttl of the key in the type store
+func keyPExpire(ctx context.Context, cmd driver.ICommonCmd, key []byte, duration int64) (int64, error) {
+	if pc, ok := cmd.(pexpireCmd); ok {
+		return pc.PExpire(ctx, key, duration)
+	}
+	return cmd.Expire(ctx, key, (duration+999)/1000)
+}
+
+// existKeyTypes the type stores which the key exists in
+func existKeyTypes(ctx context.Context, db driver.IDB, key []byte) (kts []keyType, err error) {
+	for _, kt := range keyTypes(db) {
+		n, err := kt.cmd.Exists(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			kts = append(kts, kt)
+		}
+	}
+	return
+}
+
+// expireGeneric EXPIRE/PEXPIRE/EXPIREAT/PEXPIREAT key time [NX | XX | GT | LT],
+// the time is in unit, at is true for the unix time;
+// the key is deleted if the time is in the past like redis
+func expireGeneric(ctx context.Context, c driver.IRespConn, cmd string, cmdParams [][]byte, unit time.Duration, at bool) (res interface{}, err error) {
+	if len(cmdParams) < 2 {
+		err = ErrCmdParams
+		return
+	}
+	v, err := utils.StrInt64(cmdParams[1], nil)
+	if err != nil {
+		err = ErrValue
+		return
+	}
+	var nx, xx, gt, lt bool
+	for _, arg := range cmdParams[2:] {
+		switch strings.ToLower(utils.Bytes2String(arg)) {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "gt":
+			gt = true
+		case "lt":
+			lt = true
+		default:
+			return nil, fmt.Errorf("ERR Unsupported option %s", arg)
+		}
+	}
+	if nx && (xx || gt || lt) {
+		return nil, ErrExpireNXOpts
+	}
+	if gt && lt {
+		return nil, ErrExpireGTLT
+	}
+
+	ms := int64(unit / time.Millisecond)
+	if v > math.MaxInt64/ms || v < math.MinInt64/ms {
+		return nil, fmt.Errorf("ERR invalid expire time in '%s' command", cmd)
+	}
+	now := time.Now().UnixMilli()
+	duration := v * ms
+	if at {
+		duration -= now
+	}
+
+	key := cmdParams[0]
+	kts, err := existKeyTypes(ctx, c.Db(), key)
+	if err != nil || len(kts) == 0 {
+		return int64(0), err
+	}
+	cur, err := keyPTTL(ctx, kts[0].cmd, key)
+	if err != nil {
+		return
+	}
+	// no ttl is infinite ttl for GT and LT
+	switch {
+	case nx && cur >= 0, xx && cur < 0:
+		return int64(0), nil
+	case gt && (cur < 0 || duration <= cur), lt && cur >= 0 && duration >= cur:
+		return int64(0), nil
+	}
+
+	if duration <= 0 {
+		if _, err = delAllTypes(c.Db())(ctx, key); err != nil {
+			return
+		}
+		notifyKeyEvent(c, notifyGeneric, "del", key)
+		return int64(1), nil
+	}
+	for _, kt := range kts {
+		if _, err = keyPExpire(ctx, kt.cmd, key, duration); err != nil {
+			return
+		}
+	}
+	notifyKeyEvent(c, notifyGeneric, "expire", key)
+
+	res = int64(1)
+	return
+}
+
+// EXPIRE key seconds [NX | XX | GT | LT]
+func expire(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	return expireGeneric(ctx, c, "expire", cmdParams, time.Second, false)
+}
+
+// PEXPIRE key milliseconds [NX | XX | GT | LT]
+// the ttl is rounded up to seconds if the storager doesn't support the millisecond ttl
+func pexpire(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	return expireGeneric(ctx, c, "pexpire", cmdParams, time.Millisecond, false)
+}
+
+// EXPIREAT key unix-time-seconds [NX | XX | GT | LT]
+func expireat(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	return expireGeneric(ctx, c, "expireat", cmdParams, time.Second, true)
+}
+
+// PEXPIREAT key unix-time-milliseconds [NX | XX | GT | LT]
+// the ttl is rounded up to seconds if the storager doesn't support the millisecond ttl
+func pexpireat(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	return expireGeneric(ctx, c, "pexpireat", cmdParams, time.Millisecond, true)
+}
+
+// ttlGeneric TTL/PTTL/EXPIRETIME/PEXPIRETIME key, the ttl is in unit, at is true for the unix time;
+// return -2 if the key doesn't exist, -1 if the key exists without ttl
+func ttlGeneric(ctx context.Context, c driver.IRespConn, cmdParams [][]byte, unit time.Duration, at bool) (res interface{}, err error) {
+	if len(cmdParams) != 1 {
+		err = ErrCmdParams
+		return
+	}
+
+	kt, ok, err := keyTypeOf(ctx, c.Db(), cmdParams[0])
+	if err != nil {
+		return
+	}
+	if !ok {
+		return int64(-2), nil
+	}
+	ttl, err := keyPTTL(ctx, kt.cmd, cmdParams[0])
+	if err != nil || ttl < 0 {
+		return int64(-1), err
+	}
+
+	if at {
+		ttl += time.Now().UnixMilli()
+	}
+	ms := int64(unit / time.Millisecond)
+	res = (ttl + ms/2) / ms
+	return
+}
+
+// TTL key
+func ttl(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	return ttlGeneric(ctx, c, cmdParams, time.Second, false)
+}
+
+// PTTL key
+// reply multiples of 1000 if the storager doesn't support the millisecond ttl
+func pttl(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	return ttlGeneric(ctx, c, cmdParams, time.Millisecond, false)
+}
+
+// EXPIRETIME key
+func expiretime(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	return ttlGeneric(ctx, c, cmdParams, time.Second, true)
+}
+
+// PEXPIRETIME key
+// reply in seconds precision if the storager doesn't support the millisecond ttl
+func pexpiretime(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	return ttlGeneric(ctx, c, cmdParams, time.Millisecond, true)
+}
+
+// PERSIST key
+func persist(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) != 1 {
+		err = ErrCmdParams
+		return
+	}
+
+	n, err := eachKeyType(c.Db(), func(cmd driver.ICommonCmd) (int64, error) {
+		return cmd.Persist(ctx, cmdParams[0])
+	})
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyGeneric, "persist", cmdParams[0])
+	}
+
+	res = n
+	return
+}
+
+// TYPE key
+// return none if the key doesn't exist
+func typeCmd(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) != 1 {
+		err = ErrCmdParams
+		return
+	}
+
+	kt, ok, err := keyTypeOf(ctx, c.Db(), cmdParams[0])
+	if err != nil {
+		return
+	}
+	if !ok {
+		return redcon.SimpleString("none"), nil
+	}
+
+	res = redcon.SimpleString(kt.name)
+	return
+}
+
+// KEYS pattern
+// the keys in several type stores are replied once
+func keys(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) != 1 {
+		err = ErrCmdParams
+		return
+	}
+
+	pattern := string(cmdParams[0])
+	seen := map[string]struct{}{}
+	data := []any{}
+	for _, kt := range keyTypes(c.Db()) {
+		scanner, ok := kt.cmd.(keyScanner)
+		if !ok {
+			return nil, ErrScanNotSupported
+		}
+		var last []byte
+		for {
+			items, err := scanner.Scan(ctx, last, keysScanBatch, false, "")
+			if err != nil {
+				return nil, err
+			}
+			for _, key := range items {
+				if _, ok := seen[string(key)]; ok || !match.Match(utils.Bytes2String(key), pattern) {
+					continue
+				}
+				seen[string(key)] = struct{}{}
+				data = append(data, key)
+			}
+			if len(items) < keysScanBatch {
+				break
+			}
+			last = items[len(items)-1]
+		}
+	}
+
+	res = data
+	return
+}
+
+// RANDOMKEY
+// the key is picked from the scan batch after a random position of a random type store,
+// it's approximately random without random access to the storager
+func randomkey(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) != 0 {
+		err = ErrCmdParams
+		return
+	}
+
+	kts := keyTypes(c.Db())
+	for _, i := range rand.Perm(len(kts)) {
+		scanner, ok := kts[i].cmd.(keyScanner)
+		if !ok {
+			return nil, ErrScanNotSupported
+		}
+		// start from a random position, then from the first key if it's after the last one
+		for _, cursor := range [][]byte{{byte(rand.Intn(256))}, nil} {
+			items, err := scanner.Scan(ctx, cursor, keysScanBatch, false, "")
+			if err != nil {
+				return nil, err
+			}
+			if len(items) > 0 {
+				return items[rand.Intn(len(items))], nil
+			}
+		}
+	}
+	return
+}
+
+// keyTypeByName the type store of db by the type name
+func keyTypeByName(db driver.IDB, name string) keyType {
+	for _, kt := range keyTypes(db) {
+		if kt.name == name {
+			return kt
+		}
+	}
+	return keyType{}
+}
+
+// copyKey copy the value with ttl of the key in the type store from src db to dst db as dstKey,
+// dstKey must not exist in dst db
+func copyKey(ctx context.Context, kt keyType, src, dst driver.IDB, key, dstKey []byte) (err error) {
+	if err = copyKeyValue(ctx, kt.name, src, dst, key, dstKey); err != nil {
+		return
+	}
+
+	ttl, err := keyPTTL(ctx, kt.cmd, key)
+	if err != nil || ttl <= 0 {
+		return
+	}
+	_, err = keyPExpire(ctx, keyTypeByName(dst, kt.name).cmd, dstKey, ttl)
+	return
+}
+
+// copyKeyValue copy the value of the key in the type store from src db to dst db as dstKey
+func copyKeyValue(ctx context.Context, typ string, src, dst driver.IDB, key, dstKey []byte) error {
+	switch typ {
+	case "string":
+		v, err := src.DBString().Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		return dst.DBString().Set(ctx, dstKey, v)
+	case "list":
+		vals, err := src.DBList().LRange(ctx, key, 0, -1)
+		if err != nil {
+			return err
+		}
+		_, err = dst.DBList().RPush(ctx, dstKey, vals...)
+		return err
+	case "hash":
+		pairs, err := src.DBHash().HGetAll(ctx, key)
+		if err != nil {
+			return err
+		}
+		return dst.DBHash().HMset(ctx, dstKey, pairs...)
+	case "set":
+		members, err := src.DBSet().SMembers(ctx, key)
+		if err != nil {
+			return err
+		}
+		_, err = dst.DBSet().SAdd(ctx, dstKey, members...)
+		return err
+	case "zset":
+		pairs, err := src.DBZSet().ZRangeGeneric(ctx, key, 0, -1, false)
+		if err != nil {
+			return err
+		}
+		_, err = dst.DBZSet().ZAdd(ctx, dstKey, pairs...)
+		return err
+	}
+	return nil
+}
+
+// copyKeyAllTypes copy the key in all type stores which it exists in from src db to dst db as dstKey,
+// the dstKey in dst db is deleted before copy; return false if the key doesn't exist
+func copyKeyAllTypes(ctx context.Context, src, dst driver.IDB, key, dstKey []byte) (ok bool, err error) {
+	kts, err := existKeyTypes(ctx, src, key)
+	if err != nil || len(kts) == 0 {
+		return false, err
+	}
+
+	if _, err = delAllTypes(dst)(ctx, dstKey); err != nil {
+		return
+	}
+	for _, kt := range kts {
+		if err = copyKey(ctx, kt, src, dst, key, dstKey); err != nil {
+			return
+		}
+	}
+	return true, nil
+}
+
+// renameKey rename the key to dstKey in the conn db, nx is RENAMENX;
+// return 0 if nx and dstKey exists
+func renameKey(ctx context.Context, c driver.IRespConn, key, dstKey []byte, nx bool) (n int64, err error) {
+	db := c.Db()
+	if _, ok, err := keyTypeOf(ctx, db, key); err != nil || !ok {
+		if err == nil {
+			err = ErrNoSuchKey
+		}
+		return 0, err
+	}
+	if string(key) == string(dstKey) {
+		if nx {
+			return 0, nil
+		}
+		return 1, nil
+	}
+	if nx {
+		if _, ok, err := keyTypeOf(ctx, db, dstKey); err != nil || ok {
+			return 0, err
+		}
+	}
+
+	if _, err = copyKeyAllTypes(ctx, db, db, key, dstKey); err != nil {
+		return
+	}
+	if _, err = delAllTypes(db)(ctx, key); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyGeneric, "rename_from", key)
+	notifyKeyEvent(c, notifyGeneric, "rename_to", dstKey)
+	return 1, nil
+}
+
+// RENAME key newkey
+func rename(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) != 2 {
+		err = ErrCmdParams
+		return
+	}
+
+	if _, err = renameKey(ctx, c, cmdParams[0], cmdParams[1], false); err != nil {
+		return
+	}
+	res = OK
+	return
+}
+
+// RENAMENX key newkey
+func renamenx(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) != 2 {
+		err = ErrCmdParams
+		return
+	}
+
+	res, err = renameKey(ctx, c, cmdParams[0], cmdParams[1], true)
+	return
+}
+
+// COPY source destination [DB destination-db] [REPLACE]
+func copyCmd(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) < 2 {
+		err = ErrCmdParams
+		return
+	}
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+
+	dbIdx, replace := conn.dbIdx, false
+	for i := 2; i < len(cmdParams); i++ {
+		switch strings.ToLower(utils.Bytes2String(cmdParams[i])) {
+		case "db":
+			if i+1 >= len(cmdParams) {
+				return nil, ErrSyntax
+			}
+			i++
+			if dbIdx, err = strconv.Atoi(utils.Bytes2String(cmdParams[i])); err != nil {
+				return nil, ErrValue
+			}
+		case "replace":
+			replace = true
+		default:
+			return nil, ErrSyntax
+		}
+	}
+	key, dstKey := cmdParams[0], cmdParams[1]
+	if dbIdx == conn.dbIdx && string(key) == string(dstKey) {
+		return nil, ErrSameObject
+	}
+
+	dst, err := conn.srv.store.Select(ctx, dbIdx)
+	if err != nil {
+		return
+	}
+	if !replace {
+		if _, ok, err := keyTypeOf(ctx, dst, dstKey); err != nil || ok {
+			return int64(0), err
+		}
+	}
+	if ok, err = copyKeyAllTypes(ctx, c.Db(), dst, key, dstKey); err != nil || !ok {
+		return int64(0), err
+	}
+	conn.srv.notifyKeyspaceEvent(notifyGeneric, "copy_to", dbIdx, dstKey)
+	if dbIdx != conn.dbIdx {
+		conn.srv.touchWatchedKeys(conn, dbIdx, dstKey)
+	}
+
+	res = int64(1)
+	return
+}
+
+// MOVE key db
+// return 0 if the key doesn't exist or exists in the dst db
+func move(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) != 2 {
+		err = ErrCmdParams
+		return
+	}
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+	dbIdx, err := strconv.Atoi(utils.Bytes2String(cmdParams[1]))
+	if err != nil {
+		return nil, ErrValue
+	}
+	if dbIdx == conn.dbIdx {
+		return nil, ErrSameObject
+	}
+
+	key := cmdParams[0]
+	dst, err := conn.srv.store.Select(ctx, dbIdx)
+	if err != nil {
+		return
+	}
+	if _, ok, err := keyTypeOf(ctx, dst, key); err != nil || ok {
+		return int64(0), err
+	}
+	if ok, err = copyKeyAllTypes(ctx, c.Db(), dst, key, key); err != nil || !ok {
+		return int64(0), err
+	}
+	if _, err = delAllTypes(c.Db())(ctx, key); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyGeneric, "move_from", key)
+	conn.srv.notifyKeyspaceEvent(notifyGeneric, "move_to", dbIdx, key)
+	conn.srv.touchWatchedKeys(conn, dbIdx, key)
+
+	res = int64(1)
+	return
+}
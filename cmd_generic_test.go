@@ -0,0 +1,401 @@
+package standalone
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/redcon"
+	"github.com/weedge/pkg/driver"
+	"github.com/weedge/xdis-standalone/config"
+)
+
+// testKeys in-memory common key cmds of a type store, key -> ttl (-1 without ttl)
+type testKeys map[string]int64
+
+func (k testKeys) Del(ctx context.Context, keys ...[]byte) (n int64, err error) {
+	for _, key := range keys {
+		if _, ok := k[string(key)]; ok {
+			delete(k, string(key))
+			n++
+		}
+	}
+	return
+}
+
+func (k testKeys) Exists(ctx context.Context, key []byte) (int64, error) {
+	if _, ok := k[string(key)]; ok {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (k testKeys) Expire(ctx context.Context, key []byte, duration int64) (int64, error) {
+	if _, ok := k[string(key)]; !ok {
+		return 0, nil
+	}
+	k[string(key)] = duration
+	return 1, nil
+}
+
+func (k testKeys) ExpireAt(ctx context.Context, key []byte, when int64) (int64, error) {
+	return k.Expire(ctx, key, when)
+}
+
+func (k testKeys) TTL(ctx context.Context, key []byte) (int64, error) {
+	if ttl, ok := k[string(key)]; ok {
+		return ttl, nil
+	}
+	return -1, nil
+}
+
+func (k testKeys) Persist(ctx context.Context, key []byte) (int64, error) {
+	if ttl, ok := k[string(key)]; !ok || ttl < 0 {
+		return 0, nil
+	}
+	k[string(key)] = -1
+	return 1, nil
+}
+
+func (k testKeys) Scan(ctx context.Context, cursor []byte, count int, inclusive bool, match string) ([][]byte, error) {
+	keys := make([]string, 0, len(k))
+	for key := range k {
+		if key > string(cursor) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) > count {
+		keys = keys[:count]
+	}
+	items := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, []byte(key))
+	}
+	return items, nil
+}
+
+// the type cmds are embedded deeper than testKeys, the common key cmds of testKeys are promoted
+type (
+	testStringCmd struct{ driver.IStringCmd }
+	testListCmd   struct{ driver.IListCmd }
+	testHashCmd   struct{ driver.IHashCmd }
+	testSetCmd    struct{ driver.ISetCmd }
+	testZsetCmd   struct{ driver.IZsetCmd }
+)
+
+type testString struct {
+	testStringCmd
+	testKeys
+	vals map[string][]byte
+}
+
+func (s testString) Get(ctx context.Context, key []byte) ([]byte, error) {
+	return s.vals[string(key)], nil
+}
+
+func (s testString) Set(ctx context.Context, key []byte, value []byte) error {
+	s.vals[string(key)] = value
+	s.testKeys[string(key)] = -1
+	return nil
+}
+
+func (s testString) MSet(ctx context.Context, kvs ...driver.KVPair) error {
+	for _, kv := range kvs {
+		s.Set(ctx, kv.Key, kv.Value)
+	}
+	return nil
+}
+
+type testList struct {
+	testListCmd
+	testKeys
+	vals map[string][][]byte
+}
+
+func (l testList) pop(key []byte, left bool) []byte {
+	vals := l.vals[string(key)]
+	if len(vals) == 0 {
+		return nil
+	}
+	var v []byte
+	if left {
+		v, vals = vals[0], vals[1:]
+	} else {
+		v, vals = vals[len(vals)-1], vals[:len(vals)-1]
+	}
+	l.vals[string(key)] = vals
+	if len(vals) == 0 {
+		delete(l.vals, string(key))
+		delete(l.testKeys, string(key))
+	}
+	return v
+}
+
+func (l testList) LPop(ctx context.Context, key []byte) ([]byte, error) {
+	return l.pop(key, true), nil
+}
+
+func (l testList) RPop(ctx context.Context, key []byte) ([]byte, error) {
+	return l.pop(key, false), nil
+}
+
+type testHash struct {
+	testHashCmd
+	testKeys
+}
+
+type testSet struct {
+	testSetCmd
+	testKeys
+}
+
+type testZset struct {
+	testZsetCmd
+	testKeys
+}
+
+// testDB db of in-memory type stores which only support the common key cmds
+type testDB struct {
+	driver.IDB
+	str  testString
+	list testList
+	hash testHash
+	set  testSet
+	zset testZset
+}
+
+func newTestDB() *testDB {
+	return &testDB{
+		str:  testString{testKeys: testKeys{}, vals: map[string][]byte{}},
+		list: testList{testKeys: testKeys{}, vals: map[string][][]byte{}},
+		hash: testHash{testKeys: testKeys{}},
+		set:  testSet{testKeys: testKeys{}},
+		zset: testZset{testKeys: testKeys{}},
+	}
+}
+
+func (db *testDB) DBString() driver.IStringCmd { return db.str }
+func (db *testDB) DBList() driver.IListCmd     { return db.list }
+func (db *testDB) DBHash() driver.IHashCmd     { return db.hash }
+func (db *testDB) DBSet() driver.ISetCmd       { return db.set }
+func (db *testDB) DBZSet() driver.IZsetCmd     { return db.zset }
+
+// testConn resp conn of the db
+type testConn struct {
+	*driver.RespConnBase
+	db driver.IDB
+}
+
+func (c *testConn) Db() driver.IDB { return c.db }
+
+func TestGenericCmds(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB()
+	db.hash.testKeys["h"] = -1
+	db.zset.testKeys["z"] = 100
+	c := &testConn{RespConnBase: &driver.RespConnBase{}, db: db}
+
+	if res, _ := typeCmd(ctx, c, [][]byte{[]byte("h")}); res != redcon.SimpleString("hash") {
+		t.Fatalf("type h %v", res)
+	}
+	if res, _ := typeCmd(ctx, c, [][]byte{[]byte("x")}); res != redcon.SimpleString("none") {
+		t.Fatalf("type x %v", res)
+	}
+	if res, _ := exists(ctx, c, [][]byte{[]byte("h"), []byte("z"), []byte("h"), []byte("x")}); res != int64(3) {
+		t.Fatalf("exists %v", res)
+	}
+	if res, _ := ttl(ctx, c, [][]byte{[]byte("z")}); res != int64(100) {
+		t.Fatalf("ttl z %v", res)
+	}
+	if res, _ := ttl(ctx, c, [][]byte{[]byte("x")}); res != int64(-2) {
+		t.Fatalf("ttl x %v", res)
+	}
+	if res, _ := expire(ctx, c, [][]byte{[]byte("h"), []byte("10")}); res != int64(1) || db.hash.testKeys["h"] != 10 {
+		t.Fatalf("expire h %v", res)
+	}
+	if res, _ := persist(ctx, c, [][]byte{[]byte("h")}); res != int64(1) || db.hash.testKeys["h"] != -1 {
+		t.Fatalf("persist h %v", res)
+	}
+	if res, _ := del(ctx, c, [][]byte{[]byte("h"), []byte("z"), []byte("x")}); res != int64(2) {
+		t.Fatalf("del %v", res)
+	}
+	if len(db.hash.testKeys) != 0 || len(db.zset.testKeys) != 0 {
+		t.Fatalf("keys aren't deleted")
+	}
+}
+
+func TestExpireOptions(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB()
+	db.set.testKeys["s"] = -1
+	c := &testConn{RespConnBase: &driver.RespConnBase{}, db: db}
+	args := func(s ...string) [][]byte {
+		b := make([][]byte, 0, len(s))
+		for _, v := range s {
+			b = append(b, []byte(v))
+		}
+		return b
+	}
+
+	for _, tc := range []struct {
+		cmd  func(context.Context, driver.IRespConn, [][]byte) (interface{}, error)
+		args []string
+		res  int64
+		ttl  int64
+	}{
+		{expire, []string{"s", "100", "XX"}, 0, -1},
+		{expire, []string{"s", "100", "NX"}, 1, 100},
+		{expire, []string{"s", "200", "NX"}, 0, 100},
+		{expire, []string{"s", "50", "GT"}, 0, 100},
+		{expire, []string{"s", "200", "gt"}, 1, 200},
+		{pexpire, []string{"s", "1500", "LT"}, 1, 2},
+	} {
+		if res, err := tc.cmd(ctx, c, args(tc.args...)); err != nil || res != tc.res || db.set.testKeys["s"] != tc.ttl {
+			t.Fatalf("expire %v: %v ttl %d, err: %v", tc.args, res, db.set.testKeys["s"], err)
+		}
+	}
+	if res, _ := pttl(ctx, c, args("s")); res != int64(2000) {
+		t.Fatalf("pttl %v", res)
+	}
+	if _, err := expire(ctx, c, args("s", "1", "NX", "GT")); err != ErrExpireNXOpts {
+		t.Fatalf("expire nx gt err: %v", err)
+	}
+	if _, err := pexpire(ctx, c, args("s", "1", "GT", "LT")); err != ErrExpireGTLT {
+		t.Fatalf("pexpire gt lt err: %v", err)
+	}
+	if res, _ := expireat(ctx, c, args("s", "1")); res != int64(1) || len(db.set.testKeys) != 0 {
+		t.Fatalf("expireat in the past %v", res)
+	}
+	if res, _ := pexpiretime(ctx, c, args("s")); res != int64(-2) {
+		t.Fatalf("pexpiretime %v", res)
+	}
+}
+
+func TestScan(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB()
+	db.str.testKeys["a1"], db.str.testKeys["a2"], db.str.testKeys["b1"] = -1, -1, -1
+	db.hash.testKeys["a3"] = -1
+	c := &RespCmdConn{RespConnBase: &driver.RespConnBase{}, srv: New(config.DefaultRespCmdServiceOptions())}
+	c.SetDb(db)
+
+	scanAll := func(args ...string) (keys []string, calls int) {
+		cursor := "0"
+		for {
+			params := [][]byte{[]byte(cursor)}
+			for _, arg := range args {
+				params = append(params, []byte(arg))
+			}
+			res, err := scan(ctx, c, params)
+			if err != nil {
+				t.Fatalf("scan %v err: %v", args, err)
+			}
+			calls++
+			reply := res.([]any)
+			for _, key := range reply[1].([]any) {
+				keys = append(keys, string(key.([]byte)))
+			}
+			if cursor = string(reply[0].([]byte)); cursor == "0" {
+				return
+			}
+		}
+	}
+	if keys, calls := scanAll("COUNT", "2"); strings.Join(keys, ",") != "a1,a2,b1,a3" || calls != 3 {
+		t.Fatalf("scan keys %v in %d calls", keys, calls)
+	}
+	if keys, _ := scanAll("MATCH", "a*", "TYPE", "string"); strings.Join(keys, ",") != "a1,a2" {
+		t.Fatalf("scan match type keys %v", keys)
+	}
+	if _, err := scan(ctx, c, [][]byte{[]byte("12345")}); err != ErrScanCursor {
+		t.Fatalf("scan unknown cursor err: %v", err)
+	}
+	if _, err := hscan(ctx, c, [][]byte{[]byte("a3"), []byte("0")}); err != ErrScanNotSupported {
+		t.Fatalf("hscan err: %v", err)
+	}
+}
+
+// testStorager storager of the test dbs with keyspace stats
+type testStorager struct {
+	driver.IStorager
+	dbs []*testDB
+}
+
+func (s *testStorager) Select(ctx context.Context, index int) (driver.IDB, error) {
+	if index < 0 || index >= len(s.dbs) {
+		return nil, ErrValue
+	}
+	return s.dbs[index], nil
+}
+
+func (s *testStorager) StatsInfo(sections ...string) map[string][]driver.InfoPair {
+	pairs := []driver.InfoPair{}
+	for i, db := range s.dbs {
+		pairs = append(pairs, driver.InfoPair{Key: "db" + strconv.Itoa(i), Value: fmt.Sprintf("keys=%d,expires=0,avg_ttl=0", len(db.str.testKeys))})
+	}
+	return map[string][]driver.InfoPair{"keyspace": pairs}
+}
+
+func TestKeyspaceCmds(t *testing.T) {
+	ctx := context.Background()
+	store := &testStorager{dbs: []*testDB{newTestDB(), newTestDB()}}
+	srv := New(config.DefaultRespCmdServiceOptions())
+	srv.SetStorager(store)
+	c := srv.InitRespConn(ctx, 0).(*RespCmdConn)
+	srv.AddRespCmdConn(c)
+
+	c.Db().DBString().Set(ctx, []byte("a1"), []byte("v"))
+	c.Db().DBString().Expire(ctx, []byte("a1"), 100)
+	c.Db().DBString().Set(ctx, []byte("b1"), []byte("v"))
+	if res, _ := keys(ctx, c, [][]byte{[]byte("a*")}); len(res.([]any)) != 1 {
+		t.Fatalf("keys %v", res)
+	}
+	if res, _ := move(ctx, c, [][]byte{[]byte("a1"), []byte("1")}); res != int64(1) {
+		t.Fatalf("move %v", res)
+	}
+	if string(store.dbs[1].str.vals["a1"]) != "v" || store.dbs[1].str.testKeys["a1"] != 100 || len(store.dbs[0].str.testKeys) != 1 {
+		t.Fatalf("moved key isn't in db 1 with ttl")
+	}
+	if _, err := move(ctx, c, [][]byte{[]byte("b1"), []byte("0")}); err != ErrSameObject {
+		t.Fatalf("move to same db err: %v", err)
+	}
+	store.dbs[1].str.Set(ctx, []byte("c1"), []byte("v"))
+	if res, _ := dbsize(ctx, c, nil); res != int64(1) {
+		t.Fatalf("dbsize %v", res)
+	}
+
+	if p := srv.ttlPrecision(); p != "s" {
+		t.Fatalf("ttl precision %s of the storager without millisecond ttl", p)
+	}
+
+	if _, err := swapdb(ctx, c, [][]byte{[]byte("0"), []byte("1")}); err != ErrSwapDBNotSupported {
+		t.Fatalf("swapdb without storager support err: %v", err)
+	}
+
+	srv.SetStorager(&testSwapDBStorager{store})
+	if _, err := swapdb(ctx, c, [][]byte{[]byte("0"), []byte("1")}); err != nil {
+		t.Fatalf("swapdb err: %v", err)
+	}
+	if res, _ := dbsize(ctx, c, nil); res != int64(2) || c.Db() != store.dbs[0] {
+		t.Fatalf("dbsize %v after swapdb", res)
+	}
+	if pairs := srv.keyspaceStats(); pairs[0].Key != "db0" || pairs[0].Value != "keys=2,expires=0,avg_ttl=0" {
+		t.Fatalf("keyspace stats %v after swapdb", pairs)
+	}
+	if _, err := swapdb(ctx, c, [][]byte{[]byte("0"), []byte("2")}); err == nil {
+		t.Fatalf("swapdb out of range db")
+	}
+}
+
+// testSwapDBStorager storager which swaps the dbs in the storage
+type testSwapDBStorager struct {
+	*testStorager
+}
+
+func (s *testSwapDBStorager) SwapDB(ctx context.Context, idx1, idx2 int) error {
+	s.dbs[idx1], s.dbs[idx2] = s.dbs[idx2], s.dbs[idx1]
+	return nil
+}
@@ -78,7 +78,7 @@ func hgetall(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res i
 		return
 	}
 
-	tmp := [][]byte{}
+	tmp := make(RespMap, 0, 2*len(data))
 	for _, item := range data {
 		tmp = append(tmp, item.Field)
 		tmp = append(tmp, item.Value)
@@ -99,7 +99,10 @@ func hincrby(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res i
 		return
 	}
 
-	res, err = c.Db().DBHash().HIncrBy(ctx, cmdParams[0], cmdParams[1], delta)
+	if res, err = c.Db().DBHash().HIncrBy(ctx, cmdParams[0], cmdParams[1], delta); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyHash, "hincrby", cmdParams[0])
 	return
 }
 
@@ -149,6 +152,7 @@ func hmset(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res int
 	if err = c.Db().DBHash().HMset(ctx, cmdParams[0], kvs...); err != nil {
 		return
 	}
+	notifyKeyEvent(c, notifyHash, "hset", cmdParams[0])
 
 	res = OK
 	return
@@ -160,7 +164,10 @@ func hset(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res inte
 		return
 	}
 
-	res, err = c.Db().DBHash().HSet(ctx, cmdParams[0], cmdParams[1], cmdParams[2])
+	if res, err = c.Db().DBHash().HSet(ctx, cmdParams[0], cmdParams[1], cmdParams[2]); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyHash, "hset", cmdParams[0])
 	return
 }
 
@@ -180,7 +187,7 @@ func hmclear(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res i
 		return
 	}
 
-	res, err = c.Db().DBHash().Del(ctx, cmdParams...)
+	res, err = delKeys(ctx, c, c.Db().DBHash().Del, cmdParams)
 	return
 }
 
@@ -206,7 +213,15 @@ func hexpire(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res i
 		return
 	}
 
-	res, err = c.Db().DBHash().Expire(ctx, cmdParams[0], d)
+	n, err := c.Db().DBHash().Expire(ctx, cmdParams[0], d)
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyGeneric, "expire", cmdParams[0])
+	}
+
+	res = n
 	return
 }
 
@@ -222,7 +237,15 @@ func hexpireat(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res
 		return
 	}
 
-	res, err = c.Db().DBHash().ExpireAt(ctx, cmdParams[0], d)
+	n, err := c.Db().DBHash().ExpireAt(ctx, cmdParams[0], d)
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyGeneric, "expire", cmdParams[0])
+	}
+
+	res = n
 	return
 }
 
@@ -242,6 +265,14 @@ func hpersist(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res
 		return
 	}
 
-	res, err = c.Db().DBHash().Persist(ctx, cmdParams[0])
+	n, err := c.Db().DBHash().Persist(ctx, cmdParams[0])
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyGeneric, "persist", cmdParams[0])
+	}
+
+	res = n
 	return
 }
@@ -44,7 +44,7 @@ func lmclear(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res i
 		return
 	}
 
-	res, err = c.Db().DBList().Del(ctx, cmdParams...)
+	res, err = delKeys(ctx, c, c.Db().DBList().Del, cmdParams)
 	return
 }
 
@@ -60,7 +60,17 @@ func blpop(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res int
 	}
 	timeout := time.Duration(t * float64(time.Second))
 
-	res, err = c.Db().DBList().BLPop(ctx, cmdParams[:len(cmdParams)-1], timeout)
+	kv, err := c.Db().DBList().BLPop(ctx, cmdParams[:len(cmdParams)-1], timeout)
+	if err != nil {
+		return
+	}
+	if len(kv) == 2 {
+		if key, ok := kv[0].([]byte); ok {
+			notifyKeyEvent(c, notifyList, "lpop", key)
+		}
+	}
+
+	res = kv
 	return
 }
 
@@ -76,7 +86,74 @@ func brpop(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res int
 	}
 	timeout := time.Duration(t * float64(time.Second))
 
-	res, err = c.Db().DBList().BRPop(ctx, cmdParams[:len(cmdParams)-1], timeout)
+	kv, err := c.Db().DBList().BRPop(ctx, cmdParams[:len(cmdParams)-1], timeout)
+	if err != nil {
+		return
+	}
+	if len(kv) == 2 {
+		if key, ok := kv[0].([]byte); ok {
+			notifyKeyEvent(c, notifyList, "rpop", key)
+		}
+	}
+
+	res = kv
+	return
+}
+
+// noWaitCmds the blocking cmds run as the non-blocking ones in transaction like redis,
+// they don't wait for the keys with srv cmd write lock
+var noWaitCmds = map[string]driver.CmdHandle{
+	"blpop":      blpopNoWait,
+	"brpop":      brpopNoWait,
+	"brpoplpush": brpoplpushNoWait,
+}
+
+// BLPOP key [key ...] timeout, pop from the first non-empty list without waiting
+func blpopNoWait(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	return popFirst(ctx, c, cmdParams, true)
+}
+
+// BRPOP key [key ...] timeout, pop from the first non-empty list without waiting
+func brpopNoWait(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	return popFirst(ctx, c, cmdParams, false)
+}
+
+// BRPOPLPUSH source destination timeout, run as RPOPLPUSH
+func brpoplpushNoWait(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) != 3 {
+		err = ErrCmdParams
+		return
+	}
+	return rpoplpush(ctx, c, cmdParams[:2])
+}
+
+// popFirst pop the value from the first non-empty list of the keys (the last param is timeout),
+// reply the key and value, nil if all lists are empty
+func popFirst(ctx context.Context, c driver.IRespConn, cmdParams [][]byte, left bool) (res interface{}, err error) {
+	if len(cmdParams) < 2 {
+		err = ErrCmdParams
+		return
+	}
+
+	op := "rpop"
+	if left {
+		op = "lpop"
+	}
+	for _, key := range cmdParams[:len(cmdParams)-1] {
+		var v []byte
+		if left {
+			v, err = c.Db().DBList().LPop(ctx, key)
+		} else {
+			v, err = c.Db().DBList().RPop(ctx, key)
+		}
+		if err != nil {
+			return
+		}
+		if v != nil {
+			notifyKeyEvent(c, notifyList, op, key)
+			return []interface{}{key, v}, nil
+		}
+	}
 	return
 }
 
@@ -112,7 +189,15 @@ func lpop(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res inte
 		return
 	}
 
-	res, err = c.Db().DBList().LPop(ctx, cmdParams[0])
+	v, err := c.Db().DBList().LPop(ctx, cmdParams[0])
+	if err != nil {
+		return
+	}
+	if v != nil {
+		notifyKeyEvent(c, notifyList, "lpop", cmdParams[0])
+	}
+
+	res = v
 	return
 }
 
@@ -151,6 +236,7 @@ func lset(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res inte
 	if err = c.Db().DBList().LSet(ctx, cmdParams[0], int32(i), cmdParams[2]); err != nil {
 		return
 	}
+	notifyKeyEvent(c, notifyList, "lset", cmdParams[0])
 
 	res = OK
 	return
@@ -162,7 +248,10 @@ func lpush(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res int
 		return
 	}
 
-	res, err = c.Db().DBList().LPush(ctx, cmdParams[0], cmdParams[1:]...)
+	if res, err = c.Db().DBList().LPush(ctx, cmdParams[0], cmdParams[1:]...); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyList, "lpush", cmdParams[0])
 	return
 }
 
@@ -172,7 +261,15 @@ func rpop(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res inte
 		return
 	}
 
-	res, err = c.Db().DBList().RPop(ctx, cmdParams[0])
+	v, err := c.Db().DBList().RPop(ctx, cmdParams[0])
+	if err != nil {
+		return
+	}
+	if v != nil {
+		notifyKeyEvent(c, notifyList, "rpop", cmdParams[0])
+	}
+
+	res = v
 	return
 }
 
@@ -182,7 +279,10 @@ func rpush(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res int
 		return
 	}
 
-	res, err = c.Db().DBList().RPush(ctx, cmdParams[0], cmdParams[1:]...)
+	if res, err = c.Db().DBList().RPush(ctx, cmdParams[0], cmdParams[1:]...); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyList, "rpush", cmdParams[0])
 	return
 }
 
@@ -230,6 +330,8 @@ func brpoplpush(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (re
 		c.Db().DBList().RPush(ctx, source, vdata)
 		return
 	}
+	notifyKeyEvent(c, notifyList, "rpop", source)
+	notifyKeyEvent(c, notifyList, "lpush", dest)
 
 	// reset tll
 	if ttl != -1 {
@@ -269,6 +371,8 @@ func rpoplpush(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res
 		c.Db().DBList().RPush(ctx, source, data)
 		return
 	}
+	notifyKeyEvent(c, notifyList, "rpop", source)
+	notifyKeyEvent(c, notifyList, "lpush", dest)
 
 	// reset tll
 	if ttl != -1 {
@@ -301,7 +405,15 @@ func lexpire(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res i
 		return
 	}
 
-	res, err = c.Db().DBList().Expire(ctx, cmdParams[0], d)
+	n, err := c.Db().DBList().Expire(ctx, cmdParams[0], d)
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyGeneric, "expire", cmdParams[0])
+	}
+
+	res = n
 	return
 }
 
@@ -317,7 +429,15 @@ func lexpireat(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res
 		return
 	}
 
-	res, err = c.Db().DBList().ExpireAt(ctx, cmdParams[0], d)
+	n, err := c.Db().DBList().ExpireAt(ctx, cmdParams[0], d)
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyGeneric, "expire", cmdParams[0])
+	}
+
+	res = n
 	return
 }
 
@@ -337,6 +457,14 @@ func lpersist(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res
 		return
 	}
 
-	res, err = c.Db().DBList().Persist(ctx, cmdParams[0])
+	n, err := c.Db().DBList().Persist(ctx, cmdParams[0])
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyGeneric, "persist", cmdParams[0])
+	}
+
+	res = n
 	return
 }
@@ -0,0 +1,175 @@
+package standalone
+
+import (
+	"context"
+
+	"github.com/tidwall/redcon"
+	"github.com/weedge/pkg/driver"
+)
+
+// cmd more detail reference:
+// https://redis.io/docs/interact/transactions/
+
+func init() {
+	driver.RegisterCmd(driver.CmdTypeSrv, "multi", multi)
+	driver.RegisterCmd(driver.CmdTypeSrv, "exec", exec)
+	driver.RegisterCmd(driver.CmdTypeSrv, "discard", discard)
+	driver.RegisterCmd(driver.CmdTypeSrv, "watch", watch)
+	driver.RegisterCmd(driver.CmdTypeSrv, "unwatch", unwatch)
+}
+
+// cmds which are run directly in transaction, not queued
+var multiCtrlCmds = map[string]struct{}{
+	"multi":   {},
+	"exec":    {},
+	"discard": {},
+	"watch":   {},
+	"quit":    {},
+}
+
+// multiCmd queued cmd in transaction
+type multiCmd struct {
+	cmd    string
+	f      driver.CmdHandle
+	params [][]byte
+}
+
+// queueMultiCmd queue cmd in transaction, params are copied,
+// the read buffer of conn is reused by next cmds
+func (c *RespCmdConn) queueMultiCmd(cmd string, f driver.CmdHandle, cmdParams [][]byte) (res interface{}, err error) {
+	params := make([][]byte, len(cmdParams))
+	for i, param := range cmdParams {
+		params[i] = append([]byte{}, param...)
+	}
+	c.infoMu.Lock()
+	c.multiCmds = append(c.multiCmds, &multiCmd{cmd: cmd, f: f, params: params})
+	c.infoMu.Unlock()
+
+	res = QUEUED
+	return
+}
+
+// flagMultiErr flag transaction with queue err, EXEC is aborted
+func (c *RespCmdConn) flagMultiErr() {
+	if c.inMulti {
+		c.multiErr = true
+	}
+}
+
+func (c *RespCmdConn) InMulti() bool {
+	return c.inMulti
+}
+
+func (c *RespCmdConn) discardMulti() {
+	c.infoMu.Lock()
+	c.inMulti = false
+	c.multiCmds = nil
+	c.infoMu.Unlock()
+	c.multiErr = false
+}
+
+func multi(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+	if conn.inMulti {
+		return nil, ErrMultiNested
+	}
+
+	conn.infoMu.Lock()
+	conn.inMulti = true
+	conn.infoMu.Unlock()
+	res = OK
+	return
+}
+
+// exec run queued cmds back to back with srv cmd write lock,
+// return nil if watched keys are modified by other connection
+func exec(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+	if !conn.inMulti {
+		return nil, ErrExecNoMulti
+	}
+
+	multiCmds, multiErr := conn.multiCmds, conn.multiErr
+	conn.discardMulti()
+	defer conn.srv.unwatchKeys(conn)
+	if multiErr {
+		return nil, ErrExecAbort
+	}
+	if conn.dirtyCAS.Load() {
+		return nil, nil
+	}
+
+	data := make([]any, 0, len(multiCmds))
+	for _, mc := range multiCmds {
+		f := mc.f
+		// blocking cmd don't block in transaction
+		if nf, ok := noWaitCmds[mc.cmd]; ok {
+			f = nf
+		}
+
+		conn.feedMonitors(mc.cmd, mc.params)
+		cmdRes, cmdErr := conn.runCmd(ctx, c, mc.cmd, f, mc.params)
+		if cmdErr != nil {
+			data = append(data, cmdErr)
+			continue
+		}
+		// int64 is written as bulk string in array
+		if n, ok := cmdRes.(int64); ok {
+			cmdRes = redcon.SimpleInt(n)
+		}
+		data = append(data, cmdRes)
+	}
+
+	res = data
+	return
+}
+
+func discard(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+	if !conn.inMulti {
+		return nil, ErrDiscardNoMulti
+	}
+
+	conn.discardMulti()
+	conn.srv.unwatchKeys(conn)
+	res = OK
+	return
+}
+
+// WATCH key [key ...]
+func watch(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) == 0 {
+		return nil, ErrCmdParams
+	}
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+	if conn.inMulti {
+		return nil, ErrWatchInMulti
+	}
+
+	conn.srv.watchKeys(conn, conn.dbIdx, cmdParams...)
+	res = OK
+	return
+}
+
+func unwatch(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+
+	conn.srv.unwatchKeys(conn)
+	res = OK
+	return
+}
@@ -0,0 +1,106 @@
+package standalone
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tidwall/redcon"
+	"github.com/weedge/pkg/driver"
+	"github.com/weedge/pkg/utils"
+)
+
+// cmd more detail reference:
+// https://redis.io/commands/publish/
+// https://redis.io/commands/pubsub/
+// https://redis.io/commands/spublish/
+
+func init() {
+	driver.RegisterCmd(driver.CmdTypeSrv, "publish", publish)
+	driver.RegisterCmd(driver.CmdTypeSrv, "spublish", spublish)
+	driver.RegisterCmd(driver.CmdTypeSrv, "pubsub", pubsubCmd)
+}
+
+// PUBLISH channel message
+func publish(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) != 2 {
+		return nil, ErrCmdParams
+	}
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+
+	return conn.srv.pubSub.publish(string(cmdParams[0]), cmdParams[1]), nil
+}
+
+// SPUBLISH shardchannel message
+// the shard channel is routed to the slot owner by proxy like key
+func spublish(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) != 2 {
+		return nil, ErrCmdParams
+	}
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+
+	return conn.srv.pubSub.spublish(string(cmdParams[0]), cmdParams[1]), nil
+}
+
+// PUBSUB CHANNELS [pattern]
+// PUBSUB NUMSUB [channel [channel ...]]
+// PUBSUB NUMPAT
+// PUBSUB SHARDCHANNELS [pattern]
+// PUBSUB SHARDNUMSUB [shardchannel [shardchannel ...]]
+func pubsubCmd(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) < 1 {
+		return nil, ErrCmdParams
+	}
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+
+	ps := conn.srv.pubSub
+	op := strings.ToLower(utils.Bytes2String(cmdParams[0]))
+	args := cmdParams[1:]
+	kind := pubSubChannel
+	if strings.HasPrefix(op, "shard") {
+		kind, op = pubSubShard, strings.TrimPrefix(op, "shard")
+	}
+	switch op {
+	case "channels":
+		if len(args) > 1 {
+			return nil, ErrCmdParams
+		}
+		pattern := ""
+		if len(args) == 1 {
+			pattern = string(args[0])
+		}
+		names := ps.names(kind, pattern)
+		data := make([]any, 0, len(names))
+		for _, name := range names {
+			data = append(data, []byte(name))
+		}
+		res = data
+	case "numsub":
+		data := make(RespMap, 0, 2*len(args))
+		for _, arg := range args {
+			data = append(data, arg, redcon.SimpleInt(ps.numSub(kind, string(arg))))
+		}
+		res = data
+	case "numpat":
+		if kind == pubSubShard {
+			return nil, ErrSyntax
+		}
+		if len(args) != 0 {
+			return nil, ErrCmdParams
+		}
+		_, patterns, _, _ := ps.numbers()
+		res = int64(patterns)
+	default:
+		return nil, ErrSyntax
+	}
+
+	return
+}
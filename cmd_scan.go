@@ -0,0 +1,175 @@
+package standalone
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/tidwall/match"
+	"github.com/weedge/pkg/driver"
+	"github.com/weedge/pkg/utils"
+)
+
+// cmd more detail reference:
+// https://redis.io/commands/scan/
+
+func init() {
+	driver.RegisterCmd(driver.CmdTypeString, "scan", scan)
+	driver.RegisterCmd(driver.CmdTypeHash, "hscan", hscan)
+	driver.RegisterCmd(driver.CmdTypeSet, "sscan", sscan)
+	driver.RegisterCmd(driver.CmdTypeZset, "zscan", zscan)
+}
+
+// SCAN cursor [MATCH pattern] [COUNT count] [TYPE type]
+// the keys are scanned in the type stores one by one,
+// COUNT is the num of keys scanned (not matched) in one call like redis
+func scan(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+	sa, err := parseScanArgs(cmdParams, true)
+	if err != nil {
+		return
+	}
+	owner := "scan " + sa.keyType
+	stage, last, ok := conn.srv.scanCursors.get(sa.cursor, owner)
+	if !ok {
+		return nil, ErrScanCursor
+	}
+
+	stores := []keyType{}
+	for _, kt := range keyTypes(c.Db()) {
+		if len(sa.keyType) == 0 || kt.name == sa.keyType {
+			stores = append(stores, kt)
+		}
+	}
+	keys := []any{}
+	for n := sa.count; n > 0 && stage < len(stores); {
+		scanner, ok := stores[stage].cmd.(keyScanner)
+		if !ok {
+			return nil, ErrScanNotSupported
+		}
+		items, err := scanner.Scan(ctx, last, n, false, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range items {
+			if len(sa.match) == 0 || match.Match(utils.Bytes2String(key), sa.match) {
+				keys = append(keys, key)
+			}
+		}
+		// the type store is scanned to the end
+		if n -= len(items); n > 0 {
+			stage, last = stage+1, nil
+			continue
+		}
+		last = items[len(items)-1]
+	}
+
+	cursor := uint64(0)
+	if stage < len(stores) {
+		cursor = conn.srv.scanCursors.new(owner, stage, last)
+	}
+	res = scanReply(cursor, keys)
+	return
+}
+
+// scanKey scan the items of the key after the cursor by scanFn,
+// the item is matched by its member and appended to the reply by appendItem
+func scanKey[T any](conn *RespCmdConn, cmd string, cmdParams [][]byte,
+	scanFn func(last []byte, count int) ([]T, error), member func(item T) []byte, appendItem func(items []any, item T) []any,
+) (res interface{}, err error) {
+	if len(cmdParams) < 2 {
+		return nil, ErrCmdParams
+	}
+	sa, err := parseScanArgs(cmdParams[1:], false)
+	if err != nil {
+		return
+	}
+	owner := cmd + " " + string(cmdParams[0])
+	_, last, ok := conn.srv.scanCursors.get(sa.cursor, owner)
+	if !ok {
+		return nil, ErrScanCursor
+	}
+
+	items, err := scanFn(last, sa.count)
+	if err != nil {
+		return
+	}
+	data := []any{}
+	for _, item := range items {
+		if len(sa.match) == 0 || match.Match(utils.Bytes2String(member(item)), sa.match) {
+			data = appendItem(data, item)
+		}
+	}
+
+	cursor := uint64(0)
+	if len(items) == sa.count {
+		cursor = conn.srv.scanCursors.new(owner, 0, member(items[len(items)-1]))
+	}
+	res = scanReply(cursor, data)
+	return
+}
+
+// HSCAN key cursor [MATCH pattern] [COUNT count]
+func hscan(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+	scanner, ok := c.Db().DBHash().(hashScanner)
+	if !ok {
+		return nil, ErrScanNotSupported
+	}
+
+	return scanKey(conn, "hscan", cmdParams,
+		func(last []byte, count int) ([]driver.FVPair, error) {
+			return scanner.HScan(ctx, cmdParams[0], last, count, false, "")
+		},
+		func(item driver.FVPair) []byte { return item.Field },
+		func(items []any, item driver.FVPair) []any { return append(items, item.Field, item.Value) },
+	)
+}
+
+// SSCAN key cursor [MATCH pattern] [COUNT count]
+func sscan(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+	scanner, ok := c.Db().DBSet().(setScanner)
+	if !ok {
+		return nil, ErrScanNotSupported
+	}
+
+	return scanKey(conn, "sscan", cmdParams,
+		func(last []byte, count int) ([][]byte, error) {
+			return scanner.SScan(ctx, cmdParams[0], last, count, false, "")
+		},
+		func(item []byte) []byte { return item },
+		func(items []any, item []byte) []any { return append(items, item) },
+	)
+}
+
+// ZSCAN key cursor [MATCH pattern] [COUNT count]
+// the scores are replied as bulk strings like redis
+func zscan(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+	scanner, ok := c.Db().DBZSet().(zsetScanner)
+	if !ok {
+		return nil, ErrScanNotSupported
+	}
+
+	return scanKey(conn, "zscan", cmdParams,
+		func(last []byte, count int) ([]driver.ScorePair, error) {
+			return scanner.ZScan(ctx, cmdParams[0], last, count, false, "")
+		},
+		func(item driver.ScorePair) []byte { return item.Member },
+		func(items []any, item driver.ScorePair) []any {
+			return append(items, item.Member, []byte(strconv.FormatInt(item.Score, 10)))
+		},
+	)
+}
@@ -0,0 +1,148 @@
+package standalone
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/redcon"
+	"github.com/weedge/pkg/driver"
+	"github.com/weedge/pkg/utils"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// cmd more detail reference:
+// https://redis.io/commands/?group=scripting
+
+func init() {
+	driver.RegisterCmd(driver.CmdTypeSrv, "eval", eval)
+	driver.RegisterCmd(driver.CmdTypeSrv, "evalsha", evalsha)
+	driver.RegisterCmd(driver.CmdTypeSrv, "script", script)
+}
+
+// EVAL script numkeys [key [key ...]] [arg [arg ...]]
+func eval(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) < 2 {
+		return nil, ErrCmdParams
+	}
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+
+	_, proto, err := conn.srv.scripts.Load(string(cmdParams[0]))
+	if err != nil {
+		return
+	}
+
+	return evalScript(ctx, conn, proto, cmdParams[1:])
+}
+
+// EVALSHA sha1 numkeys [key [key ...]] [arg [arg ...]]
+func evalsha(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) < 2 {
+		return nil, ErrCmdParams
+	}
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+
+	proto := conn.srv.scripts.Get(utils.Bytes2String(cmdParams[0]))
+	if proto == nil {
+		return nil, ErrNoScript
+	}
+
+	return evalScript(ctx, conn, proto, cmdParams[1:])
+}
+
+// evalScript run script with params: numkeys [key [key ...]] [arg [arg ...]]
+func evalScript(ctx context.Context, conn *RespCmdConn, proto *lua.FunctionProto, params [][]byte) (res interface{}, err error) {
+	numKeys, err := strconv.Atoi(utils.Bytes2String(params[0]))
+	if err != nil {
+		return nil, ErrValue
+	}
+	if numKeys < 0 {
+		return nil, ErrScriptNegKeys
+	}
+	if numKeys > len(params)-1 {
+		return nil, ErrScriptNumKeys
+	}
+
+	res, err = conn.runScript(ctx, proto, params[1:1+numKeys], params[1+numKeys:])
+	if err != nil {
+		return
+	}
+	// script return {err=...}
+	if e, ok := res.(error); ok {
+		return nil, e
+	}
+	return
+}
+
+// SCRIPT LOAD script
+// SCRIPT EXISTS sha1 [sha1 ...]
+// SCRIPT FLUSH [ASYNC | SYNC]
+// SCRIPT KILL
+func script(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) < 1 {
+		return nil, ErrCmdParams
+	}
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+
+	scripts := conn.srv.scripts
+	op := strings.ToLower(utils.Bytes2String(cmdParams[0]))
+	args := cmdParams[1:]
+	switch op {
+	case "load":
+		if len(args) != 1 {
+			return nil, ErrCmdParams
+		}
+		sha, _, err := scripts.Load(string(args[0]))
+		if err != nil {
+			return nil, err
+		}
+		res = sha
+	case "exists":
+		if len(args) < 1 {
+			return nil, ErrCmdParams
+		}
+		data := make([]any, 0, len(args))
+		for _, sha := range args {
+			n := 0
+			if scripts.Exists(utils.Bytes2String(sha)) {
+				n = 1
+			}
+			data = append(data, redcon.SimpleInt(n))
+		}
+		res = data
+	case "flush":
+		if len(args) > 1 {
+			return nil, ErrCmdParams
+		}
+		if len(args) == 1 {
+			mode := strings.ToLower(utils.Bytes2String(args[0]))
+			if mode != "async" && mode != "sync" {
+				return nil, ErrSyntax
+			}
+		}
+		scripts.Flush()
+		res = OK
+	case "kill":
+		if len(args) != 0 {
+			return nil, ErrCmdParams
+		}
+		if err = scripts.Kill(); err != nil {
+			return
+		}
+		res = OK
+	default:
+		return nil, fmt.Errorf("ERR unknown subcommand '%s'. Try SCRIPT HELP", op)
+	}
+
+	return
+}
@@ -40,7 +40,15 @@ func sadd(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res inte
 		return
 	}
 
-	res, err = c.Db().DBSet().SAdd(ctx, cmdParams[0], cmdParams[1:]...)
+	n, err := c.Db().DBSet().SAdd(ctx, cmdParams[0], cmdParams[1:]...)
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifySet, "sadd", cmdParams[0])
+	}
+
+	res = n
 	return
 }
 
@@ -60,7 +68,12 @@ func sdiff(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res int
 		return
 	}
 
-	res, err = c.Db().DBSet().SDiff(ctx, cmdParams...)
+	members, err := c.Db().DBSet().SDiff(ctx, cmdParams...)
+	if err != nil {
+		return
+	}
+
+	res = bytesSet(members)
 	return
 }
 
@@ -70,7 +83,10 @@ func sdiffstore(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (re
 		return
 	}
 
-	res, err = c.Db().DBSet().SDiffStore(ctx, cmdParams[0], cmdParams[1:]...)
+	if res, err = c.Db().DBSet().SDiffStore(ctx, cmdParams[0], cmdParams[1:]...); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifySet, "sdiffstore", cmdParams[0])
 	return
 }
 
@@ -80,7 +96,12 @@ func sinter(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res in
 		return
 	}
 
-	res, err = c.Db().DBSet().SInter(ctx, cmdParams...)
+	members, err := c.Db().DBSet().SInter(ctx, cmdParams...)
+	if err != nil {
+		return
+	}
+
+	res = bytesSet(members)
 	return
 }
 
@@ -90,7 +111,10 @@ func sinterstore(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (r
 		return
 	}
 
-	res, err = c.Db().DBSet().SInterStore(ctx, cmdParams[0], cmdParams[1:]...)
+	if res, err = c.Db().DBSet().SInterStore(ctx, cmdParams[0], cmdParams[1:]...); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifySet, "sinterstore", cmdParams[0])
 	return
 }
 
@@ -110,7 +134,12 @@ func smembers(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res
 		return
 	}
 
-	res, err = c.Db().DBSet().SMembers(ctx, cmdParams[0])
+	members, err := c.Db().DBSet().SMembers(ctx, cmdParams[0])
+	if err != nil {
+		return
+	}
+
+	res = bytesSet(members)
 	return
 }
 
@@ -120,7 +149,15 @@ func srem(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res inte
 		return
 	}
 
-	res, err = c.Db().DBSet().SRem(ctx, cmdParams[0], cmdParams[1:]...)
+	n, err := c.Db().DBSet().SRem(ctx, cmdParams[0], cmdParams[1:]...)
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifySet, "srem", cmdParams[0])
+	}
+
+	res = n
 	return
 }
 
@@ -130,7 +167,12 @@ func sunion(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res in
 		return
 	}
 
-	res, err = c.Db().DBSet().SUnion(ctx, cmdParams...)
+	members, err := c.Db().DBSet().SUnion(ctx, cmdParams...)
+	if err != nil {
+		return
+	}
+
+	res = bytesSet(members)
 	return
 }
 
@@ -140,7 +182,10 @@ func sunionstore(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (r
 		return
 	}
 
-	res, err = c.Db().DBSet().SUnionStore(ctx, cmdParams[0], cmdParams[1:]...)
+	if res, err = c.Db().DBSet().SUnionStore(ctx, cmdParams[0], cmdParams[1:]...); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifySet, "sunionstore", cmdParams[0])
 	return
 }
 
@@ -150,7 +195,7 @@ func smclear(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res i
 		return
 	}
 
-	res, err = c.Db().DBSet().Del(ctx, cmdParams...)
+	res, err = delKeys(ctx, c, c.Db().DBSet().Del, cmdParams)
 	return
 }
 
@@ -176,7 +221,15 @@ func sexpire(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res i
 		return
 	}
 
-	res, err = c.Db().DBSet().Expire(ctx, cmdParams[0], d)
+	n, err := c.Db().DBSet().Expire(ctx, cmdParams[0], d)
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyGeneric, "expire", cmdParams[0])
+	}
+
+	res = n
 	return
 }
 
@@ -192,7 +245,15 @@ func sexpireat(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res
 		return
 	}
 
-	res, err = c.Db().DBSet().ExpireAt(ctx, cmdParams[0], d)
+	n, err := c.Db().DBSet().ExpireAt(ctx, cmdParams[0], d)
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyGeneric, "expire", cmdParams[0])
+	}
+
+	res = n
 	return
 }
 
@@ -212,6 +273,14 @@ func spersist(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res
 		return
 	}
 
-	res, err = c.Db().DBSet().Persist(ctx, cmdParams[0])
+	n, err := c.Db().DBSet().Persist(ctx, cmdParams[0])
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyGeneric, "persist", cmdParams[0])
+	}
+
+	res = n
 	return
 }
@@ -92,6 +92,21 @@ func slotsInfoCmd(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (
 	return
 }
 
+// slotsMgrtStat record the slots migrate stats of srv
+func slotsMgrtStat(c driver.IRespConn, cmd string, keys int64) {
+	if conn, ok := c.(*RespCmdConn); ok {
+		conn.srv.stats.slotsMgrt(cmd, keys)
+	}
+}
+
+// slotsMoved the slots are migrated out or deleted,
+// the shard channel subscribers of the slots are told to resubscribe by sunsubscribe
+func slotsMoved(c driver.IRespConn, slots ...uint64) {
+	if conn, ok := c.(*RespCmdConn); ok {
+		conn.srv.pubSub.unsubscribeSlots(slots...)
+	}
+}
+
 func parseMgrtArgs(cmdParams [][]byte) (addr string, timeout time.Duration, err error) {
 	if len(cmdParams) != 4 {
 		err = ErrCmdParams
@@ -131,6 +146,7 @@ func slotsMgrtOneCmd(ctx context.Context, c driver.IRespConn, cmdParams [][]byte
 	if err != nil {
 		return nil, err
 	}
+	slotsMgrtStat(c, "slotsmgrtone", int64(migrateCn))
 	res = redcon.SimpleInt(migrateCn)
 
 	return
@@ -152,6 +168,11 @@ func slotsMgrtSlotCmd(ctx context.Context, c driver.IRespConn, cmdParams [][]byt
 	if err != nil {
 		return 0, err
 	}
+	slotsMgrtStat(c, "slotsmgrtslot", int64(migrateCn))
+	// no key is left in the slot
+	if migrateCn == 0 {
+		slotsMoved(c, uint64(slot))
+	}
 	res = redcon.SimpleInt(migrateCn)
 
 	return
@@ -169,6 +190,7 @@ func slotsMgrtTagOneCmd(ctx context.Context, c driver.IRespConn, cmdParams [][]b
 	if err != nil {
 		return nil, err
 	}
+	slotsMgrtStat(c, "slotsmgrttagone", int64(migrateCn))
 	res = redcon.SimpleInt(migrateCn)
 
 	return
@@ -190,6 +212,7 @@ func slotsMgrtTagSlotCmd(ctx context.Context, c driver.IRespConn, cmdParams [][]
 	if err != nil {
 		return 0, err
 	}
+	slotsMgrtStat(c, "slotsmgrttagslot", int64(migrateCn))
 
 	slotsInfo, err := c.Db().(driver.IDBSlots).DBSlot().SlotsInfo(ctx, uint64(slot), 0, true)
 	if err != nil {
@@ -201,6 +224,9 @@ func slotsMgrtTagSlotCmd(ctx context.Context, c driver.IRespConn, cmdParams [][]
 	if len(slotsInfo) == 1 {
 		data[1] = redcon.SimpleInt(slotsInfo[0].Size)
 	}
+	if data[1] == redcon.SimpleInt(0) {
+		slotsMoved(c, uint64(slot))
+	}
 
 	res = data
 	return
@@ -277,6 +303,7 @@ func slotsDelCmd(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (r
 	if err != nil {
 		return nil, err
 	}
+	slotsMoved(c, slots...)
 	data := make([]any, len(slotsInfo))
 	for i := 0; i < len(slotsInfo); i++ {
 		data[i] = []any{
@@ -0,0 +1,81 @@
+package standalone
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/redcon"
+	"github.com/weedge/pkg/driver"
+	"github.com/weedge/pkg/utils"
+)
+
+// cmd more detail reference:
+// https://redis.io/commands/slowlog/
+
+func init() {
+	driver.RegisterCmd(driver.CmdTypeSrv, "slowlog", slowlog)
+}
+
+// SLOWLOG GET [count]
+// SLOWLOG LEN
+// SLOWLOG RESET
+func slowlog(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) < 1 {
+		return nil, ErrCmdParams
+	}
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+
+	sl := conn.srv.slowLog
+	op := strings.ToLower(utils.Bytes2String(cmdParams[0]))
+	args := cmdParams[1:]
+	switch op {
+	case "get":
+		if len(args) > 1 {
+			return nil, ErrCmdParams
+		}
+		n := 10
+		if len(args) == 1 {
+			if n, err = strconv.Atoi(utils.Bytes2String(args[0])); err != nil || n < -1 {
+				return nil, fmt.Errorf("ERR count should be greater than or equal to -1")
+			}
+		}
+
+		entries := sl.Get(n)
+		data := make([]any, 0, len(entries))
+		for _, entry := range entries {
+			entryArgs := make([]any, 0, len(entry.Args))
+			for _, arg := range entry.Args {
+				entryArgs = append(entryArgs, arg)
+			}
+			data = append(data, []any{
+				redcon.SimpleInt(entry.ID),
+				redcon.SimpleInt(entry.Timestamp.Unix()),
+				redcon.SimpleInt(entry.Duration),
+				entryArgs,
+				entry.ClientAddr,
+				entry.ClientName,
+			})
+		}
+		res = data
+	case "len":
+		if len(args) != 0 {
+			return nil, ErrCmdParams
+		}
+		res = int64(sl.Len())
+	case "reset":
+		if len(args) != 0 {
+			return nil, ErrCmdParams
+		}
+		sl.Reset()
+		res = OK
+	default:
+		return nil, fmt.Errorf("ERR unknown subcommand '%s'. Try SLOWLOG HELP", op)
+	}
+
+	return
+}
@@ -0,0 +1,331 @@
+package standalone
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/weedge/pkg/utils"
+)
+
+type cmdFlag uint32
+
+const (
+	// cmd may modify the keyspace
+	cmdFlagWrite cmdFlag = 1 << iota
+	// cmd may block the connection, run without srv cmd lock
+	cmdFlagBlocking
+	// cmd runs exclusively with srv cmd lock, e.g. EXEC
+	cmdFlagExclusive
+	// cmd runs without srv cmd lock, e.g. SCRIPT KILL the running script
+	cmdFlagNoLock
+	// cmd is not allowed from script
+	cmdFlagNoScript
+)
+
+// cmdSpec cmd arity, flags and key positions in the cmd args (cmd name is at index 0),
+// more detail reference:
+// https://redis.io/docs/reference/command-arity/
+// https://redis.io/docs/reference/key-specs/
+type cmdSpec struct {
+	// number of args include cmd name, -N means >= N
+	arity int
+	flags cmdFlag
+	// first key index, 0 means cmd has no key
+	firstKey int
+	// last key index, negative is counted from the end, -1 is the last arg
+	lastKey int
+	// step between keys
+	keyStep int
+	// getKeys for cmd which keys can't be described by first/last/step
+	getKeys func(cmdParams [][]byte) [][]byte
+	// exclusive for cmd which runs exclusively only with some args, e.g. SET with options
+	exclusive func(cmdParams [][]byte) bool
+}
+
+func spec(arity int, flags cmdFlag, firstKey, lastKey, keyStep int) *cmdSpec {
+	return &cmdSpec{arity: arity, flags: flags, firstKey: firstKey, lastKey: lastKey, keyStep: keyStep}
+}
+
+// cmd spec flags short name for cmd specs table
+const (
+	readCmd      = cmdFlag(0)
+	writeCmd     = cmdFlagWrite
+	blockingCmd  = cmdFlagWrite | cmdFlagBlocking
+	exclusiveCmd = cmdFlagExclusive
+)
+
+var cmdSpecs = map[string]*cmdSpec{
+	// srv
+	"auth":     spec(-2, readCmd|cmdFlagNoScript, 0, 0, 0),
+	"client":   spec(-2, readCmd|cmdFlagNoScript, 0, 0, 0),
+	"echo":     spec(2, readCmd, 0, 0, 0),
+	"hello":    spec(-1, readCmd|cmdFlagNoScript, 0, 0, 0),
+	"ping":     spec(-1, readCmd, 0, 0, 0),
+	"select":   spec(2, readCmd, 0, 0, 0),
+	"flushdb":  spec(-1, writeCmd, 0, 0, 0),
+	"flushall": spec(-1, writeCmd, 0, 0, 0),
+	"dbsize":   spec(1, readCmd, 0, 0, 0),
+	"swapdb":   spec(3, writeCmd|exclusiveCmd, 0, 0, 0),
+	"acl":      spec(-2, readCmd|cmdFlagNoScript, 0, 0, 0),
+	"multi":    spec(1, readCmd|cmdFlagNoScript, 0, 0, 0),
+	"exec":     spec(1, exclusiveCmd|cmdFlagNoScript, 0, 0, 0),
+	"discard":  spec(1, readCmd|cmdFlagNoScript, 0, 0, 0),
+	"watch":    spec(-2, readCmd|cmdFlagNoScript, 1, -1, 1),
+	"unwatch":  spec(1, readCmd|cmdFlagNoScript, 0, 0, 0),
+	"eval":     {arity: -3, flags: exclusiveCmd | cmdFlagNoScript, getKeys: evalKeys},
+	"evalsha":  {arity: -3, flags: exclusiveCmd | cmdFlagNoScript, getKeys: evalKeys},
+	"script":   spec(-2, cmdFlagNoLock|cmdFlagNoScript, 0, 0, 0),
+	"config":   spec(-2, exclusiveCmd|cmdFlagNoScript, 0, 0, 0),
+	"slowlog":  spec(-2, readCmd, 0, 0, 0),
+	"shutdown": spec(-1, cmdFlagNoLock|cmdFlagNoScript, 0, 0, 0),
+	"publish":  spec(3, readCmd, 0, 0, 0),
+	"spublish": spec(3, readCmd, 0, 0, 0),
+	"pubsub":   spec(-2, readCmd, 0, 0, 0),
+
+	// string
+	"append":      spec(3, writeCmd, 1, 1, 1),
+	"decr":        spec(2, writeCmd, 1, 1, 1),
+	"decrby":      spec(3, writeCmd, 1, 1, 1),
+	"get":         spec(2, readCmd, 1, 1, 1),
+	"getrange":    spec(4, readCmd, 1, 1, 1),
+	"getset":      spec(3, writeCmd, 1, 1, 1),
+	"incr":        spec(2, writeCmd, 1, 1, 1),
+	"incrby":      spec(3, writeCmd, 1, 1, 1),
+	"mget":        spec(-2, readCmd, 1, -1, 1),
+	"mset":        spec(-3, writeCmd, 1, -1, 2),
+	"set":         {arity: -3, flags: writeCmd, firstKey: 1, lastKey: 1, keyStep: 1, exclusive: optsExclusive(2)},
+	"setnx":       spec(3, writeCmd, 1, 1, 1),
+	"setex":       spec(4, writeCmd, 1, 1, 1),
+	"setrange":    spec(4, writeCmd, 1, 1, 1),
+	"strlen":      spec(2, readCmd, 1, 1, 1),
+	"setnxex":     spec(4, writeCmd, 1, 1, 1),
+	"setxxex":     spec(4, writeCmd, 1, 1, 1),
+	"psetex":      spec(4, writeCmd, 1, 1, 1),
+	"getex":       {arity: -2, flags: writeCmd, firstKey: 1, lastKey: 1, keyStep: 1, exclusive: optsExclusive(1)},
+	"getdel":      spec(2, writeCmd|exclusiveCmd, 1, 1, 1),
+	"msetnx":      spec(-3, writeCmd|exclusiveCmd, 1, -1, 2),
+	"incrbyfloat": spec(3, writeCmd|exclusiveCmd, 1, 1, 1),
+	"lcs":         spec(-3, readCmd, 1, 2, 1),
+
+	// generic
+	"del":         spec(-2, writeCmd, 1, -1, 1),
+	"exists":      spec(-2, readCmd, 1, -1, 1),
+	"expire":      {arity: -3, flags: writeCmd, firstKey: 1, lastKey: 1, keyStep: 1, exclusive: optsExclusive(2)},
+	"expireat":    {arity: -3, flags: writeCmd, firstKey: 1, lastKey: 1, keyStep: 1, exclusive: optsExclusive(2)},
+	"pexpire":     {arity: -3, flags: writeCmd, firstKey: 1, lastKey: 1, keyStep: 1, exclusive: optsExclusive(2)},
+	"pexpireat":   {arity: -3, flags: writeCmd, firstKey: 1, lastKey: 1, keyStep: 1, exclusive: optsExclusive(2)},
+	"persist":     spec(2, writeCmd, 1, 1, 1),
+	"ttl":         spec(2, readCmd, 1, 1, 1),
+	"pttl":        spec(2, readCmd, 1, 1, 1),
+	"expiretime":  spec(2, readCmd, 1, 1, 1),
+	"pexpiretime": spec(2, readCmd, 1, 1, 1),
+	"type":        spec(2, readCmd, 1, 1, 1),
+	"scan":        spec(-2, readCmd, 0, 0, 0),
+	"keys":        spec(2, readCmd, 0, 0, 0),
+	"randomkey":   spec(1, readCmd, 0, 0, 0),
+	"rename":      spec(3, writeCmd|exclusiveCmd, 1, 2, 1),
+	"renamenx":    spec(3, writeCmd|exclusiveCmd, 1, 2, 1),
+	"copy":        spec(-3, writeCmd|exclusiveCmd, 1, 2, 1),
+	"move":        spec(3, writeCmd|exclusiveCmd, 1, 1, 1),
+
+	// bitmap
+	"bitcount": spec(-2, readCmd, 1, 1, 1),
+	"bitop":    spec(-4, writeCmd, 2, -1, 1),
+	"bitpos":   spec(-3, readCmd, 1, 1, 1),
+	"getbit":   spec(3, readCmd, 1, 1, 1),
+	"setbit":   spec(4, writeCmd, 1, 1, 1),
+
+	// hash
+	"hexists":    spec(3, readCmd, 1, 1, 1),
+	"hget":       spec(3, readCmd, 1, 1, 1),
+	"hgetall":    spec(2, readCmd, 1, 1, 1),
+	"hincrby":    spec(4, writeCmd, 1, 1, 1),
+	"hkeys":      spec(2, readCmd, 1, 1, 1),
+	"hlen":       spec(2, readCmd, 1, 1, 1),
+	"hmget":      spec(-3, readCmd, 1, 1, 1),
+	"hmset":      spec(-4, writeCmd, 1, 1, 1),
+	"hset":       spec(4, writeCmd, 1, 1, 1),
+	"hvals":      spec(2, readCmd, 1, 1, 1),
+	"hscan":      spec(-3, readCmd, 1, 1, 1),
+	"hmclear":    spec(-2, writeCmd, 1, -1, 1),
+	"hkeyexists": spec(2, readCmd, 1, 1, 1),
+	"hexpire":    spec(3, writeCmd, 1, 1, 1),
+	"hexpireat":  spec(3, writeCmd, 1, 1, 1),
+	"httl":       spec(2, readCmd, 1, 1, 1),
+	"hpersist":   spec(2, writeCmd, 1, 1, 1),
+
+	// list
+	"blpop":      spec(-3, blockingCmd, 1, -2, 1),
+	"brpop":      spec(-3, blockingCmd, 1, -2, 1),
+	"lindex":     spec(3, readCmd, 1, 1, 1),
+	"llen":       spec(2, readCmd, 1, 1, 1),
+	"lpop":       spec(2, writeCmd, 1, 1, 1),
+	"lrange":     spec(4, readCmd, 1, 1, 1),
+	"lset":       spec(4, writeCmd, 1, 1, 1),
+	"lpush":      spec(-3, writeCmd, 1, 1, 1),
+	"rpop":       spec(2, writeCmd, 1, 1, 1),
+	"rpush":      spec(-3, writeCmd, 1, 1, 1),
+	"brpoplpush": spec(4, blockingCmd, 1, 2, 1),
+	"rpoplpush":  spec(3, writeCmd, 1, 2, 1),
+	"lmclear":    spec(-2, writeCmd, 1, -1, 1),
+	"lkeyexists": spec(2, readCmd, 1, 1, 1),
+	"lexpire":    spec(3, writeCmd, 1, 1, 1),
+	"lexpireat":  spec(3, writeCmd, 1, 1, 1),
+	"lttl":       spec(2, readCmd, 1, 1, 1),
+	"lpersist":   spec(2, writeCmd, 1, 1, 1),
+
+	// set
+	"sadd":        spec(-3, writeCmd, 1, 1, 1),
+	"scard":       spec(2, readCmd, 1, 1, 1),
+	"sdiff":       spec(-2, readCmd, 1, -1, 1),
+	"sdiffstore":  spec(-3, writeCmd, 1, -1, 1),
+	"sinter":      spec(-2, readCmd, 1, -1, 1),
+	"sinterstore": spec(-3, writeCmd, 1, -1, 1),
+	"sismember":   spec(3, readCmd, 1, 1, 1),
+	"smembers":    spec(2, readCmd, 1, 1, 1),
+	"srem":        spec(-3, writeCmd, 1, 1, 1),
+	"sunion":      spec(-2, readCmd, 1, -1, 1),
+	"sunionstore": spec(-3, writeCmd, 1, -1, 1),
+	"sscan":       spec(-3, readCmd, 1, 1, 1),
+	"smclear":     spec(-2, writeCmd, 1, -1, 1),
+	"sexpire":     spec(3, writeCmd, 1, 1, 1),
+	"sexpireat":   spec(3, writeCmd, 1, 1, 1),
+	"sttl":        spec(2, readCmd, 1, 1, 1),
+	"spersist":    spec(2, writeCmd, 1, 1, 1),
+	"skeyexists":  spec(2, readCmd, 1, 1, 1),
+
+	// zset
+	"zadd":             spec(-4, writeCmd, 1, 1, 1),
+	"zcard":            spec(2, readCmd, 1, 1, 1),
+	"zcount":           spec(4, readCmd, 1, 1, 1),
+	"zincrby":          spec(4, writeCmd, 1, 1, 1),
+	"zrange":           spec(-4, readCmd, 1, 1, 1),
+	"zrangebyscore":    spec(-4, readCmd, 1, 1, 1),
+	"zrank":            spec(3, readCmd, 1, 1, 1),
+	"zrem":             spec(-3, writeCmd, 1, 1, 1),
+	"zremrangebyrank":  spec(4, writeCmd, 1, 1, 1),
+	"zremrangebyscore": spec(4, writeCmd, 1, 1, 1),
+	"zrevrange":        spec(-4, readCmd, 1, 1, 1),
+	"zrevrank":         spec(3, readCmd, 1, 1, 1),
+	"zrevrangebyscore": spec(-4, readCmd, 1, 1, 1),
+	"zscore":           spec(3, readCmd, 1, 1, 1),
+	"zunionstore":      {arity: -4, flags: writeCmd, getKeys: zstoreKeys},
+	"zinterstore":      {arity: -4, flags: writeCmd, getKeys: zstoreKeys},
+	"zrangebylex":      spec(-4, readCmd, 1, 1, 1),
+	"zremrangebylex":   spec(4, writeCmd, 1, 1, 1),
+	"zlexcount":        spec(4, readCmd, 1, 1, 1),
+	"zscan":            spec(-3, readCmd, 1, 1, 1),
+	"zmclear":          spec(-2, writeCmd, 1, -1, 1),
+	"zexpire":          spec(3, writeCmd, 1, 1, 1),
+	"zexpireat":        spec(3, writeCmd, 1, 1, 1),
+	"zttl":             spec(2, readCmd, 1, 1, 1),
+	"zpersist":         spec(2, writeCmd, 1, 1, 1),
+	"zkeyexists":       spec(2, readCmd, 1, 1, 1),
+
+	// slot
+	"slotshashkey":     spec(-2, readCmd, 1, -1, 1),
+	"slotsinfo":        spec(-1, readCmd, 0, 0, 0),
+	"slotsdel":         spec(-2, writeCmd, 0, 0, 0),
+	"slotscheck":       spec(1, readCmd, 0, 0, 0),
+	"slotsrestore":     spec(-4, writeCmd, 1, -1, 3),
+	"slotsmgrtone":     spec(5, writeCmd, 4, 4, 1),
+	"slotsmgrtslot":    spec(5, writeCmd, 0, 0, 0),
+	"slotsmgrttagone":  spec(5, writeCmd, 4, 4, 1),
+	"slotsmgrttagslot": spec(5, writeCmd, 0, 0, 0),
+}
+
+// ZUNIONSTORE/ZINTERSTORE destination numkeys key [key ...] ...
+func zstoreKeys(cmdParams [][]byte) (keys [][]byte) {
+	if len(cmdParams) < 2 {
+		return
+	}
+
+	keys = append(keys, cmdParams[0])
+	n, err := strconv.Atoi(utils.Bytes2String(cmdParams[1]))
+	if err != nil || n <= 0 || n > len(cmdParams)-2 {
+		return
+	}
+	keys = append(keys, cmdParams[2:2+n]...)
+
+	return
+}
+
+// optsExclusive the cmd runs exclusively if it has options after the n params,
+// the options are read-then-write sequences, e.g. SET XX checks the key exists then sets it,
+// GETEX gets the key then sets its ttl, EXPIRE NX|XX|GT|LT checks the ttl then sets it
+func optsExclusive(n int) func(cmdParams [][]byte) bool {
+	return func(cmdParams [][]byte) bool {
+		return len(cmdParams) > n
+	}
+}
+
+// EVAL/EVALSHA script numkeys [key [key ...]] [arg [arg ...]]
+func evalKeys(cmdParams [][]byte) (keys [][]byte) {
+	if len(cmdParams) < 2 {
+		return
+	}
+
+	n, err := strconv.Atoi(utils.Bytes2String(cmdParams[1]))
+	if err != nil || n <= 0 || n > len(cmdParams)-2 {
+		return
+	}
+	keys = cmdParams[2 : 2+n]
+
+	return
+}
+
+// cmdKeys get the keys from cmd params (without cmd name) by cmd spec,
+// return nil if the cmd has no key
+func cmdKeys(cmd string, cmdParams [][]byte) (keys [][]byte) {
+	spec, ok := cmdSpecs[cmd]
+	if !ok {
+		return
+	}
+	if spec.getKeys != nil {
+		return spec.getKeys(cmdParams)
+	}
+	if spec.firstKey <= 0 {
+		return
+	}
+
+	argc := len(cmdParams) + 1
+	last := spec.lastKey
+	if last < 0 {
+		last = argc + last
+	}
+	for i := spec.firstKey; i <= last && i < argc; i += spec.keyStep {
+		keys = append(keys, cmdParams[i-1])
+	}
+
+	return
+}
+
+// cmdExclusive check the cmd whether runs exclusively with the params
+func cmdExclusive(cmd string, cmdParams [][]byte) bool {
+	spec, ok := cmdSpecs[cmd]
+	if !ok {
+		return false
+	}
+	return spec.flags&cmdFlagExclusive != 0 || spec.exclusive != nil && spec.exclusive(cmdParams)
+}
+
+// cmdHasFlag check the cmd spec whether has the flag
+func cmdHasFlag(cmd string, flag cmdFlag) bool {
+	spec, ok := cmdSpecs[cmd]
+	return ok && spec.flags&flag != 0
+}
+
+// checkCmdArity check the cmd params (without cmd name) number by cmd spec arity,
+// cmd without spec is not checked
+func checkCmdArity(cmd string, cmdParams [][]byte) error {
+	spec, ok := cmdSpecs[cmd]
+	if !ok {
+		return nil
+	}
+
+	argc := len(cmdParams) + 1
+	if (spec.arity > 0 && argc != spec.arity) || (spec.arity < 0 && argc < -spec.arity) {
+		return fmt.Errorf("ERR wrong number of arguments for '%s' command", cmd)
+	}
+	return nil
+}
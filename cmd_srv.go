@@ -14,33 +14,56 @@ import (
 
 func init() {
 	// not use storage
+	driver.RegisterCmd(driver.CmdTypeSrv, "auth", auth)
 	driver.RegisterCmd(driver.CmdTypeSrv, "client", client)
 	driver.RegisterCmd(driver.CmdTypeSrv, "echo", echo)
 	driver.RegisterCmd(driver.CmdTypeSrv, "hello", hello)
 	driver.RegisterCmd(driver.CmdTypeSrv, "ping", ping)
+	driver.RegisterCmd(driver.CmdTypeSrv, "shutdown", shutdown)
 
 	// need use storage
 	driver.RegisterCmd(driver.CmdTypeSrv, "select", selectCmd)
 	driver.RegisterCmd(driver.CmdTypeSrv, "flushdb", flushdb)
 	driver.RegisterCmd(driver.CmdTypeSrv, "flushall", flushall)
+	driver.RegisterCmd(driver.CmdTypeSrv, "dbsize", dbsize)
+	driver.RegisterCmd(driver.CmdTypeSrv, "swapdb", swapdb)
 }
 
-func authUser(ctx context.Context, c driver.IRespConn, pwd string) (err error) {
+// authUser check user password with srv acl users
+func authUser(ctx context.Context, c driver.IRespConn, user, pwd string) (err error) {
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return ErrNoInitRespConn
+	}
+
+	u := conn.srv.acl.GetUser(user)
+	if u != nil && u.Name == DefaultAclUserName && u.NoPass {
+		return ErrNOPwd
+	}
+	if u == nil || !u.Enabled || !u.CheckPassword(pwd) {
+		conn.srv.acl.AddLog(AclLogReasonAuth, "AUTH", user, conn.clientInfo())
+		return ErrInvalidPwd
+	}
+	conn.setAuthed(u.Name)
+
 	return
 }
 
-func client(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
-	if len(cmdParams) < 1 {
+// AUTH [username] password
+func auth(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) < 1 || len(cmdParams) > 2 {
 		return nil, ErrCmdParams
 	}
-	op := strings.ToLower(utils.Bytes2String(cmdParams[0]))
-	switch op {
-	case "getname":
-		res = c.Name()
-	default:
-		//todo
+
+	user, pwd := "default", utils.Bytes2String(cmdParams[0])
+	if len(cmdParams) == 2 {
+		user, pwd = utils.Bytes2String(cmdParams[0]), utils.Bytes2String(cmdParams[1])
+	}
+	if err = authUser(ctx, c, user, pwd); err != nil {
+		return nil, err
 	}
 
+	res = OK
 	return
 }
 
@@ -51,21 +74,16 @@ func hello(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res int
 		return nil, fmt.Errorf("%s in %s option '%s'", ErrSyntax.Error(), "HELLO", op)
 	}
 
-	/*
-		data := map[string]any{
-			"server": "redis",
-			"proto":  redcon.SimpleInt(2),
-			"mode":   c.srv.opts.RespCmdSrvOpts.Mode,
-		}
-	*/
-	data := []any{
-		"server", "redis",
-		"proto", redcon.SimpleInt(2),
-		"mode", config.RegisterRespSrvModeName,
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
 	}
-	res = data
+
 	if len(cmdParams) == 0 {
-		return
+		if !conn.IsAuthed() {
+			return nil, ErrHelloNoAuth
+		}
+		return helloReply(conn), nil
 	}
 
 	protocalVer, err := strconv.ParseInt(utils.Bytes2String(cmdParams[0]), 10, 64)
@@ -81,12 +99,10 @@ func hello(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res int
 		op := strings.ToLower(utils.Bytes2String(cmdParams[nextArg]))
 		if op == "auth" && moreArgs > 0 && moreArgs%2 == 0 {
 			nextArg++
-			if strings.ToLower(utils.Bytes2String(cmdParams[nextArg])) != "default" {
-				return nil, ErrInvalidPwd
-			}
+			user := utils.Bytes2String(cmdParams[nextArg])
 			nextArg++
 			pwd := utils.Bytes2String(cmdParams[nextArg])
-			if err = authUser(ctx, c, pwd); err != nil {
+			if err = authUser(ctx, c, user, pwd); err != nil {
 				return nil, err
 			}
 			//println("auth", nextArg)
@@ -103,9 +119,25 @@ func hello(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res int
 		}
 	}
 
+	if !conn.IsAuthed() {
+		return nil, ErrHelloNoAuth
+	}
+
+	// switch the connection protocol, reply with the new protocol
+	conn.setRespProtoVer(int(protocalVer))
+	res = helloReply(conn)
 	return
 }
 
+// helloReply hello reply map, RESP2 connection gets flat array
+func helloReply(conn *RespCmdConn) RespMap {
+	return RespMap{
+		"server", "redis",
+		"proto", redcon.SimpleInt(conn.RespProtoVer()),
+		"mode", config.RegisterRespSrvModeName,
+	}
+}
+
 func ping(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
 	if len(cmdParams) > 1 {
 		return nil, ErrCmdParams
@@ -137,11 +169,16 @@ func selectCmd(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res
 		return
 	}
 
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
 	db, err := c.Storager().Select(ctx, index)
 	if err != nil {
 		return
 	}
 	c.SetDb(db)
+	conn.setDbIndex(index)
 
 	res = OK
 	return
@@ -152,6 +189,11 @@ func flushdb(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res i
 	if err != nil {
 		return
 	}
+	if conn, ok := c.(*RespCmdConn); ok {
+		conn.srv.touchWatchedDb(conn, conn.dbIdx)
+		conn.srv.flushVolatileKeys(conn.dbIdx)
+		conn.srv.invalidateAll()
+	}
 
 	res = OK
 	return
@@ -162,7 +204,94 @@ func flushall(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res
 	if err != nil {
 		return
 	}
+	if conn, ok := c.(*RespCmdConn); ok {
+		conn.srv.touchWatchedDb(conn, -1)
+		conn.srv.flushVolatileKeys(-1)
+		conn.srv.invalidateAll()
+	}
+
+	res = OK
+	return
+}
+
+// DBSIZE
+// the keys num is from the storager keyspace stats like INFO keyspace
+func dbsize(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) != 0 {
+		return nil, ErrCmdParams
+	}
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+
+	res = conn.srv.dbSize(conn.dbIdx)
+	return
+}
+
+// SWAPDB index1 index2
+// the storager swaps the dbs if it supports, the conns which selected one db see the other one immediately
+func swapdb(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) != 2 {
+		return nil, ErrCmdParams
+	}
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+	idx1, err := strconv.Atoi(utils.Bytes2String(cmdParams[0]))
+	if err != nil {
+		return nil, ErrSwapDBIndex1
+	}
+	idx2, err := strconv.Atoi(utils.Bytes2String(cmdParams[1]))
+	if err != nil {
+		return nil, ErrSwapDBIndex2
+	}
+
+	if err = conn.srv.swapDB(ctx, idx1, idx2); err != nil {
+		return
+	}
+	conn.reselectDB(ctx)
+	if idx1 != idx2 {
+		conn.srv.touchWatchedDb(conn, idx1)
+		conn.srv.touchWatchedDb(conn, idx2)
+		conn.srv.invalidateAll()
+	}
 
 	res = OK
 	return
 }
+
+// SHUTDOWN [NOSAVE|SAVE] [NOW] [FORCE]
+// the conn is closed without reply if shutdown ok
+func shutdown(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	conn, ok := c.(*RespCmdConn)
+	if !ok {
+		return nil, ErrNoInitRespConn
+	}
+
+	flags, save := shutdownFlags{}, false
+	for _, arg := range cmdParams {
+		switch strings.ToLower(utils.Bytes2String(arg)) {
+		case "nosave":
+			flags.noSave = true
+		case "save":
+			save = true
+		case "now":
+			flags.now = true
+		case "force":
+			flags.force = true
+		default:
+			return nil, ErrSyntax
+		}
+	}
+	if save && flags.noSave {
+		return nil, ErrSyntax
+	}
+
+	// the SHUTDOWN cmd itself is in-flight
+	if err = conn.srv.shutdown(ctx, flags, 1); err != nil {
+		return
+	}
+	return nil, ErrNoops
+}
@@ -2,8 +2,12 @@ package standalone
 
 import (
 	"context"
+	"math"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/tidwall/redcon"
 	"github.com/weedge/pkg/driver"
 	"github.com/weedge/pkg/utils"
 )
@@ -28,14 +32,12 @@ func init() {
 	// new
 	driver.RegisterCmd(driver.CmdTypeString, "setnxex", setnxex)
 	driver.RegisterCmd(driver.CmdTypeString, "setxxex", setxxex)
-
-	// just for string type key
-	driver.RegisterCmd(driver.CmdTypeString, "del", del)
-	driver.RegisterCmd(driver.CmdTypeString, "exists", exists)
-	driver.RegisterCmd(driver.CmdTypeString, "expire", expire)
-	driver.RegisterCmd(driver.CmdTypeString, "expireat", expireat)
-	driver.RegisterCmd(driver.CmdTypeString, "persist", persist)
-	driver.RegisterCmd(driver.CmdTypeString, "ttl", ttl)
+	driver.RegisterCmd(driver.CmdTypeString, "psetex", psetex)
+	driver.RegisterCmd(driver.CmdTypeString, "getex", getex)
+	driver.RegisterCmd(driver.CmdTypeString, "getdel", getdel)
+	driver.RegisterCmd(driver.CmdTypeString, "msetnx", msetnx)
+	driver.RegisterCmd(driver.CmdTypeString, "incrbyfloat", incrbyfloat)
+	driver.RegisterCmd(driver.CmdTypeString, "lcs", lcs)
 }
 
 func get(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
@@ -57,17 +59,192 @@ func get(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res inter
 	return
 }
 
+// setArgs SET options
+type setArgs struct {
+	nx, xx  bool
+	get     bool
+	keepTTL bool
+	// ttl milliseconds of EX/PX/EXAT/PXAT
+	hasTTL bool
+	ttl    int64
+}
+
+// parseSetArgs parse [NX | XX] [GET] [EX seconds | PX milliseconds |
+// EXAT unix-time-seconds | PXAT unix-time-milliseconds | KEEPTTL]
+func parseSetArgs(args [][]byte) (sa setArgs, err error) {
+	for i := 0; i < len(args); i++ {
+		switch op := strings.ToLower(utils.Bytes2String(args[i])); op {
+		case "nx":
+			sa.nx = true
+		case "xx":
+			sa.xx = true
+		case "get":
+			sa.get = true
+		case "keepttl":
+			if sa.hasTTL {
+				return sa, ErrSyntax
+			}
+			sa.keepTTL = true
+		case "ex", "px", "exat", "pxat":
+			if sa.hasTTL || sa.keepTTL || i+1 >= len(args) {
+				return sa, ErrSyntax
+			}
+			i++
+			v, err := utils.StrInt64(args[i], nil)
+			if err != nil {
+				return sa, ErrValue
+			}
+			if v <= 0 {
+				return sa, ErrSetExpireTime
+			}
+			sa.hasTTL = true
+			if sa.ttl, err = expireArgTTL(op, v); err != nil {
+				return sa, ErrSetExpireTime
+			}
+		default:
+			return sa, ErrSyntax
+		}
+	}
+	if sa.nx && sa.xx {
+		return sa, ErrSyntax
+	}
+	return
+}
+
+// expireArgTTL the millisecond ttl of the EX/PX/EXAT/PXAT option value
+func expireArgTTL(op string, v int64) (int64, error) {
+	switch op {
+	case "ex", "exat":
+		if v > math.MaxInt64/1000 {
+			return 0, ErrValue
+		}
+		v *= 1000
+	}
+	switch op {
+	case "exat", "pxat":
+		v -= time.Now().UnixMilli()
+	}
+	return v, nil
+}
+
+// SET key value [NX | XX] [GET] [EX seconds | PX milliseconds |
+// EXAT unix-time-seconds | PXAT unix-time-milliseconds | KEEPTTL]
+// reply nil if NX/XX condition isn't met, or the old value with GET;
+// PX/PXAT ttl is rounded up to seconds if the storager doesn't support the millisecond ttl
 func set(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
-	if len(cmdParams) != 2 {
+	if len(cmdParams) < 2 {
 		err = ErrCmdParams
 		return
 	}
+	key, value := cmdParams[0], cmdParams[1]
+	if len(cmdParams) == 2 {
+		if err = c.Db().DBString().Set(ctx, key, value); err != nil {
+			return
+		}
+		notifyKeyEvent(c, notifyString, "set", key)
+		return OK, nil
+	}
 
-	if err = c.Db().DBString().Set(ctx, cmdParams[0], cmdParams[1]); err != nil {
+	sa, err := parseSetArgs(cmdParams[2:])
+	if err != nil {
 		return
 	}
 
-	return OK, nil
+	var old []byte
+	if sa.get {
+		if kt, ok, err := keyTypeOf(ctx, c.Db(), key); err != nil {
+			return nil, err
+		} else if ok && kt.name != "string" {
+			return nil, ErrWrongType
+		}
+		if old, err = c.Db().DBString().Get(ctx, key); err != nil {
+			return
+		}
+	}
+	ok, err := setWithArgs(ctx, c, key, value, sa)
+	if err != nil {
+		return
+	}
+	if ok {
+		notifyKeyEvent(c, notifyString, "set", key)
+		if sa.hasTTL && sa.ttl > 0 {
+			notifyKeyEvent(c, notifyGeneric, "expire", key)
+		}
+	}
+
+	switch {
+	case sa.get && old != nil:
+		res = old
+	case !sa.get && ok:
+		res = OK
+	}
+	return
+}
+
+// setWithArgs set the key value with the SET options by the string store operations,
+// return false if NX/XX condition isn't met
+func setWithArgs(ctx context.Context, c driver.IRespConn, key, value []byte, sa setArgs) (ok bool, err error) {
+	store := c.Db().DBString()
+	exists := func() (bool, error) {
+		n, err := store.Exists(ctx, key)
+		return n > 0, err
+	}
+
+	// EXAT/PXAT in the past, the key is set and expired immediately like redis
+	if sa.hasTTL && sa.ttl <= 0 {
+		if sa.nx || sa.xx {
+			if ok, err = exists(); err != nil || ok != sa.xx {
+				return false, err
+			}
+		}
+		if _, err = store.Del(ctx, key); err != nil {
+			return
+		}
+		return true, nil
+	}
+
+	// the store sets the ttl in seconds, then it's refined to milliseconds if supported
+	var n int64
+	sec := (sa.ttl + 999) / 1000
+	switch {
+	case sa.hasTTL && sa.nx:
+		n, err = store.SetNXEX(ctx, key, sec, value)
+	case sa.hasTTL && sa.xx:
+		n, err = store.SetXXEX(ctx, key, sec, value)
+	case sa.hasTTL:
+		n, err = 1, store.SetEX(ctx, key, sec, value)
+	case sa.nx:
+		n, err = store.SetNX(ctx, key, value)
+		return n > 0, err
+	}
+	if sa.hasTTL {
+		if err != nil || n == 0 {
+			return false, err
+		}
+		if _, ok := store.(pexpireCmd); ok && sa.ttl%1000 != 0 {
+			_, err = keyPExpire(ctx, store, key, sa.ttl)
+		}
+		return true, err
+	}
+
+	if sa.xx {
+		if ok, err = exists(); err != nil || !ok {
+			return
+		}
+	}
+	ttl := int64(-1)
+	if sa.keepTTL {
+		if ttl, err = keyPTTL(ctx, store, key); err != nil {
+			return
+		}
+	}
+	if err = store.Set(ctx, key, value); err != nil {
+		return
+	}
+	if ttl > 0 {
+		_, err = keyPExpire(ctx, store, key, ttl)
+	}
+	return true, err
 }
 
 func appendCmd(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
@@ -76,7 +253,10 @@ func appendCmd(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res
 		return
 	}
 
-	res, err = c.Db().DBString().Append(ctx, cmdParams[0], cmdParams[1])
+	if res, err = c.Db().DBString().Append(ctx, cmdParams[0], cmdParams[1]); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyString, "append", cmdParams[0])
 	return
 }
 
@@ -86,7 +266,10 @@ func decr(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res inte
 		return
 	}
 
-	res, err = c.Db().DBString().Decr(ctx, cmdParams[0])
+	if res, err = c.Db().DBString().Decr(ctx, cmdParams[0]); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyString, "decrby", cmdParams[0])
 	return
 }
 
@@ -101,27 +284,10 @@ func decrby(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res in
 		return
 	}
 
-	res, err = c.Db().DBString().DecrBy(ctx, cmdParams[0], delta)
-	return
-}
-
-func del(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
-	if len(cmdParams) == 0 {
-		err = ErrCmdParams
+	if res, err = c.Db().DBString().DecrBy(ctx, cmdParams[0], delta); err != nil {
 		return
 	}
-
-	res, err = c.Db().DBString().Del(ctx, cmdParams...)
-	return
-}
-
-func exists(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
-	if len(cmdParams) != 1 {
-		err = ErrCmdParams
-		return
-	}
-
-	res, err = c.Db().DBString().Exists(ctx, cmdParams[0])
+	notifyKeyEvent(c, notifyString, "decrby", cmdParams[0])
 	return
 }
 
@@ -153,7 +319,10 @@ func getset(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res in
 		return
 	}
 
-	res, err = c.Db().DBString().GetSet(ctx, cmdParams[0], cmdParams[1])
+	if res, err = c.Db().DBString().GetSet(ctx, cmdParams[0], cmdParams[1]); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyString, "set", cmdParams[0])
 	return
 }
 
@@ -163,7 +332,10 @@ func incr(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res inte
 		return
 	}
 
-	res, err = c.Db().DBString().Incr(ctx, cmdParams[0])
+	if res, err = c.Db().DBString().Incr(ctx, cmdParams[0]); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyString, "incrby", cmdParams[0])
 	return
 }
 
@@ -179,7 +351,10 @@ func incrby(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res in
 		return
 	}
 
-	res, err = c.Db().DBString().IncrBy(ctx, cmdParams[0], delta)
+	if res, err = c.Db().DBString().IncrBy(ctx, cmdParams[0], delta); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyString, "incrby", cmdParams[0])
 	return
 }
 
@@ -208,6 +383,9 @@ func mset(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res inte
 	if err != nil {
 		return
 	}
+	for _, kv := range kvs {
+		notifyKeyEvent(c, notifyString, "set", kv.Key)
+	}
 	res = OK
 
 	return
@@ -219,7 +397,15 @@ func setnx(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res int
 		return
 	}
 
-	res, err = c.Db().DBString().SetNX(ctx, cmdParams[0], cmdParams[1])
+	n, err := c.Db().DBString().SetNX(ctx, cmdParams[0], cmdParams[1])
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyString, "set", cmdParams[0])
+	}
+
+	res = n
 	return
 }
 
@@ -239,6 +425,8 @@ func setex(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res int
 	if err != nil {
 		return
 	}
+	notifyKeyEvent(c, notifyString, "set", cmdParams[0])
+	notifyKeyEvent(c, notifyGeneric, "expire", cmdParams[0])
 
 	res = OK
 	return
@@ -256,7 +444,16 @@ func setnxex(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res i
 		return
 	}
 
-	res, err = c.Db().DBString().SetNXEX(ctx, cmdParams[0], sec, cmdParams[2])
+	n, err := c.Db().DBString().SetNXEX(ctx, cmdParams[0], sec, cmdParams[2])
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyString, "set", cmdParams[0])
+		notifyKeyEvent(c, notifyGeneric, "expire", cmdParams[0])
+	}
+
+	res = n
 	return
 }
 
@@ -272,7 +469,16 @@ func setxxex(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res i
 		return
 	}
 
-	res, err = c.Db().DBString().SetXXEX(ctx, cmdParams[0], sec, cmdParams[2])
+	n, err := c.Db().DBString().SetXXEX(ctx, cmdParams[0], sec, cmdParams[2])
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyString, "set", cmdParams[0])
+		notifyKeyEvent(c, notifyGeneric, "expire", cmdParams[0])
+	}
+
+	res = n
 	return
 }
 
@@ -288,7 +494,10 @@ func setrange(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res
 		return
 	}
 
-	res, err = c.Db().DBString().SetRange(ctx, cmdParams[0], offset, cmdParams[2])
+	if res, err = c.Db().DBString().SetRange(ctx, cmdParams[0], offset, cmdParams[2]); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyString, "setrange", cmdParams[0])
 	return
 }
 
@@ -301,54 +510,341 @@ func strlen(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res in
 	return c.Db().DBString().StrLen(ctx, cmdParams[0])
 }
 
-func expire(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
-	if len(cmdParams) != 2 {
+// getStringValue the string value of the key, nil if the key doesn't exist;
+// ErrWrongType if the key holds the other type value
+func getStringValue(ctx context.Context, db driver.IDB, key []byte) (v []byte, err error) {
+	if v, err = db.DBString().Get(ctx, key); err != nil || v != nil {
+		return
+	}
+	kt, ok, err := keyTypeOf(ctx, db, key)
+	if err == nil && ok && kt.name != "string" {
+		err = ErrWrongType
+	}
+	return
+}
+
+// PSETEX key milliseconds value
+// the ttl is rounded up to seconds if the storager doesn't support the millisecond ttl
+func psetex(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) != 3 {
 		err = ErrCmdParams
 		return
 	}
 
-	duration, err := utils.StrInt64(cmdParams[1], nil)
+	ms, err := utils.StrInt64(cmdParams[1], nil)
 	if err != nil {
 		err = ErrValue
 		return
 	}
+	if ms <= 0 {
+		return nil, ErrPSetExExpireTime
+	}
+
+	if _, err = setWithArgs(ctx, c, cmdParams[0], cmdParams[2], setArgs{hasTTL: true, ttl: ms}); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyString, "set", cmdParams[0])
+	notifyKeyEvent(c, notifyGeneric, "expire", cmdParams[0])
 
-	res, err = c.Db().DBString().Expire(ctx, cmdParams[0], duration)
+	res = OK
 	return
 }
 
-func expireat(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
-	if len(cmdParams) != 2 {
+// GETEX key [EX seconds | PX milliseconds | EXAT unix-time-seconds |
+// PXAT unix-time-milliseconds | PERSIST]
+// PX/PXAT ttl is rounded up to seconds if the storager doesn't support the millisecond ttl
+func getex(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) == 0 {
 		err = ErrCmdParams
 		return
 	}
 
-	when, err := utils.StrInt64(cmdParams[1], nil)
-	if err != nil {
-		err = ErrValue
+	var hasTTL, persist bool
+	var ttl int64
+	args := cmdParams[1:]
+	for i := 0; i < len(args); i++ {
+		switch op := strings.ToLower(utils.Bytes2String(args[i])); op {
+		case "persist":
+			if hasTTL || persist {
+				return nil, ErrSyntax
+			}
+			persist = true
+		case "ex", "px", "exat", "pxat":
+			if hasTTL || persist || i+1 >= len(args) {
+				return nil, ErrSyntax
+			}
+			i++
+			v, err := utils.StrInt64(args[i], nil)
+			if err != nil {
+				return nil, ErrValue
+			}
+			if v <= 0 {
+				return nil, ErrGetExExpireTime
+			}
+			if ttl, err = expireArgTTL(op, v); err != nil {
+				return nil, ErrGetExExpireTime
+			}
+			hasTTL = true
+		default:
+			return nil, ErrSyntax
+		}
+	}
+
+	key := cmdParams[0]
+	v, err := getStringValue(ctx, c.Db(), key)
+	if err != nil || v == nil {
+		return
+	}
+	store := c.Db().DBString()
+	switch {
+	case hasTTL && ttl <= 0:
+		if _, err = store.Del(ctx, key); err != nil {
+			return
+		}
+		notifyKeyEvent(c, notifyGeneric, "del", key)
+	case hasTTL:
+		if _, err = keyPExpire(ctx, store, key, ttl); err != nil {
+			return
+		}
+		notifyKeyEvent(c, notifyGeneric, "expire", key)
+	case persist:
+		n, err := store.Persist(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			notifyKeyEvent(c, notifyGeneric, "persist", key)
+		}
+	}
+
+	res = v
+	return
+}
+
+// GETDEL key
+func getdel(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) != 1 {
+		err = ErrCmdParams
+		return
+	}
+
+	v, err := getStringValue(ctx, c.Db(), cmdParams[0])
+	if err != nil || v == nil {
 		return
 	}
+	if _, err = c.Db().DBString().Del(ctx, cmdParams[0]); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyGeneric, "del", cmdParams[0])
 
-	res, err = c.Db().DBString().ExpireAt(ctx, cmdParams[0], when)
+	res = v
 	return
 }
 
-func ttl(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
-	if len(cmdParams) != 1 {
+// MSETNX key value [key value ...]
+// none of the keys is set if any key exists in any type,
+// it's atomic with the srv cmd write lock
+func msetnx(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) == 0 || len(cmdParams)%2 != 0 {
 		err = ErrCmdParams
 		return
 	}
 
-	res, err = c.Db().DBString().TTL(ctx, cmdParams[0])
+	kvs := make([]driver.KVPair, len(cmdParams)/2)
+	for i := 0; i < len(kvs); i++ {
+		kvs[i].Key = cmdParams[2*i]
+		kvs[i].Value = cmdParams[2*i+1]
+		_, ok, err := keyTypeOf(ctx, c.Db(), kvs[i].Key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return int64(0), nil
+		}
+	}
+
+	if err = c.Db().DBString().MSet(ctx, kvs...); err != nil {
+		return
+	}
+	for _, kv := range kvs {
+		notifyKeyEvent(c, notifyString, "set", kv.Key)
+	}
+
+	res = int64(1)
 	return
 }
 
-func persist(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
-	if len(cmdParams) != 1 {
+// INCRBYFLOAT key increment
+// the key ttl is kept like redis
+func incrbyfloat(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) != 2 {
+		err = ErrCmdParams
+		return
+	}
+
+	parseFloat := func(b []byte) (float64, error) {
+		f, err := strconv.ParseFloat(utils.Bytes2String(b), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, ErrFloatValue
+		}
+		return f, nil
+	}
+	delta, err := parseFloat(cmdParams[1])
+	if err != nil {
+		return
+	}
+
+	key := cmdParams[0]
+	v, err := getStringValue(ctx, c.Db(), key)
+	if err != nil {
+		return
+	}
+	store := c.Db().DBString()
+	f, ttl := float64(0), int64(-1)
+	if v != nil {
+		if f, err = parseFloat(v); err != nil {
+			return
+		}
+		if ttl, err = keyPTTL(ctx, store, key); err != nil {
+			return
+		}
+	}
+	if f += delta; math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, ErrIncrFloatNaN
+	}
+
+	val := []byte(strconv.FormatFloat(f, 'f', -1, 64))
+	if err = store.Set(ctx, key, val); err != nil {
+		return
+	}
+	if ttl > 0 {
+		if _, err = keyPExpire(ctx, store, key, ttl); err != nil {
+			return
+		}
+	}
+	notifyKeyEvent(c, notifyString, "incrbyfloat", key)
+
+	res = val
+	return
+}
+
+// lcsMatch the matched ranges of the two strings in the longest common subsequence
+type lcsMatch struct {
+	aStart, aEnd int
+	bStart, bEnd int
+}
+
+// lcsOf the longest common subsequence of a and b by the dynamic programming,
+// the matches are in the reverse order like redis
+func lcsOf(a, b []byte) (seq []byte, matches []lcsMatch) {
+	// dp[i*(len(b)+1)+j] is the lcs length of a[:i] and b[:j]
+	w := len(b) + 1
+	dp := make([]uint32, (len(a)+1)*w)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			switch {
+			case a[i-1] == b[j-1]:
+				dp[i*w+j] = dp[(i-1)*w+j-1] + 1
+			case dp[(i-1)*w+j] > dp[i*w+j-1]:
+				dp[i*w+j] = dp[(i-1)*w+j]
+			default:
+				dp[i*w+j] = dp[i*w+j-1]
+			}
+		}
+	}
+
+	seq = make([]byte, dp[len(a)*w+len(b)])
+	idx := len(seq)
+	inMatch := false
+	for i, j := len(a), len(b); i > 0 && j > 0; {
+		if a[i-1] == b[j-1] {
+			idx--
+			seq[idx] = a[i-1]
+			if inMatch {
+				matches[len(matches)-1].aStart, matches[len(matches)-1].bStart = i-1, j-1
+			} else {
+				matches = append(matches, lcsMatch{i - 1, i - 1, j - 1, j - 1})
+				inMatch = true
+			}
+			i, j = i-1, j-1
+			continue
+		}
+		inMatch = false
+		if dp[(i-1)*w+j] > dp[i*w+j-1] {
+			i--
+		} else {
+			j--
+		}
+	}
+	return
+}
+
+// LCS key1 key2 [LEN] [IDX] [MINMATCHLEN min-match-len] [WITHMATCHLEN]
+func lcs(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res interface{}, err error) {
+	if len(cmdParams) < 2 {
 		err = ErrCmdParams
 		return
 	}
 
-	res, err = c.Db().DBString().Persist(ctx, cmdParams[0])
+	var getLen, getIdx, withMatchLen bool
+	minMatchLen := int64(0)
+	args := cmdParams[2:]
+	for i := 0; i < len(args); i++ {
+		switch strings.ToLower(utils.Bytes2String(args[i])) {
+		case "len":
+			getLen = true
+		case "idx":
+			getIdx = true
+		case "withmatchlen":
+			withMatchLen = true
+		case "minmatchlen":
+			if i+1 >= len(args) {
+				return nil, ErrSyntax
+			}
+			i++
+			if minMatchLen, err = utils.StrInt64(args[i], nil); err != nil {
+				return nil, ErrValue
+			}
+		default:
+			return nil, ErrSyntax
+		}
+	}
+	if getLen && getIdx {
+		return nil, ErrLCSLenIdx
+	}
+
+	a, err := getStringValue(ctx, c.Db(), cmdParams[0])
+	if err != nil {
+		return
+	}
+	b, err := getStringValue(ctx, c.Db(), cmdParams[1])
+	if err != nil {
+		return
+	}
+
+	seq, matches := lcsOf(a, b)
+	switch {
+	case getLen:
+		return int64(len(seq)), nil
+	case !getIdx:
+		return seq, nil
+	}
+
+	items := []any{}
+	for _, m := range matches {
+		matchLen := m.aEnd - m.aStart + 1
+		if int64(matchLen) < minMatchLen {
+			continue
+		}
+		item := []any{
+			[]any{redcon.SimpleInt(m.aStart), redcon.SimpleInt(m.aEnd)},
+			[]any{redcon.SimpleInt(m.bStart), redcon.SimpleInt(m.bEnd)},
+		}
+		if withMatchLen {
+			item = append(item, redcon.SimpleInt(matchLen))
+		}
+		items = append(items, item)
+	}
+	res = RespMap{"matches", items, "len", redcon.SimpleInt(len(seq))}
 	return
 }
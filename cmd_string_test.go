@@ -0,0 +1,121 @@
+package standalone
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/weedge/pkg/driver"
+)
+
+func TestParseSetArgs(t *testing.T) {
+	args := func(s ...string) [][]byte {
+		b := make([][]byte, 0, len(s))
+		for _, v := range s {
+			b = append(b, []byte(v))
+		}
+		return b
+	}
+
+	sa, err := parseSetArgs(args("NX", "PX", "30001"))
+	if err != nil || !sa.nx || !sa.hasTTL || sa.ttl != 30001 {
+		t.Fatalf("set args %+v, err: %v", sa, err)
+	}
+	sa, err = parseSetArgs(args("exat", strconv.FormatInt(time.Now().Unix()+100, 10), "GET"))
+	if err != nil || !sa.get || sa.ttl < 98000 || sa.ttl > 100000 {
+		t.Fatalf("set args %+v, err: %v", sa, err)
+	}
+	for _, invalid := range [][]string{{"NX", "XX"}, {"EX", "1", "PX", "1"}, {"EX", "1", "KEEPTTL"}, {"EX"}, {"FOO"}} {
+		if _, err = parseSetArgs(args(invalid...)); err != ErrSyntax {
+			t.Fatalf("set args %v err: %v", invalid, err)
+		}
+	}
+	if _, err = parseSetArgs(args("EX", "0")); err != ErrSetExpireTime {
+		t.Fatalf("set args EX 0 err: %v", err)
+	}
+}
+
+func TestSetOptions(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB()
+	c := &testConn{RespConnBase: &driver.RespConnBase{}, db: db}
+
+	if res, _ := set(ctx, c, [][]byte{[]byte("k"), []byte("v1"), []byte("XX")}); res != nil || len(db.str.vals) != 0 {
+		t.Fatalf("set xx on missing key %v", res)
+	}
+	set(ctx, c, [][]byte{[]byte("k"), []byte("v1")})
+	db.str.Expire(ctx, []byte("k"), 100)
+	res, _ := set(ctx, c, [][]byte{[]byte("k"), []byte("v2"), []byte("XX"), []byte("GET"), []byte("KEEPTTL")})
+	if string(res.([]byte)) != "v1" || string(db.str.vals["k"]) != "v2" || db.str.testKeys["k"] != 100 {
+		t.Fatalf("set xx get keepttl %v", res)
+	}
+
+	db.hash.testKeys["h"] = -1
+	if _, err := set(ctx, c, [][]byte{[]byte("h"), []byte("v"), []byte("GET")}); err != ErrWrongType {
+		t.Fatalf("set get on hash err: %v", err)
+	}
+
+	// the options read then write, the cmds with options run exclusively like INCRBYFLOAT
+	if cmdExclusive("set", [][]byte{[]byte("k"), []byte("v")}) || !cmdExclusive("set", [][]byte{[]byte("k"), []byte("v"), []byte("XX")}) {
+		t.Fatalf("set exclusive by options")
+	}
+	if cmdExclusive("getex", [][]byte{[]byte("k")}) || !cmdExclusive("getex", [][]byte{[]byte("k"), []byte("PERSIST")}) {
+		t.Fatalf("getex exclusive by options")
+	}
+	if cmdExclusive("expire", [][]byte{[]byte("k"), []byte("1")}) || !cmdExclusive("pexpire", [][]byte{[]byte("k"), []byte("1"), []byte("NX")}) {
+		t.Fatalf("expire exclusive by options")
+	}
+	if !cmdExclusive("getdel", [][]byte{[]byte("k")}) {
+		t.Fatalf("getdel isn't exclusive")
+	}
+}
+
+func TestStringExtCmds(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB()
+	c := &testConn{RespConnBase: &driver.RespConnBase{}, db: db}
+	args := func(s ...string) [][]byte {
+		b := make([][]byte, 0, len(s))
+		for _, v := range s {
+			b = append(b, []byte(v))
+		}
+		return b
+	}
+
+	db.str.Set(ctx, []byte("f"), []byte("10.5"))
+	db.str.Expire(ctx, []byte("f"), 100)
+	if res, _ := incrbyfloat(ctx, c, args("f", "0.1")); string(res.([]byte)) != "10.6" || db.str.testKeys["f"] != 100 {
+		t.Fatalf("incrbyfloat %s", res)
+	}
+	if _, err := incrbyfloat(ctx, c, args("f", "abc")); err != ErrFloatValue {
+		t.Fatalf("incrbyfloat abc err: %v", err)
+	}
+
+	db.hash.testKeys["h"] = -1
+	if res, _ := msetnx(ctx, c, args("a", "1", "h", "2")); res != int64(0) || len(db.str.vals) != 1 {
+		t.Fatalf("msetnx with existing key %v", res)
+	}
+	if res, _ := msetnx(ctx, c, args("a", "1", "b", "2")); res != int64(1) || string(db.str.vals["b"]) != "2" {
+		t.Fatalf("msetnx %v", res)
+	}
+	if res, _ := getdel(ctx, c, args("a")); string(res.([]byte)) != "1" || db.str.testKeys["a"] != 0 {
+		t.Fatalf("getdel %s", res)
+	}
+	if _, err := getdel(ctx, c, args("h")); err != ErrWrongType {
+		t.Fatalf("getdel on hash err: %v", err)
+	}
+
+	db.str.Set(ctx, []byte("k1"), []byte("ohmytext"))
+	db.str.Set(ctx, []byte("k2"), []byte("mynewtext"))
+	if res, _ := lcs(ctx, c, args("k1", "k2")); string(res.([]byte)) != "mytext" {
+		t.Fatalf("lcs %s", res)
+	}
+	if res, _ := lcs(ctx, c, args("k1", "k2", "LEN")); res != int64(6) {
+		t.Fatalf("lcs len %v", res)
+	}
+	res, _ := lcs(ctx, c, args("k1", "k2", "IDX", "MINMATCHLEN", "4", "WITHMATCHLEN"))
+	if b := AppendReply(nil, RespProtoVer2, res); string(b) != "*4\r\n$7\r\nmatches\r\n*1\r\n*3\r\n*2\r\n:4\r\n:7\r\n*2\r\n:5\r\n:8\r\n:4\r\n$3\r\nlen\r\n:6\r\n" {
+		t.Fatalf("lcs idx %q", b)
+	}
+}
@@ -72,7 +72,10 @@ func zadd(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res inte
 		params[i].Member = args[2*i+1]
 	}
 
-	res, err = c.Db().DBZSet().ZAdd(ctx, cmdParams[0], params...)
+	if res, err = c.Db().DBZSet().ZAdd(ctx, cmdParams[0], params...); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyZset, "zadd", cmdParams[0])
 	return
 }
 
@@ -183,8 +186,9 @@ func zincrby(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res i
 	if err != nil {
 		return nil, err
 	}
+	notifyKeyEvent(c, notifyZset, "zincr", cmdParams[0])
 
-	res = fmt.Sprintf("%d", data)
+	res = RespDouble(data)
 	return
 }
 
@@ -344,7 +348,15 @@ func zrem(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res inte
 		return
 	}
 
-	res, err = c.Db().DBZSet().ZRem(ctx, cmdParams[0], cmdParams[1:]...)
+	n, err := c.Db().DBZSet().ZRem(ctx, cmdParams[0], cmdParams[1:]...)
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyZset, "zrem", cmdParams[0])
+	}
+
+	res = n
 	return
 }
 
@@ -359,7 +371,15 @@ func zremrangebyrank(ctx context.Context, c driver.IRespConn, cmdParams [][]byte
 		return nil, ErrValue
 	}
 
-	res, err = c.Db().DBZSet().ZRemRangeByRank(ctx, cmdParams[0], s, e)
+	n, err := c.Db().DBZSet().ZRemRangeByRank(ctx, cmdParams[0], s, e)
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyZset, "zremrangebyrank", cmdParams[0])
+	}
+
+	res = n
 	return
 }
 
@@ -387,7 +407,15 @@ func zremrangebyscore(ctx context.Context, c driver.IRespConn, cmdParams [][]byt
 		return nil, ErrValue
 	}
 
-	res, err = c.Db().DBZSet().ZRemRangeByScore(ctx, cmdParams[0], int64(s), int64(e))
+	n, err := c.Db().DBZSet().ZRemRangeByScore(ctx, cmdParams[0], int64(s), int64(e))
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyZset, "zremrangebyscore", cmdParams[0])
+	}
+
+	res = n
 	return
 }
 
@@ -431,7 +459,7 @@ func zscore(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res in
 		return nil, err
 	}
 
-	res = fmt.Sprintf("%d", data)
+	res = RespDouble(data)
 	return
 }
 
@@ -446,8 +474,10 @@ func zunionstore(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (r
 		return
 	}
 
-	res, err = c.Db().DBZSet().ZUnionStore(ctx, destKey, srcKeys, weights, aggregate)
-
+	if res, err = c.Db().DBZSet().ZUnionStore(ctx, destKey, srcKeys, weights, aggregate); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyZset, "zunionstore", destKey)
 	return
 }
 
@@ -534,7 +564,10 @@ func zinterstore(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (r
 		return
 	}
 
-	res, err = c.Db().DBZSet().ZInterStore(ctx, destKey, srcKeys, weights, aggregate)
+	if res, err = c.Db().DBZSet().ZInterStore(ctx, destKey, srcKeys, weights, aggregate); err != nil {
+		return
+	}
+	notifyKeyEvent(c, notifyZset, "zinterstore", destKey)
 	return
 }
 
@@ -622,7 +655,15 @@ func zremrangebylex(ctx context.Context, c driver.IRespConn, cmdParams [][]byte)
 		return nil, err
 	}
 
-	res, err = c.Db().DBZSet().ZRemRangeByLex(ctx, cmdParams[0], min, max, rangeType)
+	n, err := c.Db().DBZSet().ZRemRangeByLex(ctx, cmdParams[0], min, max, rangeType)
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyZset, "zremrangebylex", cmdParams[0])
+	}
+
+	res = n
 	return
 }
 
@@ -648,7 +689,7 @@ func zmclear(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res i
 		return
 	}
 
-	res, err = c.Db().DBZSet().Del(ctx, cmdParams...)
+	res, err = delKeys(ctx, c, c.Db().DBZSet().Del, cmdParams)
 	return
 }
 
@@ -674,7 +715,15 @@ func zexpire(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res i
 		return
 	}
 
-	res, err = c.Db().DBZSet().Expire(ctx, cmdParams[0], d)
+	n, err := c.Db().DBZSet().Expire(ctx, cmdParams[0], d)
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyGeneric, "expire", cmdParams[0])
+	}
+
+	res = n
 	return
 }
 
@@ -690,7 +739,15 @@ func zexpireat(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res
 		return
 	}
 
-	res, err = c.Db().DBZSet().ExpireAt(ctx, cmdParams[0], d)
+	n, err := c.Db().DBZSet().ExpireAt(ctx, cmdParams[0], d)
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyGeneric, "expire", cmdParams[0])
+	}
+
+	res = n
 	return
 }
 
@@ -710,6 +767,14 @@ func zpersist(ctx context.Context, c driver.IRespConn, cmdParams [][]byte) (res
 		return
 	}
 
-	res, err = c.Db().DBZSet().Persist(ctx, cmdParams[0])
+	n, err := c.Db().DBZSet().Persist(ctx, cmdParams[0])
+	if err != nil {
+		return
+	}
+	if n > 0 {
+		notifyKeyEvent(c, notifyGeneric, "persist", cmdParams[0])
+	}
+
+	res = n
 	return
 }
@@ -1,9 +1,60 @@
 package config
 
 type RespCmdServiceOptions struct {
-	Addr                  string `mapstructure:"addr"`
-	AuthPassword          string `mapstructure:"authPassword"`
+	// plaintext listen address, empty disables if tls listen address or unix socket is set
+	Addr         string `mapstructure:"addr"`
+	AuthPassword string `mapstructure:"authPassword"`
+	// idle conn close time in seconds, 0 disables
 	ConnKeepaliveInterval int    `mapstructure:"connKeepaliveInterval"`
+	AclFile               string `mapstructure:"aclFile"`
+	// log cmds slower than the microseconds, negative disables, 0 logs all cmds
+	SlowlogLogSlowerThan int64 `mapstructure:"slowlogLogSlowerThan"`
+	// max entries num of slow log
+	SlowlogMaxLen int `mapstructure:"slowlogMaxLen"`
+	// prometheus metrics http listen address, empty disables
+	MetricsAddr string `mapstructure:"metricsAddr"`
+	// cmd trace spans exporter: otlp, stdout, file; empty disables
+	TraceExporter string `mapstructure:"traceExporter"`
+	// otlp http endpoint url for otlp exporter, or file path for file exporter
+	TraceEndpoint string `mapstructure:"traceEndpoint"`
+	// sample ratio of cmd trace spans, [0, 1]
+	TraceSampleRatio float64 `mapstructure:"traceSampleRatio"`
+
+	// tls listen address, empty disables tls
+	TLSAddr string `mapstructure:"tlsAddr"`
+	// server cert and key pem files, reloaded on change
+	TLSCertFile string `mapstructure:"tlsCertFile"`
+	TLSKeyFile  string `mapstructure:"tlsKeyFile"`
+	// ca cert pem file to verify client certs, reloaded on change
+	TLSCACertFile string `mapstructure:"tlsCaCertFile"`
+	// require and verify client certs (mutual tls)
+	TLSAuthClients bool `mapstructure:"tlsAuthClients"`
+	// CN: the client cert CN is the authenticated acl user name; empty disables
+	TLSAuthClientsUser string `mapstructure:"tlsAuthClientsUser"`
+	// min tls version: 1.0, 1.1, 1.2, 1.3; empty is 1.2
+	TLSMinVersion string `mapstructure:"tlsMinVersion"`
+	// comma separated cipher suite names for tls 1.2 and below, empty uses go defaults
+	TLSCipherSuites string `mapstructure:"tlsCipherSuites"`
+
+	// unix socket path, empty disables unix socket
+	UnixSocket string `mapstructure:"unixSocket"`
+	// octal permission of unix socket file, e.g. 770; empty keeps umask default
+	UnixSocketPerm string `mapstructure:"unixSocketPerm"`
+
+	// max number of connected clients, 0 is unlimited
+	MaxClients int `mapstructure:"maxClients"`
+	// max number of connected clients from the same source ip, 0 is unlimited
+	MaxClientsPerIP int `mapstructure:"maxClientsPerIp"`
+	// comma separated cidr list, only the clients from them are allowed if not empty
+	AllowCIDRs string `mapstructure:"allowCidrs"`
+	// comma separated cidr list, the clients from them are denied
+	DenyCIDRs string `mapstructure:"denyCidrs"`
+
+	// max seconds to wait for in-flight cmds on graceful shutdown, 0 doesn't wait
+	ShutdownTimeout int `mapstructure:"shutdownTimeout"`
+
+	// keyspace event classes notified over pub/sub like redis, e.g. KEA; empty disables
+	NotifyKeyspaceEvents string `mapstructure:"notifyKeyspaceEvents"`
 }
 
 func DefaultRespCmdServiceOptions() *RespCmdServiceOptions {
@@ -11,5 +62,10 @@ func DefaultRespCmdServiceOptions() *RespCmdServiceOptions {
 		Addr: "127.0.0.1:6666",
 		//defualt 0 disable and not check
 		ConnKeepaliveInterval: 0,
+		SlowlogLogSlowerThan:  10000,
+		SlowlogMaxLen:         128,
+		TraceSampleRatio:      1,
+		MaxClients:            10000,
+		ShutdownTimeout:       10,
 	}
 }
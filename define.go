@@ -17,21 +17,88 @@ var (
 	ErrNoInitRespConn = errors.New("not init resp conn")
 
 	ErrNotAuthenticated      = errors.New("ERR not authenticated")
+	ErrNoAuth                = errors.New("NOAUTH Authentication required.")
+	ErrHelloNoAuth           = errors.New("NOAUTH HELLO must be called with the client already authenticated, otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and select the RESP protocol version at the same time")
 	ErrAuthenticationFailure = errors.New("ERR authentication failure")
 	ErrCmdParams             = errors.New("ERR wrong number of arguments")
 	ErrValue                 = errors.New("ERR value is not an integer or out of range")
 	ErrSyntax                = errors.New("ERR syntax error")
+	ErrWrongType             = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+	ErrSetExpireTime         = errors.New("ERR invalid expire time in 'set' command")
+	ErrExpireNXOpts          = errors.New("ERR NX and XX, GT or LT options at the same time are not compatible")
+	ErrExpireGTLT            = errors.New("ERR GT and LT options at the same time are not compatible")
+	ErrPSetExExpireTime      = errors.New("ERR invalid expire time in 'psetex' command")
+	ErrGetExExpireTime       = errors.New("ERR invalid expire time in 'getex' command")
+	ErrFloatValue            = errors.New("ERR value is not a valid float")
+	ErrIncrFloatNaN          = errors.New("ERR increment would produce NaN or Infinity")
+	ErrLCSLenIdx             = errors.New("ERR If you want both the length and indexes, please just use IDX.")
 
 	ErrProtocalVer  = errors.New("ERR Protocol version is not an integer or out of range")
 	ErrUnsupportVer = errors.New("NOPROTO unsupported protocol version")
 	ErrNOPwd        = errors.New("ERR Client sent AUTH, but no password is set")
 	ErrInvalidPwd   = errors.New("ERR invalid password")
+
+	ErrMultiNested       = errors.New("ERR MULTI calls can not be nested")
+	ErrExecNoMulti       = errors.New("ERR EXEC without MULTI")
+	ErrDiscardNoMulti    = errors.New("ERR DISCARD without MULTI")
+	ErrWatchInMulti      = errors.New("ERR WATCH inside MULTI is not allowed")
+	ErrExecAbort         = errors.New("EXECABORT Transaction discarded because of previous errors.")
+	ErrNotAllowedInMulti = errors.New("ERR Command not allowed inside a transaction")
+
+	ErrAclNoPermKey      = errors.New("NOPERM this user has no permissions to access one of the keys used as arguments")
+	ErrAclDelDefaultUser = errors.New("ERR The 'default' user cannot be removed")
+	ErrAclNoFile         = errors.New("ERR This Redis instance is not configured to use an ACL file. You may want to specify users via the ACL SETUSER command and then issue a CONFIG REWRITE (assuming you have a Redis configuration file set) in order to store users in the Redis configuration.")
+
+	ErrNoScript         = errors.New("NOSCRIPT No matching script. Please use EVAL.")
+	ErrScriptNotBusy    = errors.New("NOTBUSY No scripts in execution right now.")
+	ErrScriptUnkillable = errors.New("UNKILLABLE Sorry the script already executed write commands against the dataset. You can either wait the script termination or kill the server in a hard way using the SHUTDOWN NOSAVE command.")
+	ErrScriptKilled     = errors.New("ERR Script killed by user with SCRIPT KILL...")
+	ErrScriptNumKeys    = errors.New("ERR Number of keys can't be greater than number of args")
+	ErrScriptNegKeys    = errors.New("ERR Number of keys can't be negative")
+
+	ErrConfigNoFile = errors.New("ERR The server is running without a config file")
+
+	ErrClientNoSuch   = errors.New("ERR No such client")
+	ErrClientName     = errors.New("ERR Client names cannot contain spaces, newlines or special characters.")
+	ErrClientID       = errors.New("ERR client-id should be greater than 0")
+	ErrClientTimeout  = errors.New("ERR timeout is not an integer or out of range")
+	ErrClientKillSkip = errors.New("ERR syntax error, SKIPME must be yes or no")
+
+	ErrTrackingPrefixNoBcast   = errors.New("ERR PREFIX option requires BCAST mode to be enabled")
+	ErrTrackingOptInOptOut     = errors.New("ERR You can't use both OPTIN and OPTOUT at the same time")
+	ErrTrackingOptBcast        = errors.New("ERR OPTIN and OPTOUT are not compatible with BCAST")
+	ErrTrackingRedirect        = errors.New("ERR The client ID you want redirect to does not exist")
+	ErrTrackingSwitchBcast     = errors.New("ERR You can't switch BCAST mode on/off before disabling tracking for this client, and then re-enabling it with a different mode.")
+	ErrTrackingSwitchOpt       = errors.New("ERR You can't switch OPTIN/OPTOUT mode before disabling tracking for this client, and then re-enabling it with a different mode.")
+	ErrTrackingCachingNoOpt    = errors.New("ERR CLIENT CACHING can be called only when the client is in tracking mode with OPTIN or OPTOUT mode enabled")
+	ErrTrackingCachingYesOpt   = errors.New("ERR CLIENT CACHING YES is only valid when tracking is enabled in OPTIN mode.")
+	ErrTrackingCachingNoOptOut = errors.New("ERR CLIENT CACHING NO is only valid when tracking is enabled in OPTOUT mode.")
+
+	ErrMaxClients         = errors.New("ERR max number of clients reached")
+	ErrMaxClientsPerIP    = errors.New("ERR max number of clients per ip reached")
+	ErrConnAddrNotAllowed = errors.New("ERR client address is not allowed")
+
+	ErrShuttingDown      = errors.New("ERR server is shutting down")
+	ErrShutdownUnblocked = errors.New("UNBLOCKED server is shutting down")
+	ErrShutdownFailed    = errors.New("ERR Errors trying to SHUTDOWN. Check logs.")
+
+	ErrSlotsNotSupported = errors.New("ERR storager doesn't support slots")
+	ErrScanNotSupported  = errors.New("ERR storager doesn't support scan")
+	ErrScanCursor        = errors.New("ERR invalid cursor")
+
+	ErrNoSuchKey          = errors.New("ERR no such key")
+	ErrSameObject         = errors.New("ERR source and destination objects are the same")
+	ErrSwapDBIndex1       = errors.New("ERR invalid first DB index")
+	ErrSwapDBIndex2       = errors.New("ERR invalid second DB index")
+	ErrSwapDBNotSupported = errors.New("ERR SWAPDB is not supported by the storager")
 )
 
 const (
 	PONG  = redcon.SimpleString("PONG")
 	OK    = redcon.SimpleString("OK")
 	NOKEY = redcon.SimpleString("NOKEY")
+
+	QUEUED = redcon.SimpleString("QUEUED")
 )
 
 var (
@@ -3,17 +3,79 @@ package standalone
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/cloudwego/kitex/pkg/klog"
 	"github.com/tidwall/redcon"
 	"github.com/weedge/pkg/driver"
 )
 
+// cmds which can be run before the connection is authenticated
+var noAuthCmds = map[string]struct{}{
+	"auth":  {},
+	"hello": {},
+	"quit":  {},
+}
+
 type RespCmdConn struct {
 	*driver.RespConnBase
 
-	srv      *RespCmdService
+	srv *RespCmdService
+	// client id, unique and monotonically increasing in srv
+	id        int64
+	createdAt time.Time
+	// last cmd run time (unix nano) and last cmd name
+	lastActiveAt atomic.Int64
+	lastCmd      atomic.Value
+	// the last cmd is running
+	running atomic.Bool
+
+	// infoMu guards the conn info which is read by other conns, e.g. CLIENT LIST/KILL,
+	// the conn writes it with the lock and reads it without
+	infoMu sync.RWMutex
+	// CLIENT REPLY mode
+	replyMode clientReplyMode
+
 	isAuthed bool
+	// acl user name which the connection authenticated as
+	userName string
+	// db index which the connection selected
+	dbIdx int
+	// SWAPDB times when the db is selected, the swapped db is reselected by the conn itself
+	dbSwaps int64
+	// resp protocol version negotiated by HELLO
+	respProtoVer int
+	// tls client cert is checked for authentication after handshake
+	tlsChecked bool
+	// admitted by srv conn admission from the source ip, released on closed
+	admitted bool
+	admitIP  string
+	// detached by MONITOR or SUBSCRIBE, the writer is owned by the detached conn loops
+	detached bool
+	// killed by its own CLIENT KILL, closed after the reply is flushed
+	closeAfterReply bool
+
+	// transaction state
+	inMulti bool
+	// queue cmd err, EXEC is aborted
+	multiErr  bool
+	multiCmds []*multiCmd
+	// watched keys are modified by other connection
+	dirtyCAS    atomic.Bool
+	watchedKeys []watchedKey
+
+	// CLIENT TRACKING opts, nil if tracking is off
+	tracking atomic.Pointer[clientTrackingOpts]
+	// CLIENT CACHING yes|no for the next cmd
+	trackingCaching clientCaching
+	// pusher of invalidation msgs ordered with the replies, started on the first cmd
+	pusherMu     sync.Mutex
+	pusher       *connPusher
+	pusherClosed bool
 
 	redcon.Conn
 
@@ -24,7 +86,16 @@ func (c *RespCmdConn) SetRedConn(redConn redcon.Conn) {
 	c.Conn = redConn
 }
 
+// GetRemoteAddr return the remote address of the connection,
+// it's <socket path>:0 for the unix socket conn like redis,
+// empty if the conn isn't set up, e.g. the internal conn
 func (c *RespCmdConn) GetRemoteAddr() string {
+	if c.Conn == nil {
+		return ""
+	}
+	if isUnixConn(c.Conn) {
+		return c.LocalAddr()
+	}
 	return c.Conn.RemoteAddr()
 }
 
@@ -38,24 +109,278 @@ func (c *RespCmdConn) Closed() bool {
 	return c.closed
 }
 
+// IsAuthed return true if the connection has authenticated,
+// or the default user is nopass when the connection accepted
+func (c *RespCmdConn) IsAuthed() bool {
+	return c.isAuthed
+}
+
+func (c *RespCmdConn) DbIndex() int {
+	c.infoMu.RLock()
+	defer c.infoMu.RUnlock()
+	return c.dbIdx
+}
+
+func (c *RespCmdConn) setDbIndex(idx int) {
+	c.infoMu.Lock()
+	c.dbIdx = idx
+	c.infoMu.Unlock()
+}
+
+// reselectDB reselect the db of the db index if the dbs are swapped by SWAPDB,
+// the conn reselects its db itself before the cmd
+func (c *RespCmdConn) reselectDB(ctx context.Context) {
+	swaps := c.srv.dbSwaps.Load()
+	if c.dbSwaps == swaps {
+		return
+	}
+	db, err := c.srv.store.Select(ctx, c.dbIdx)
+	if err != nil {
+		klog.Errorf("reselect db %d err: %s", c.dbIdx, err.Error())
+		return
+	}
+	c.SetDb(db)
+	c.dbSwaps = swaps
+}
+
+// RespProtoVer return the resp protocol version, RESP2 by default
+func (c *RespCmdConn) RespProtoVer() int {
+	c.infoMu.RLock()
+	defer c.infoMu.RUnlock()
+	return c.respProtoVerLocked()
+}
+
+func (c *RespCmdConn) respProtoVerLocked() int {
+	if c.respProtoVer == 0 {
+		return RespProtoVer2
+	}
+	return c.respProtoVer
+}
+
+func (c *RespCmdConn) setRespProtoVer(ver int) {
+	c.infoMu.Lock()
+	c.respProtoVer = ver
+	c.infoMu.Unlock()
+}
+
+func (c *RespCmdConn) UserName() string {
+	c.infoMu.RLock()
+	defer c.infoMu.RUnlock()
+	return c.userName
+}
+
+// setAuthed set the connection authenticated as the acl user
+func (c *RespCmdConn) setAuthed(userName string) {
+	c.infoMu.Lock()
+	c.isAuthed = true
+	c.userName = userName
+	c.infoMu.Unlock()
+}
+
+func (c *RespCmdConn) Name() string {
+	c.infoMu.RLock()
+	defer c.infoMu.RUnlock()
+	return c.RespConnBase.Name()
+}
+
+func (c *RespCmdConn) SetConnName(name string) {
+	c.infoMu.Lock()
+	c.RespConnBase.SetConnName(name)
+	c.infoMu.Unlock()
+}
+
+func (c *RespCmdConn) setReplyMode(mode clientReplyMode) {
+	c.infoMu.Lock()
+	c.replyMode = mode
+	c.infoMu.Unlock()
+}
+
+// ID return client id
+func (c *RespCmdConn) ID() int64 {
+	return c.id
+}
+
+// touch record the cmd which the connection is running
+func (c *RespCmdConn) touch(cmd string) {
+	c.lastActiveAt.Store(time.Now().UnixNano())
+	c.lastCmd.Store(cmd)
+}
+
+// LocalAddr return the local address of the connection
+func (c *RespCmdConn) LocalAddr() string {
+	if c.Conn == nil || c.Conn.NetConn() == nil {
+		return ""
+	}
+	if isUnixConn(c.Conn) {
+		return c.Conn.NetConn().LocalAddr().String() + ":0"
+	}
+	return c.Conn.NetConn().LocalAddr().String()
+}
+
+// clientInfo client info line for CLIENT LIST/INFO, more detail reference:
+// https://redis.io/commands/client-list/
+func (c *RespCmdConn) clientInfo() string {
+	addr := ""
+	if c.Conn != nil {
+		addr = c.GetRemoteAddr()
+	}
+
+	now := time.Now()
+	age, idle := int64(0), int64(0)
+	if !c.createdAt.IsZero() {
+		age = int64(now.Sub(c.createdAt).Seconds())
+	}
+	if lastActiveAt := c.lastActiveAt.Load(); lastActiveAt > 0 {
+		idle = int64(now.Sub(time.Unix(0, lastActiveAt)).Seconds())
+	}
+	// the conn info is read without holding the lock during the srv calls
+	c.infoMu.RLock()
+	name, dbIdx, userName, protoVer := c.RespConnBase.Name(), c.dbIdx, c.userName, c.respProtoVerLocked()
+	flags, multi := "", -1
+	if c.inMulti {
+		flags, multi = "x", len(c.multiCmds)
+	}
+	c.infoMu.RUnlock()
+	if isUnixConn(c.Conn) {
+		flags += "U"
+	}
+	if opts := c.tracking.Load(); opts != nil {
+		flags += "t"
+		if opts.bcast {
+			flags += "B"
+		}
+		if opts.redirect > 0 && !c.srv.trackingRedirectExists(opts.redirect) {
+			flags += "R"
+		}
+	}
+	if len(flags) == 0 {
+		flags = "N"
+	}
+	lastCmd, _ := c.lastCmd.Load().(string)
+	if len(lastCmd) == 0 {
+		lastCmd = "NULL"
+	}
+
+	return fmt.Sprintf("id=%d addr=%s laddr=%s name=%s age=%d idle=%d flags=%s db=%d multi=%d user=%s resp=%d cmd=%s",
+		c.id, addr, c.LocalAddr(), name, age, idle, flags, dbIdx, multi, userName, protoVer, lastCmd)
+}
+
+// checkPerm check the connection whether authenticated
+// and the acl user has permissions to run the cmd
+func (c *RespCmdConn) checkPerm(cmd string, cmdParams [][]byte) error {
+	if _, ok := noAuthCmds[cmd]; ok {
+		return nil
+	}
+	if !c.IsAuthed() {
+		return ErrNoAuth
+	}
+
+	return c.srv.acl.CheckCmdPerm(c.userName, c.clientInfo, cmd, cmdParams)
+}
+
 func (c *RespCmdConn) DoCmd(ctx context.Context, cmd string, cmdParams [][]byte) (res interface{}, err error) {
 	cmd = strings.ToLower(strings.TrimSpace(cmd))
 	f, ok := c.srv.handles[cmd]
 	if !ok {
+		c.flagMultiErr()
 		err = errors.New("ERR unknown command '" + cmd + "'")
 		return
 	}
 
+	if err = checkCmdArity(cmd, cmdParams); err != nil {
+		c.flagMultiErr()
+		c.srv.stats.cmdRejected(cmd)
+		return
+	}
+	if err = c.checkPerm(cmd, cmdParams); err != nil {
+		c.flagMultiErr()
+		c.srv.stats.cmdRejected(cmd)
+		return
+	}
+
 	respConn, ok := ctx.Value(RespCmdCtxKey).(driver.IRespConn)
 	if !ok {
 		err = errors.New("respCmdCtxKey not IRespConn")
 		return
 	}
 
+	if _, ok := multiCtrlCmds[cmd]; c.inMulti && !ok {
+		return c.queueMultiCmd(cmd, f, cmdParams)
+	}
+
+	// exclusive cmd runs with srv cmd write lock, e.g. EXEC, SET with options;
+	// blocking cmd runs without lock, don't block the exclusive cmd;
+	// no lock cmd runs without lock, e.g. SCRIPT KILL the running script
+	switch {
+	case cmdExclusive(cmd, cmdParams):
+		c.srv.cmdLock.Lock()
+		defer c.srv.cmdLock.Unlock()
+	case !cmdHasFlag(cmd, cmdFlagBlocking|cmdFlagNoLock):
+		c.srv.cmdLock.RLock()
+		defer c.srv.cmdLock.RUnlock()
+	}
+	c.reselectDB(ctx)
+	c.feedMonitors(cmd, cmdParams)
+	if cmdHasFlag(cmd, cmdFlagBlocking) {
+		c.srv.stats.blockedClients.Add(1)
+		defer c.srv.stats.blockedClients.Add(-1)
+		// the blocked conn is woken up on shutdown
+		var unblock func()
+		ctx, unblock = c.srv.blockConn(ctx, c)
+		defer unblock()
+		res, err = c.runCmd(ctx, respConn, cmd, f, cmdParams)
+		if ctx.Err() != nil && (err != nil || res == nil) {
+			return nil, ErrShutdownUnblocked
+		}
+		return
+	}
+
+	res, err = c.runCmd(ctx, respConn, cmd, f, cmdParams)
+	// CLIENT CACHING yes|no is only for the next cmd, or the cmds in the next transaction
+	if cmd != "client" && !c.inMulti {
+		c.trackingCaching = clientCachingNone
+	}
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// feedMonitors stream the cmd which is going to run to monitor conns
+func (c *RespCmdConn) feedMonitors(cmd string, cmdParams [][]byte) {
+	if c.srv.monitorNum.Load() == 0 {
+		return
+	}
+	args := append([][]byte{[]byte(cmd)}, cmdParams...)
+	c.srv.feedMonitors(c.dbIdx, c.GetRemoteAddr(), args)
+}
+
+// runCmd run the cmd handle and record the cmd stats and slow log,
+// touch the watched keys after write cmd ok
+func (c *RespCmdConn) runCmd(ctx context.Context, respConn driver.IRespConn, cmd string, f driver.CmdHandle, cmdParams [][]byte) (res interface{}, err error) {
+	keys := cmdKeys(cmd, cmdParams)
+	c.srv.expireIfNeeded(ctx, c, keys)
+
+	startTime := time.Now()
 	res, err = f(ctx, respConn, cmdParams)
+	duration := time.Since(startTime)
+	c.srv.stats.cmdCall(cmd, duration, err)
+	// the blocking cmd duration is mostly the wait for the keys, it isn't a slow cmd
+	if !cmdHasFlag(cmd, cmdFlagBlocking) && c.srv.slowLog.IsSlow(duration) {
+		c.srv.slowLog.Add(startTime, duration, append([][]byte{[]byte(cmd)}, cmdParams...), c.GetRemoteAddr(), c.Name())
+	}
 	if err != nil {
 		return
 	}
 
+	if cmdHasFlag(cmd, cmdFlagWrite) {
+		c.srv.touchWatchedKeys(c, c.dbIdx, keys...)
+		c.srv.invalidateKeys(c, keys...)
+		c.srv.indexKeysTTL(ctx, c, cmd, keys)
+	} else if len(keys) > 0 {
+		c.srv.stats.keyspaceLookup(cmd, res)
+		c.srv.trackKeys(c, keys)
+	}
 	return
 }
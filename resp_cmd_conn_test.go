@@ -1,9 +1,14 @@
 package standalone
 
 import (
+	"context"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/tidwall/redcon"
 	"github.com/weedge/pkg/driver"
+	"github.com/weedge/xdis-standalone/config"
 )
 
 func TestImpIRespConn(t *testing.T) {
@@ -12,3 +17,228 @@ func TestImpIRespConn(t *testing.T) {
 		t.Fatalf("does not implement driver.IRespConn")
 	}
 }
+
+func TestDoCmdAuth(t *testing.T) {
+	opts := config.DefaultRespCmdServiceOptions()
+	opts.AuthPassword = "pwd"
+	srv := New(opts)
+	conn := &RespCmdConn{RespConnBase: &driver.RespConnBase{}, srv: srv}
+	ctx := context.WithValue(context.Background(), RespCmdCtxKey, conn)
+
+	if _, err := conn.DoCmd(ctx, "ping", nil); err != ErrNoAuth {
+		t.Fatalf("ping before auth err: %v", err)
+	}
+	if _, err := conn.DoCmd(ctx, "auth", [][]byte{[]byte("bad")}); err != ErrInvalidPwd {
+		t.Fatalf("auth with invalid password err: %v", err)
+	}
+	srv.slowLog.SetConfig(0, 10)
+	if _, err := conn.DoCmd(ctx, "auth", [][]byte{[]byte("default"), []byte("pwd")}); err != nil {
+		t.Fatalf("auth err: %v", err)
+	}
+	if entries := srv.slowLog.Get(-1); len(entries) != 1 || strings.Join(entries[0].Args, " ") != "auth (redacted) (redacted)" {
+		t.Fatalf("slow log entries: %v", entries)
+	}
+	res, err := conn.DoCmd(ctx, "ping", nil)
+	if err != nil || res != PONG {
+		t.Fatalf("ping after auth res: %v err: %v", res, err)
+	}
+}
+
+func TestDoCmdMulti(t *testing.T) {
+	srv := New(config.DefaultRespCmdServiceOptions())
+	conn := &RespCmdConn{RespConnBase: &driver.RespConnBase{}, srv: srv, isAuthed: true, userName: DefaultAclUserName}
+	ctx := context.WithValue(context.Background(), RespCmdCtxKey, conn)
+	doCmd := func(cmd string, params ...string) (interface{}, error) {
+		cmdParams := make([][]byte, len(params))
+		for i, param := range params {
+			cmdParams[i] = []byte(param)
+		}
+		return conn.DoCmd(ctx, cmd, cmdParams)
+	}
+
+	doCmd("multi")
+	if res, err := doCmd("echo", "hi"); err != nil || res != QUEUED {
+		t.Fatalf("queue echo res: %v err: %v", res, err)
+	}
+	if _, err := doCmd("echo"); err == nil {
+		t.Fatalf("queue echo with wrong arity should fail")
+	}
+	if _, err := doCmd("exec"); err != ErrExecAbort {
+		t.Fatalf("exec err: %v", err)
+	}
+
+	doCmd("multi")
+	doCmd("echo", "hi")
+	res, err := doCmd("exec")
+	if data, ok := res.([]any); err != nil || !ok || len(data) != 1 || string(data[0].([]byte)) != "hi" {
+		t.Fatalf("exec res: %v err: %v", res, err)
+	}
+
+	doCmd("watch", "k")
+	other := &RespCmdConn{srv: srv}
+	srv.touchWatchedKeys(other, 0, []byte("k"))
+	doCmd("multi")
+	doCmd("echo", "hi")
+	if res, err := doCmd("exec"); err != nil || res != nil {
+		t.Fatalf("exec with dirty watched key res: %v err: %v", res, err)
+	}
+}
+
+func TestDoCmdMultiBlocking(t *testing.T) {
+	db := newTestDB()
+	db.list.vals["l"] = [][]byte{[]byte("a"), []byte("b")}
+	db.list.testKeys["l"] = -1
+	store := &testStorager{dbs: []*testDB{db}}
+	srv := New(config.DefaultRespCmdServiceOptions())
+	srv.SetStorager(store)
+	conn := srv.InitRespConn(context.Background(), 0).(*RespCmdConn)
+	conn.setAuthed(DefaultAclUserName)
+	conn.SetStorager(store)
+	ctx := context.WithValue(context.Background(), RespCmdCtxKey, conn)
+	doCmd := func(cmd string, params ...string) (interface{}, error) {
+		cmdParams := make([][]byte, len(params))
+		for i, param := range params {
+			cmdParams[i] = []byte(param)
+		}
+		return conn.DoCmd(ctx, cmd, cmdParams)
+	}
+
+	// the queued blocking cmds don't wait with the infinite timeout
+	doCmd("multi")
+	doCmd("blpop", "x", "l", "0")
+	doCmd("brpop", "l", "0")
+	doCmd("brpop", "l", "0")
+	res, err := doCmd("exec")
+	data, ok := res.([]any)
+	if err != nil || !ok || len(data) != 3 {
+		t.Fatalf("exec res: %v err: %v", res, err)
+	}
+	if kv, ok := data[0].([]interface{}); !ok || string(kv[0].([]byte)) != "l" || string(kv[1].([]byte)) != "a" {
+		t.Fatalf("blpop res: %v", data[0])
+	}
+	if kv, ok := data[1].([]interface{}); !ok || string(kv[1].([]byte)) != "b" {
+		t.Fatalf("brpop res: %v", data[1])
+	}
+	if data[2] != nil {
+		t.Fatalf("brpop empty list res: %v", data[2])
+	}
+}
+
+func TestDoCmdEval(t *testing.T) {
+	srv := New(config.DefaultRespCmdServiceOptions())
+	conn := &RespCmdConn{RespConnBase: &driver.RespConnBase{}, srv: srv, isAuthed: true, userName: DefaultAclUserName}
+	ctx := context.WithValue(context.Background(), RespCmdCtxKey, conn)
+	doCmd := func(cmd string, params ...string) (interface{}, error) {
+		cmdParams := make([][]byte, len(params))
+		for i, param := range params {
+			cmdParams[i] = []byte(param)
+		}
+		return conn.DoCmd(ctx, cmd, cmdParams)
+	}
+
+	res, err := doCmd("eval", "return {KEYS[1], redis.call('echo', ARGV[1]), 1}", "1", "k", "hi")
+	if data, ok := res.([]any); err != nil || !ok || len(data) != 3 ||
+		string(data[0].([]byte)) != "k" || string(data[1].([]byte)) != "hi" || data[2] != redcon.SimpleInt(1) {
+		t.Fatalf("eval res: %v err: %v", res, err)
+	}
+
+	res, err = doCmd("eval", "return {redis.call('echo', 1.5), redis.call('echo', 3)}", "0")
+	if data, ok := res.([]any); err != nil || !ok || len(data) != 2 ||
+		string(data[0].([]byte)) != "1.5" || string(data[1].([]byte)) != "3" {
+		t.Fatalf("eval with number args res: %v err: %v", res, err)
+	}
+
+	sha, err := doCmd("script", "load", "return redis.pcall('eval', 'return 1', 0)")
+	if err != nil {
+		t.Fatalf("script load err: %v", err)
+	}
+	if _, err := doCmd("evalsha", sha.(string), "0"); err == nil {
+		t.Fatalf("evalsha nested eval should fail")
+	}
+	doCmd("script", "flush")
+	if _, err := doCmd("evalsha", sha.(string), "0"); err != ErrNoScript {
+		t.Fatalf("evalsha after flush err: %v", err)
+	}
+	if _, err := doCmd("script", "kill"); err != ErrScriptNotBusy {
+		t.Fatalf("script kill err: %v", err)
+	}
+}
+
+func TestDoCmdClient(t *testing.T) {
+	srv := New(config.DefaultRespCmdServiceOptions())
+	conn := &RespCmdConn{RespConnBase: &driver.RespConnBase{}, srv: srv, id: 7, isAuthed: true, userName: DefaultAclUserName}
+	ctx := context.WithValue(context.Background(), RespCmdCtxKey, conn)
+	doCmd := func(cmd string, params ...string) (interface{}, error) {
+		cmdParams := make([][]byte, len(params))
+		for i, param := range params {
+			cmdParams[i] = []byte(param)
+		}
+		return conn.DoCmd(ctx, cmd, cmdParams)
+	}
+
+	if res, err := doCmd("client", "id"); err != nil || res != int64(7) {
+		t.Fatalf("client id res: %v err: %v", res, err)
+	}
+	if _, err := doCmd("client", "setname", "a b"); err != ErrClientName {
+		t.Fatalf("client setname with space err: %v", err)
+	}
+	doCmd("client", "setname", "conn1")
+	if res, err := doCmd("client", "getname"); err != nil || res != "conn1" {
+		t.Fatalf("client getname res: %v err: %v", res, err)
+	}
+	res, err := doCmd("client", "info")
+	if info, ok := res.(string); err != nil || !ok || !strings.HasPrefix(info, "id=7 ") || !strings.Contains(info, " name=conn1 ") {
+		t.Fatalf("client info res: %v err: %v", res, err)
+	}
+	srv.respConnMap[conn] = struct{}{}
+	if res, err := doCmd("client", "kill", "id", "7", "skipme", "no"); err != nil || res != redcon.SimpleInt(1) || !conn.closeAfterReply || conn.Closed() {
+		t.Fatalf("client kill self res: %v err: %v", res, err)
+	}
+	if _, err := doCmd("client", "reply", "off"); err != ErrNoops || conn.replyMode != clientReplyOff {
+		t.Fatalf("client reply off err: %v", err)
+	}
+}
+
+func TestConnInfoConcurrent(t *testing.T) {
+	store := &testSwapDBStorager{&testStorager{dbs: []*testDB{newTestDB(), newTestDB()}}}
+	srv := New(config.DefaultRespCmdServiceOptions())
+	srv.SetStorager(store)
+	newConn := func() (*RespCmdConn, context.Context) {
+		c := srv.InitRespConn(context.Background(), 0).(*RespCmdConn)
+		c.setAuthed(DefaultAclUserName)
+		c.SetStorager(store)
+		srv.AddRespCmdConn(c)
+		return c, context.WithValue(context.Background(), RespCmdCtxKey, c)
+	}
+	c1, ctx1 := newConn()
+	c2, ctx2 := newConn()
+
+	// the conn info and db of c2 are read and swapped by c1 while c2 runs cmds
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			c2.DoCmd(ctx2, "select", [][]byte{[]byte(strconv.Itoa(i % 2))})
+			c2.DoCmd(ctx2, "client", [][]byte{[]byte("setname"), []byte("c" + strconv.Itoa(i))})
+			c2.DoCmd(ctx2, "multi", nil)
+			c2.DoCmd(ctx2, "get", [][]byte{[]byte("k")})
+			c2.DoCmd(ctx2, "discard", nil)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if _, err := c1.DoCmd(ctx1, "client", [][]byte{[]byte("list")}); err != nil {
+			t.Fatalf("client list err: %v", err)
+		}
+		if _, err := c1.DoCmd(ctx1, "swapdb", [][]byte{[]byte("0"), []byte("1")}); err != nil {
+			t.Fatalf("swapdb err: %v", err)
+		}
+	}
+	<-done
+
+	c2.DoCmd(ctx2, "select", [][]byte{[]byte("0")})
+	c1.DoCmd(ctx1, "swapdb", [][]byte{[]byte("0"), []byte("1")})
+	c2.DoCmd(ctx2, "exists", [][]byte{[]byte("k")})
+	if c2.Db() != store.dbs[0] {
+		t.Fatalf("swapped db isn't reselected by the conn")
+	}
+}
@@ -2,7 +2,11 @@ package standalone
 
 import (
 	"context"
+	"net/http"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cloudwego/kitex/pkg/klog"
@@ -10,6 +14,8 @@ import (
 	"github.com/tidwall/redcon"
 	"github.com/weedge/pkg/driver"
 	"github.com/weedge/xdis-standalone/config"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type OnAccept func(conn redcon.Conn) bool
@@ -17,6 +23,8 @@ type OnClosed func(conn redcon.Conn, err error)
 
 type RespCmdService struct {
 	opts *config.RespCmdServiceOptions
+	// config file which opts are loaded from, for CONFIG REWRITE
+	configFile string
 	// redcon server handler
 	mux *redcon.ServeMux
 	// redcon server
@@ -25,6 +33,30 @@ type RespCmdService struct {
 	onClosed  OnClosed
 	handles   map[string]driver.CmdHandle
 
+	// redcon tls server and the reloadable certs
+	redconTLSSrv *redcon.TLSServer
+	tlsCerts     *tlsCerts
+	// close to stop the tls certs watcher
+	tlsDone chan struct{}
+	// redcon unix socket server
+	redconUnixSrv *redcon.Server
+	// admission control of accepted conns
+	admission *connAdmission
+
+	// graceful shutdown state, new conns and cmds are refused when shutting down
+	shutdownMu   sync.Mutex
+	shuttingDown atomic.Bool
+	inflightCmds atomic.Int64
+	// blocked conn -> cancel func to wake up the blocking cmd
+	blockedMu    sync.Mutex
+	blockedConns map[*RespCmdConn]context.CancelFunc
+	// parent ctx of the cmds, canceled to abort the in-flight cmds on shutdown
+	cmdCtx    context.Context
+	cmdCancel context.CancelFunc
+	// closed after srv closed
+	done     chan struct{}
+	doneOnce sync.Once
+
 	// storager
 	store driver.IStatsStorager
 
@@ -32,12 +64,60 @@ type RespCmdService struct {
 	rcm sync.Mutex
 	// resp cmd connects map
 	respConnMap map[driver.IRespConn]struct{}
-
-	// pub/sub
-	pubSub redcon.PubSub
+	// last resp cmd conn client id
+	lastConnID atomic.Int64
+	// CLIENT PAUSE state
+	pause clientPause
+	// srv counters
+	stats *srvStats
+	// close to stop the stats sample loop
+	statsDone chan struct{}
+	// SWAPDB times, the conns reselect the swapped dbs by it
+	dbSwaps atomic.Int64
+	// keys with ttl index to find the expired keys, nil if the storager notifies them
+	volatileKeys *volatileKeys
+	expireDone   chan struct{}
+	// prometheus metrics http server
+	metricsSrv *http.Server
+	// cmd tracer, noop if tracing is disabled
+	tracer         trace.Tracer
+	tracerProvider *sdktrace.TracerProvider
+	traceSampler   *ratioSampler
+
+	// pub/sub registry of subscriber conns
+	pubSub *pubSub
+	// notify-keyspace-events class flags
+	notifyFlags atomic.Int64
+	// client side caching tracked keys and tracking conns
+	tracking *tracking
+	// SCAN family cursor dict
+	scanCursors scanCursors
 
 	// info service dump info
 	info driver.ISrvInfo
+
+	// acl users and acl logs
+	acl *Acl
+
+	// lua script cache and running script
+	scripts *Scripts
+
+	// slow cmds log
+	slowLog *SlowLog
+
+	// rw lock for monitors
+	mm sync.RWMutex
+	// monitor conns which stream the executed cmds
+	monitors   map[*monitorConn]struct{}
+	monitorNum atomic.Int64
+
+	// cmd rw lock, exclusive cmd (e.g. EXEC) runs with write lock
+	cmdLock sync.RWMutex
+
+	// mutex lock for watchedKeys
+	wkm sync.Mutex
+	// watched keys for transaction, key -> conns which watched the key
+	watchedKeys map[watchedKey]map[*RespCmdConn]struct{}
 }
 
 func New(opts *config.RespCmdServiceOptions) (srv *RespCmdService) {
@@ -48,22 +128,49 @@ func New(opts *config.RespCmdServiceOptions) (srv *RespCmdService) {
 		opts:        opts,
 		mux:         redcon.NewServeMux(),
 		respConnMap: map[driver.IRespConn]struct{}{},
+		watchedKeys: map[watchedKey]map[*RespCmdConn]struct{}{},
+		monitors:    map[*monitorConn]struct{}{},
+		pubSub:      newPubSub(),
+		tracking:    newTracking(),
+		tracer:      trace.NewNoopTracerProvider().Tracer(tracerName),
+		admission:   newConnAdmission(),
+
+		blockedConns: map[*RespCmdConn]context.CancelFunc{},
+		done:         make(chan struct{}),
 	}
 
+	srv.cmdCtx, srv.cmdCancel = context.WithCancel(context.Background())
 	srv.onAccept = srv.OnAccept
 	srv.onClosed = srv.OnClosed
 
 	driver.RegisterCmd(driver.CmdTypeSrv, "quit", nil)
 	driver.RegisterCmd(driver.CmdTypeSrv, "info", nil)
-	driver.RegisterCmd(driver.CmdTypeSrv, "publish", nil)
 	driver.RegisterCmd(driver.CmdTypeSrv, "subscribe", nil)
 	driver.RegisterCmd(driver.CmdTypeSrv, "psubscribe", nil)
+	driver.RegisterCmd(driver.CmdTypeSrv, "unsubscribe", nil)
+	driver.RegisterCmd(driver.CmdTypeSrv, "punsubscribe", nil)
+	driver.RegisterCmd(driver.CmdTypeSrv, "ssubscribe", nil)
+	driver.RegisterCmd(driver.CmdTypeSrv, "sunsubscribe", nil)
+	driver.RegisterCmd(driver.CmdTypeSrv, "monitor", nil)
 	srv.handles = driver.RegisteredCmdHandles
+	cmds := []string{}
+	for _, cmdSet := range driver.RegisteredCmdSet {
+		cmds = append(cmds, cmdSet...)
+	}
+	srv.stats = newSrvStats(cmds)
 	srv.mux.HandleFunc("quit", srv.QuitCmd)
-	srv.mux.HandleFunc("info", srv.InfoCmd)
-	srv.mux.HandleFunc("publish", srv.PublishCmd)
-	srv.mux.HandleFunc("subscribe", srv.SubscribeCmd)
-	srv.mux.HandleFunc("psubscribe", srv.SubscribeCmd)
+	srv.mux.HandleFunc("info", srv.srvCmdHandle(srv.InfoCmd))
+	srv.mux.HandleFunc("subscribe", srv.srvCmdHandle(srv.SubscribeCmd))
+	srv.mux.HandleFunc("psubscribe", srv.srvCmdHandle(srv.SubscribeCmd))
+	srv.mux.HandleFunc("unsubscribe", srv.srvCmdHandle(srv.UnsubscribeCmd))
+	srv.mux.HandleFunc("punsubscribe", srv.srvCmdHandle(srv.UnsubscribeCmd))
+	srv.mux.HandleFunc("ssubscribe", srv.srvCmdHandle(srv.SubscribeCmd))
+	srv.mux.HandleFunc("sunsubscribe", srv.srvCmdHandle(srv.UnsubscribeCmd))
+	srv.mux.HandleFunc("monitor", srv.srvCmdHandle(srv.MonitorCmd))
+
+	srv.acl = NewAcl(opts.AuthPassword)
+	srv.scripts = NewScripts()
+	srv.slowLog = NewSlowLog(opts.SlowlogLogSlowerThan, opts.SlowlogMaxLen)
 
 	return
 }
@@ -71,6 +178,16 @@ func New(opts *config.RespCmdServiceOptions) (srv *RespCmdService) {
 func (s *RespCmdService) SetStorager(store driver.IStorager) {
 	s.store = store.(driver.IStatsStorager)
 	s.info = NewSrvInfo(s)
+	notifier, ok := store.(expiredNotifyStorager)
+	if !ok {
+		// the expired keys are found by the srv if the storager doesn't notify them
+		s.volatileKeys = newVolatileKeys()
+		return
+	}
+	notifier.SetExpiredHandler(func(dbIdx int, key []byte) {
+		s.notifyKeyspaceEvent(notifyExpired, "expired", dbIdx, key)
+		s.invalidateKeys(nil, key)
+	})
 }
 
 func (s *RespCmdService) Name() driver.RespServiceName {
@@ -78,7 +195,25 @@ func (s *RespCmdService) Name() driver.RespServiceName {
 }
 
 func (s *RespCmdService) Close() (err error) {
+	s.shuttingDown.Store(true)
+	defer s.doneOnce.Do(func() { close(s.done) })
 	s.CloseAllRespCmdConnect()
+	s.closeMonitors()
+	s.closeSubscribers()
+	if s.statsDone != nil {
+		close(s.statsDone)
+		s.statsDone = nil
+	}
+	if s.expireDone != nil {
+		close(s.expireDone)
+		s.expireDone = nil
+	}
+	if err = s.closeMetrics(); err != nil {
+		klog.Errorf("close metrics http server err: %s", err.Error())
+	}
+	if err = s.closeTracer(); err != nil {
+		klog.Errorf("close cmd tracer err: %s", err.Error())
+	}
 
 	if s.redconSrv != nil {
 		if err = s.redconSrv.Close(); err != nil {
@@ -86,6 +221,19 @@ func (s *RespCmdService) Close() (err error) {
 		}
 		s.redconSrv = nil
 	}
+	if s.tlsDone != nil {
+		close(s.tlsDone)
+		s.tlsDone = nil
+	}
+	if s.redconTLSSrv != nil {
+		if err = s.redconTLSSrv.Close(); err != nil {
+			klog.Errorf("close redcon tls service err: %s", err.Error())
+		}
+		s.redconTLSSrv = nil
+	}
+	if err = s.closeUnixServer(); err != nil {
+		klog.Errorf("close redcon unix socket service err: %s", err.Error())
+	}
 
 	if err == nil {
 		klog.Infof("close resp cmd service ok")
@@ -111,9 +259,11 @@ func (s *RespCmdService) RegisterRespCmdConnHandle() {
 				klog.Errorf("resp cmd connect init err")
 				return
 			}
-			ctx := context.WithValue(context.Background(), RespCmdCtxKey, conn.Context())
+			ctx := context.WithValue(s.cmdCtx, RespCmdCtxKey, conn.Context())
+			ctx, span := s.startCmdSpan(ctx, conn, strings.ToLower(cmdOp), params)
 			startTime := time.Now()
 			res, err := respConn.DoCmd(ctx, cmdOp, params)
+			endCmdSpan(span, err)
 			klog.Debugf("resp cmd %s params %s res: %+v to %s err: %v cost: %d ms", cmdOp, params, res, conn.RemoteAddr(), err, time.Since(startTime).Milliseconds())
 
 			// nothing to do, has Write to connFd in DoCmd
@@ -122,13 +272,16 @@ func (s *RespCmdService) RegisterRespCmdConnHandle() {
 			}
 			if err != nil {
 				conn.WriteError(err.Error())
+				s.stats.errorReply(err.Error())
+				s.stats.netOutputBytes.Add(int64(len(err.Error()) + 3))
 				return
 			}
-			if _, ok := res.(int64); ok {
-				conn.WriteInt64(res.(int64))
-				return
+			protoVer := RespProtoVer2
+			if respCmdConn, ok := respConn.(*RespCmdConn); ok {
+				protoVer = respCmdConn.RespProtoVer()
 			}
-			conn.WriteAny(res)
+			n := writeReply(conn, protoVer, res)
+			s.stats.netOutputBytes.Add(int64(n))
 		})
 	}
 }
@@ -138,7 +291,21 @@ func (s *RespCmdService) InitRespConn(ctx context.Context, dbIdx int) driver.IRe
 		dbIdx = 0
 	}
 
-	conn := &RespCmdConn{RespConnBase: &driver.RespConnBase{}, srv: s, isAuthed: false}
+	conn := &RespCmdConn{
+		RespConnBase: &driver.RespConnBase{},
+		srv:          s,
+		id:           s.lastConnID.Add(1),
+		createdAt:    time.Now(),
+		isAuthed:     false,
+		dbIdx:        dbIdx,
+		dbSwaps:      s.dbSwaps.Load(),
+		respProtoVer: RespProtoVer2,
+	}
+	// auto authenticate as default user if default user is nopass
+	if u := s.acl.GetUser(DefaultAclUserName); u != nil && u.Enabled && u.NoPass {
+		conn.isAuthed = true
+		conn.userName = DefaultAclUserName
+	}
 	db, err := s.store.Select(ctx, dbIdx)
 	if err != nil {
 		return nil
@@ -159,65 +326,172 @@ func (s *RespCmdService) SetOnClosed(onClosed OnClosed) {
 func (s *RespCmdService) OnAccept(conn redcon.Conn) bool {
 	klog.Infof("accept: %s", conn.RemoteAddr())
 
-	// todo: get net.Conn request info set to context Value for trace
-	// add resp cmd conn
+	if s.shuttingDown.Load() {
+		s.rejectConn(conn, ErrShuttingDown)
+		return false
+	}
+
+	// admission control, the rejected conn is closed after err reply
+	ip := connIP(conn)
+	if err := s.admission.admit(ip); err != nil {
+		klog.Warnf("reject conn %s: %s", conn.RemoteAddr(), err.Error())
+		s.rejectConn(conn, err)
+		return false
+	}
+
+	// add resp cmd conn, the conn info is set to cmd trace span
 	respConn := s.InitRespConn(context.Background(), 0)
 	respCmdConn := respConn.(*RespCmdConn)
+	respCmdConn.admitted, respCmdConn.admitIP = true, ip
 	respCmdConn.SetRedConn(conn)
 	respCmdConn.SetStorager(s.store)
 	s.AddRespCmdConn(respCmdConn)
+	s.stats.connsReceived.Add(1)
 
 	// set ctx
 	conn.SetContext(respConn)
 	return true
 }
 
+// OnClosed the conn serve goroutine exits,
+// the conn detached by MONITOR or SUBSCRIBE is still open and released when its loops close it
 func (s *RespCmdService) OnClosed(conn redcon.Conn, err error) {
-	logF := klog.Infof
-	if err != nil {
-		logF = klog.Errorf
-	}
-	logF("closed by %s, err: %v", conn.RemoteAddr(), err)
-
-	// del resp cmd conn
 	respConn, ok := conn.Context().(driver.IRespConn)
 	if !ok {
 		klog.Errorf("resp cmd connect client init err")
 		return
 	}
 	respCmdConn := respConn.(*RespCmdConn)
+	if respCmdConn.detached {
+		klog.Debugf("detached by %s", conn.RemoteAddr())
+		respCmdConn.closePusher()
+		return
+	}
+
+	logF := klog.Infof
+	if err != nil {
+		logF = klog.Errorf
+	}
+	logF("closed by %s, err: %v", conn.RemoteAddr(), err)
+	s.releaseRespCmdConn(respCmdConn)
+}
+
+// releaseRespCmdConn del the closed resp cmd conn and release its admission slot
+func (s *RespCmdService) releaseRespCmdConn(respCmdConn *RespCmdConn) {
+	s.unwatchKeys(respCmdConn)
+	s.tracking.disable(respCmdConn)
+	respCmdConn.closePusher()
 	s.DelRespCmdConn(respCmdConn)
+	if respCmdConn.admitted {
+		s.admission.release(respCmdConn.admitIP)
+	}
 }
 
 func (s *RespCmdService) Start(ctx context.Context) (err error) {
-	//RESP cmd tcp server
-	s.redconSrv = redcon.NewServer(s.opts.Addr, s.mux.ServeRESP,
-		// use this function to accept (return true) or deny the connection (return false).
-		s.onAccept,
-		// this is called when the connection has been closed by remote client
-		s.onClosed,
-	)
+	if len(s.opts.AclFile) > 0 {
+		if err = s.acl.LoadFile(s.opts.AclFile); err != nil {
+			klog.Errorf("load acl file %s err: %s", s.opts.AclFile, err.Error())
+			return
+		}
+	}
 
-	if s.opts.ConnKeepaliveInterval > 0 {
-		s.redconSrv.SetIdleClose(time.Duration(s.opts.ConnKeepaliveInterval))
+	if err = applyAdmissionConfig(s); err != nil {
+		klog.Errorf("conn admission config err: %s", err.Error())
+		return
+	}
+	if err = applyNotifyConfig(s); err != nil {
+		klog.Errorf("notify keyspace events config err: %s", err.Error())
+		return
+	}
+
+	if len(s.opts.TLSAddr) > 0 {
+		if s.tlsCerts, err = newTLSCerts(s.opts); err != nil {
+			klog.Errorf("load tls certs err: %s", err.Error())
+			return
+		}
 	}
 
 	s.RegisterRespCmdConnHandle()
 
+	s.statsDone = make(chan struct{})
+	go s.stats.sampleLoop(s.statsDone)
+	if s.volatileKeys != nil {
+		s.expireDone = make(chan struct{})
+		go s.activeExpireLoop(s.expireDone)
+	}
+
+	if err = s.startTracer(ctx); err != nil {
+		klog.Errorf("start cmd tracer err:%s", err.Error())
+		return
+	}
+
+	if len(s.opts.MetricsAddr) > 0 {
+		if err = s.startMetrics(); err != nil {
+			klog.Errorf("metrics http server listen err:%s", err.Error())
+			return
+		}
+	}
+
+	idleClose := s.idleClose()
+	//RESP cmd tcp server, it's optional if tls server or unix socket is enabled
+	if len(s.opts.Addr) > 0 || (len(s.opts.TLSAddr) == 0 && len(s.opts.UnixSocket) == 0) {
+		s.redconSrv = redcon.NewServer(s.opts.Addr, s.ServeRESP,
+			// use this function to accept (return true) or deny the connection (return false).
+			s.onAccept,
+			// this is called when the connection has been closed by remote client
+			s.onClosed,
+		)
+		if idleClose > 0 {
+			s.redconSrv.SetIdleClose(idleClose)
+		}
+		if err = listenServe(s.redconSrv); err != nil {
+			klog.Errorf("resp cmd server listen err:%s", err.Error())
+			return
+		}
+		klog.Infof("resp cmd server listening on address=%s", s.opts.Addr)
+	}
+
+	//RESP cmd tls server, certs are reloaded on change
+	if s.tlsCerts != nil {
+		s.redconTLSSrv = redcon.NewServerNetworkTLS("tcp", s.opts.TLSAddr, s.ServeRESP,
+			s.onAccept, s.onClosed, s.tlsCerts.TLSConfig())
+		if idleClose > 0 {
+			s.redconTLSSrv.SetIdleClose(idleClose)
+		}
+		if err = listenServe(s.redconTLSSrv); err != nil {
+			klog.Errorf("resp cmd tls server listen err:%s", err.Error())
+			return
+		}
+		s.tlsDone = make(chan struct{})
+		go s.tlsCerts.watch(s.tlsDone)
+		klog.Infof("resp cmd tls server listening on address=%s", s.opts.TLSAddr)
+	}
+
+	//RESP cmd unix socket server
+	if len(s.opts.UnixSocket) > 0 {
+		if err = s.startUnixServer(); err != nil {
+			klog.Errorf("resp cmd unix socket server listen err:%s", err.Error())
+			return
+		}
+		klog.Infof("resp cmd server listening on unix socket=%s", s.opts.UnixSocket)
+	}
+
+	return
+}
+
+// listenServe listen and serve the redcon server in background,
+// return after listened
+func listenServe(srv interface {
+	ListenServeAndSignal(signal chan error) error
+}) error {
 	listenErrSignal := make(chan error)
 	go func() {
-		err := s.redconSrv.ListenServeAndSignal(listenErrSignal)
+		err := srv.ListenServeAndSignal(listenErrSignal)
 		if err != nil {
 			klog.Fatal(err)
 		}
 	}()
-	err = <-listenErrSignal
-	if err != nil {
-		klog.Errorf("resp cmd server listen err:%s", err.Error())
-		return
-	}
-	klog.Infof("resp cmd server listening on address=%s", s.opts.Addr)
-	return
+	return <-listenErrSignal
 }
 
 func (s *RespCmdService) AddRespCmdConn(c driver.IRespConn) {
@@ -232,16 +506,59 @@ func (s *RespCmdService) DelRespCmdConn(c driver.IRespConn) {
 	s.rcm.Unlock()
 }
 
+// CloseAllRespCmdConnect close all resp cmd connects,
+// close net conn to let the connect serve goroutine clean up,
+// the conn writer isn't touched out of its serve goroutine
 func (s *RespCmdService) CloseAllRespCmdConnect() {
+	n := s.KillRespCmdConns(func(c *RespCmdConn) bool { return true })
+	klog.Debugf("close %d conns", n)
+}
+
+// KillRespCmdConns close the resp cmd connects which match filter,
+// close net conn to let the connect serve goroutine clean up.
+// return killed connect num
+func (s *RespCmdService) KillRespCmdConns(filter func(c *RespCmdConn) bool) (n int) {
+	s.rcm.Lock()
+	defer s.rcm.Unlock()
+	for c := range s.respConnMap {
+		respCmdConn, ok := c.(*RespCmdConn)
+		if !ok || !filter(respCmdConn) {
+			continue
+		}
+		if err := respCmdConn.Conn.NetConn().Close(); err != nil {
+			klog.Errorf("kill conn %s err %s", respCmdConn.GetRemoteAddr(), err.Error())
+			continue
+		}
+		n++
+	}
+	return
+}
+
+// respCmdConnByID the resp cmd connect of the client id, nil if it's not connected
+func (s *RespCmdService) respCmdConnByID(id int64) *RespCmdConn {
+	s.rcm.Lock()
+	defer s.rcm.Unlock()
+	for c := range s.respConnMap {
+		if respCmdConn, ok := c.(*RespCmdConn); ok && respCmdConn.id == id {
+			return respCmdConn
+		}
+	}
+	return nil
+}
+
+// RespCmdConns return the resp cmd connects sorted by client id
+func (s *RespCmdService) RespCmdConns() []*RespCmdConn {
 	s.rcm.Lock()
+	conns := make([]*RespCmdConn, 0, len(s.respConnMap))
 	for c := range s.respConnMap {
-		if err := c.Close(); err != nil {
-			klog.Errorf("close conn %s err %s", c.Name(), err.Error())
-		} else {
-			klog.Debugf("close conn %s ok", c.Name())
+		if respCmdConn, ok := c.(*RespCmdConn); ok {
+			conns = append(conns, respCmdConn)
 		}
 	}
 	s.rcm.Unlock()
+
+	sort.Slice(conns, func(i, j int) bool { return conns[i].id < conns[j].id })
+	return conns
 }
 
 func (s *RespCmdService) RespCmdConnectNum() int {
@@ -254,3 +571,8 @@ func (s *RespCmdService) RespCmdConnectNum() int {
 func (s *RespCmdService) SetSrvInfo(info driver.ISrvInfo) {
 	s.info = info
 }
+
+// idleClose idle conn close time of the listeners, ConnKeepaliveInterval is in seconds
+func (s *RespCmdService) idleClose() time.Duration {
+	return time.Duration(s.opts.ConnKeepaliveInterval) * time.Second
+}
@@ -0,0 +1,174 @@
+package standalone
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/redcon"
+)
+
+// the rejected conn is closed after the err reply is written or the write deadline
+const connRejectDeadline = 100 * time.Millisecond
+
+// connAdmission admission control of accepted conns by
+// max clients, max clients per source ip and allow/deny cidr list
+type connAdmission struct {
+	mu sync.Mutex
+	// admitted conns num and source ip -> admitted conns num
+	conns   int
+	ipConns map[string]int
+
+	maxClients      int
+	maxClientsPerIP int
+	// the conn is denied if the source ip is in deny list,
+	// or the allow list isn't empty and the ip is not in it
+	allowNets []*net.IPNet
+	denyNets  []*net.IPNet
+}
+
+func newConnAdmission() *connAdmission {
+	return &connAdmission{ipConns: map[string]int{}}
+}
+
+// parseCIDRs parse comma separated cidr list, ip without mask is a single host
+func parseCIDRs(cidrs string) (nets []*net.IPNet, err error) {
+	for _, cidr := range strings.Split(cidrs, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if len(cidr) == 0 {
+			continue
+		}
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid cidr %q", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				bits = 8 * net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr %q", cidr)
+		}
+		nets = append(nets, ipNet)
+	}
+	return
+}
+
+// SetConfig set the limits and cidr lists
+func (a *connAdmission) SetConfig(maxClients, maxClientsPerIP int, allowCIDRs, denyCIDRs string) (err error) {
+	if maxClients < 0 || maxClientsPerIP < 0 {
+		return fmt.Errorf("argument must be greater than or equal to 0")
+	}
+	allowNets, err := parseCIDRs(allowCIDRs)
+	if err != nil {
+		return
+	}
+	denyNets, err := parseCIDRs(denyCIDRs)
+	if err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	a.maxClients, a.maxClientsPerIP = maxClients, maxClientsPerIP
+	a.allowNets, a.denyNets = allowNets, denyNets
+	a.mu.Unlock()
+	return
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// connIP the source ip of conn, empty for unix socket conn
+func connIP(conn redcon.Conn) string {
+	if isUnixConn(conn) {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(conn.RemoteAddr())
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+// admit check the conn from the source ip whether can be admitted and count it,
+// the unix socket conn with empty ip is only limited by max clients
+func (a *connAdmission) admit(ip string) (err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(ip) > 0 {
+		netIP := net.ParseIP(ip)
+		if containsIP(a.denyNets, netIP) || (len(a.allowNets) > 0 && !containsIP(a.allowNets, netIP)) {
+			return ErrConnAddrNotAllowed
+		}
+	}
+	if a.maxClients > 0 && a.conns >= a.maxClients {
+		return ErrMaxClients
+	}
+	if len(ip) > 0 && a.maxClientsPerIP > 0 && a.ipConns[ip] >= a.maxClientsPerIP {
+		return ErrMaxClientsPerIP
+	}
+
+	a.conns++
+	if len(ip) > 0 {
+		a.ipConns[ip]++
+	}
+	return
+}
+
+// release uncount the admitted conn from the source ip
+func (a *connAdmission) release(ip string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.conns--
+	if len(ip) == 0 {
+		return
+	}
+	if a.ipConns[ip]--; a.ipConns[ip] <= 0 {
+		delete(a.ipConns, ip)
+	}
+}
+
+// applyAdmissionConfig apply the admission options by CONFIG SET,
+// the admitted conns are kept
+func applyAdmissionConfig(s *RespCmdService) error {
+	return s.admission.SetConfig(s.opts.MaxClients, s.opts.MaxClientsPerIP, s.opts.AllowCIDRs, s.opts.DenyCIDRs)
+}
+
+// rejectConn reply the err to the rejected conn and count it,
+// the conn is closed by redcon after the reply is flushed in the accept loop;
+// the tls conn is closed silently, the reply would run the tls handshake in the accept loop
+func (s *RespCmdService) rejectConn(conn redcon.Conn, err error) {
+	switch err {
+	case ErrMaxClients:
+		s.stats.connsRejectedMaxClients.Add(1)
+	case ErrMaxClientsPerIP:
+		s.stats.connsRejectedPerIP.Add(1)
+	case ErrConnAddrNotAllowed:
+		s.stats.connsRejectedCIDR.Add(1)
+	}
+	s.stats.connsRejected.Add(1)
+
+	netConn := conn.NetConn()
+	if _, ok := netConn.(*tls.Conn); ok {
+		return
+	}
+	// the small reply is written to the socket buffer, the deadline is for the slow client
+	if netConn != nil {
+		netConn.SetWriteDeadline(time.Now().Add(connRejectDeadline))
+	}
+	conn.WriteError(err.Error())
+}
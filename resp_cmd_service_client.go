@@ -0,0 +1,92 @@
+package standalone
+
+import (
+	"sync"
+	"time"
+)
+
+// cmds which are paused by CLIENT PAUSE WRITE besides write cmds
+var pauseWriteCmds = map[string]struct{}{
+	"eval":    {},
+	"evalsha": {},
+	"exec":    {},
+	"publish": {},
+}
+
+// clientPause CLIENT PAUSE state, more detail reference:
+// https://redis.io/commands/client-pause/
+type clientPause struct {
+	mu sync.Mutex
+	// pause all cmds, otherwise only write cmds
+	all bool
+	end time.Time
+	// closed by unpause to wake up the paused conns
+	unpauseCh chan struct{}
+}
+
+// Pause pause client cmds until timeout,
+// the longer timeout and the more restrictive mode win
+func (p *clientPause) Pause(all bool, timeout time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if now.After(p.end) {
+		p.all = false
+	}
+	if end := now.Add(timeout); end.After(p.end) {
+		p.end = end
+	}
+	p.all = p.all || all
+	if p.unpauseCh == nil {
+		p.unpauseCh = make(chan struct{})
+	}
+}
+
+// Unpause resume the paused conns, return true if it was paused
+func (p *clientPause) Unpause() (paused bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.all = false
+	p.end = time.Time{}
+	if p.unpauseCh != nil {
+		close(p.unpauseCh)
+		p.unpauseCh = nil
+		paused = true
+	}
+	return
+}
+
+// Wait block until the cmd isn't paused,
+// CLIENT cmd isn't paused to unpause
+func (p *clientPause) Wait(cmd string) {
+	if cmd == "client" {
+		return
+	}
+
+	for {
+		p.mu.Lock()
+		now := time.Now()
+		if !now.Before(p.end) || (!p.all && !isPauseWriteCmd(cmd)) {
+			p.mu.Unlock()
+			return
+		}
+		timer := time.NewTimer(p.end.Sub(now))
+		unpauseCh := p.unpauseCh
+		p.mu.Unlock()
+
+		select {
+		case <-timer.C:
+		case <-unpauseCh:
+		}
+		timer.Stop()
+	}
+}
+
+func isPauseWriteCmd(cmd string) bool {
+	if _, ok := pauseWriteCmds[cmd]; ok {
+		return true
+	}
+	return cmdHasFlag(cmd, cmdFlagWrite)
+}
@@ -0,0 +1,348 @@
+package standalone
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/match"
+)
+
+// redis config param names alias to srv options mapstructure tag names
+var configAliases = map[string]string{
+	"requirepass":             "authPassword",
+	"timeout":                 "connKeepaliveInterval",
+	"aclfile":                 "aclFile",
+	"slowlog-log-slower-than": "slowlogLogSlowerThan",
+	"slowlog-max-len":         "slowlogMaxLen",
+	"maxclients":              "maxClients",
+	"shutdown-timeout":        "shutdownTimeout",
+	"notify-keyspace-events":  "notifyKeyspaceEvents",
+}
+
+// configAppliers apply the changed option to the running srv by CONFIG SET,
+// the option without applier is immutable
+var configAppliers = map[string]func(s *RespCmdService) error{
+	"authPassword": func(s *RespCmdService) error {
+		if len(s.opts.AuthPassword) == 0 {
+			return s.acl.SetUser(DefaultAclUserName, "resetpass", "nopass")
+		}
+		return s.acl.SetUser(DefaultAclUserName, "resetpass", ">"+s.opts.AuthPassword)
+	},
+	"connKeepaliveInterval": func(s *RespCmdService) error {
+		if s.opts.ConnKeepaliveInterval < 0 {
+			return fmt.Errorf("argument must be greater than or equal to 0")
+		}
+		if s.redconSrv != nil {
+			s.redconSrv.SetIdleClose(s.idleClose())
+		}
+		if s.redconTLSSrv != nil {
+			s.redconTLSSrv.SetIdleClose(s.idleClose())
+		}
+		if s.redconUnixSrv != nil {
+			s.redconUnixSrv.SetIdleClose(s.idleClose())
+		}
+		return nil
+	},
+	"slowlogLogSlowerThan": applySlowLogConfig,
+	"slowlogMaxLen":        applySlowLogConfig,
+	"maxClients":           applyAdmissionConfig,
+	"maxClientsPerIp":      applyAdmissionConfig,
+	"allowCidrs":           applyAdmissionConfig,
+	"denyCidrs":            applyAdmissionConfig,
+	"shutdownTimeout": func(s *RespCmdService) error {
+		if s.opts.ShutdownTimeout < 0 {
+			return fmt.Errorf("argument must be greater than or equal to 0")
+		}
+		return nil
+	},
+	"notifyKeyspaceEvents": applyNotifyConfig,
+	"traceSampleRatio": func(s *RespCmdService) error {
+		if s.opts.TraceSampleRatio < 0 || s.opts.TraceSampleRatio > 1 {
+			return fmt.Errorf("argument must be between 0 and 1")
+		}
+		if s.traceSampler != nil {
+			s.traceSampler.SetRatio(s.opts.TraceSampleRatio)
+		}
+		return nil
+	},
+}
+
+func applySlowLogConfig(s *RespCmdService) error {
+	if s.opts.SlowlogMaxLen < 0 {
+		return fmt.Errorf("argument must be greater than or equal to 0")
+	}
+	s.slowLog.SetConfig(s.opts.SlowlogLogSlowerThan, s.opts.SlowlogMaxLen)
+	return nil
+}
+
+// configParam srv option field with mapstructure tag name
+type configParam struct {
+	name  string
+	field reflect.Value
+}
+
+// configParams srv option params in struct field order
+func (s *RespCmdService) configParams() (params []configParam) {
+	rv := reflect.ValueOf(s.opts).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		name := rt.Field(i).Tag.Get("mapstructure")
+		if len(name) == 0 || name == "-" {
+			continue
+		}
+		params = append(params, configParam{name: name, field: rv.Field(i)})
+	}
+	return
+}
+
+// configParam find srv option param by name or redis alias, case insensitive
+func (s *RespCmdService) configParam(name string) (param configParam, ok bool) {
+	name = strings.ToLower(name)
+	if alias, has := configAliases[name]; has {
+		name = strings.ToLower(alias)
+	}
+	for _, param = range s.configParams() {
+		if strings.ToLower(param.name) == name {
+			return param, true
+		}
+	}
+	return
+}
+
+func (p configParam) String() string {
+	switch p.field.Kind() {
+	case reflect.Bool:
+		if p.field.Bool() {
+			return "yes"
+		}
+		return "no"
+	}
+	return fmt.Sprint(p.field.Interface())
+}
+
+// Set parse val to the option field
+func (p configParam) Set(val string) error {
+	switch p.field.Kind() {
+	case reflect.String:
+		p.field.SetString(val)
+	case reflect.Bool:
+		switch strings.ToLower(val) {
+		case "yes", "true":
+			p.field.SetBool(true)
+		case "no", "false":
+			p.field.SetBool(false)
+		default:
+			return fmt.Errorf("argument must be 'yes' or 'no'")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || p.field.OverflowInt(n) {
+			return fmt.Errorf("argument couldn't be parsed into an integer")
+		}
+		p.field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, 64)
+		if err != nil || p.field.OverflowUint(n) {
+			return fmt.Errorf("argument couldn't be parsed into an integer")
+		}
+		p.field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return fmt.Errorf("argument couldn't be parsed into a number")
+		}
+		p.field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported config type %s", p.field.Kind())
+	}
+	return nil
+}
+
+// tomlValue option value in toml format
+func (p configParam) tomlValue() string {
+	switch p.field.Kind() {
+	case reflect.String:
+		return strconv.Quote(p.field.String())
+	}
+	return fmt.Sprint(p.field.Interface())
+}
+
+// ConfigGet get srv option params which name or redis alias matches the patterns,
+// return name, value pairs
+func (s *RespCmdService) ConfigGet(patterns ...string) (pairs []string) {
+	matched := map[string]struct{}{}
+	add := func(name, val string) {
+		if _, ok := matched[name]; ok {
+			return
+		}
+		matched[name] = struct{}{}
+		pairs = append(pairs, name, val)
+	}
+
+	params := s.configParams()
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		for _, param := range params {
+			if match.Match(strings.ToLower(param.name), pattern) {
+				add(param.name, param.String())
+			}
+		}
+		for alias, name := range configAliases {
+			if !match.Match(alias, pattern) {
+				continue
+			}
+			if param, ok := s.configParam(name); ok {
+				add(alias, param.String())
+			}
+		}
+	}
+	return
+}
+
+// ConfigSet set srv option params with name, value pairs and apply to the running srv,
+// all params are set or none if one of them failed
+func (s *RespCmdService) ConfigSet(pairs ...string) (err error) {
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return fmt.Errorf("ERR wrong number of arguments for 'config|set' command")
+	}
+
+	type change struct {
+		param configParam
+		arg   string
+		old   reflect.Value
+	}
+	changes := make([]change, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		param, ok := s.configParam(pairs[i])
+		if !ok {
+			return fmt.Errorf("ERR Unknown option or number of arguments for CONFIG SET - '%s'", pairs[i])
+		}
+		if _, ok := configAppliers[param.name]; !ok {
+			return fmt.Errorf("ERR CONFIG SET failed (possibly related to argument '%s') - can't set immutable config", pairs[i])
+		}
+		old := reflect.New(param.field.Type()).Elem()
+		old.Set(param.field)
+		changes = append(changes, change{param: param, arg: pairs[i], old: old})
+	}
+
+	rollback := func() {
+		for _, c := range changes {
+			c.param.field.Set(c.old)
+		}
+		for _, c := range changes {
+			configAppliers[c.param.name](s)
+		}
+	}
+	for i, c := range changes {
+		if err = c.param.Set(pairs[2*i+1]); err != nil {
+			rollback()
+			return fmt.Errorf("ERR CONFIG SET failed (possibly related to argument '%s') - %s", c.arg, err.Error())
+		}
+	}
+	for _, c := range changes {
+		if err = configAppliers[c.param.name](s); err != nil {
+			rollback()
+			return fmt.Errorf("ERR CONFIG SET failed (possibly related to argument '%s') - %s", c.arg, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// SetConfigFile set the config file which srv options are loaded from,
+// CONFIG REWRITE persists the options to it
+func (s *RespCmdService) SetConfigFile(file string) {
+	s.configFile = file
+}
+
+// ConfigRewrite rewrite the config file with the current srv options,
+// the comments and the other keys and tables are kept,
+// the options which are not in the file are added before the first table
+func (s *RespCmdService) ConfigRewrite() (err error) {
+	if len(s.configFile) == 0 {
+		return ErrConfigNoFile
+	}
+
+	mode := os.FileMode(0644)
+	data, err := os.ReadFile(s.configFile)
+	switch {
+	case err == nil:
+		if fi, statErr := os.Stat(s.configFile); statErr == nil {
+			mode = fi.Mode().Perm()
+		}
+	case os.IsNotExist(err):
+		err = nil
+	default:
+		return
+	}
+
+	params := map[string]configParam{}
+	for _, param := range s.configParams() {
+		params[param.name] = param
+	}
+	lines := []string{}
+	firstTable := -1
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && firstTable < 0 {
+			firstTable = len(lines)
+		}
+		// options are the top level keys before the first table
+		if key, _, ok := strings.Cut(trimmed, "="); ok && firstTable < 0 && !strings.HasPrefix(trimmed, "#") {
+			key = strings.TrimSpace(key)
+			if param, ok := params[key]; ok {
+				line = key + " = " + param.tomlValue()
+				delete(params, key)
+			}
+		}
+		lines = append(lines, line)
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+
+	added := []string{}
+	for _, param := range s.configParams() {
+		if _, ok := params[param.name]; ok {
+			added = append(added, param.name+" = "+param.tomlValue())
+		}
+	}
+	if len(added) > 0 {
+		if firstTable < 0 {
+			firstTable = len(lines)
+		}
+		added = append(added, "")
+		lines = append(lines[:firstTable], append(added, lines[firstTable:]...)...)
+	}
+
+	// write tmp file and rename for atomic rewrite
+	tmpFile, err := os.CreateTemp(filepath.Dir(s.configFile), filepath.Base(s.configFile)+".tmp-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err = tmpFile.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		tmpFile.Close()
+		return
+	}
+	if err = tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return
+	}
+	if err = tmpFile.Close(); err != nil {
+		return
+	}
+	if err = os.Chmod(tmpFile.Name(), mode); err != nil {
+		return
+	}
+
+	return os.Rename(tmpFile.Name(), s.configFile)
+}
@@ -0,0 +1,88 @@
+package standalone
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/weedge/pkg/driver"
+)
+
+// swapDBStorager storager which swaps the dbs of the two indexes in the storage,
+// the swap is persisted and seen by the storager paths, e.g. slots restore, replication and keyspace stats;
+// SWAPDB is supported only if the storager implements it
+type swapDBStorager interface {
+	SwapDB(ctx context.Context, idx1, idx2 int) error
+}
+
+// swapDB swap the dbs of the two db indexes in the storager and for all conns,
+// the other conns fields aren't touched out of their goroutines;
+// the caller must hold srv cmd write lock for atomicity
+func (s *RespCmdService) swapDB(ctx context.Context, idx1, idx2 int) (err error) {
+	store, ok := s.store.(swapDBStorager)
+	if !ok {
+		return ErrSwapDBNotSupported
+	}
+	if _, err = s.store.Select(ctx, idx1); err != nil {
+		return
+	}
+	if _, err = s.store.Select(ctx, idx2); err != nil {
+		return
+	}
+	if idx1 == idx2 {
+		return
+	}
+
+	if err = store.SwapDB(ctx, idx1, idx2); err != nil {
+		return
+	}
+	if s.volatileKeys != nil {
+		s.volatileKeys.swap(idx1, idx2)
+	}
+	// the conns reselect the swapped dbs before their next cmds
+	s.dbSwaps.Add(1)
+	return
+}
+
+// ttlPrecision the storager ttl precision, ms or s
+func (s *RespCmdService) ttlPrecision() string {
+	if s.store == nil {
+		return "s"
+	}
+	db, err := s.store.Select(context.Background(), 0)
+	if err != nil {
+		return "s"
+	}
+	return ttlPrecision(db)
+}
+
+// keyspaceStats the storager keyspace stats sorted by db index,
+// e.g. db0:keys=1,expires=0,avg_ttl=0
+func (s *RespCmdService) keyspaceStats() []driver.InfoPair {
+	pairs := append([]driver.InfoPair{}, s.store.StatsInfo("keyspace")["keyspace"]...)
+	sort.SliceStable(pairs, func(i, j int) bool {
+		idx1, _ := strconv.Atoi(strings.TrimPrefix(pairs[i].Key, "db"))
+		idx2, _ := strconv.Atoi(strings.TrimPrefix(pairs[j].Key, "db"))
+		return idx1 < idx2
+	})
+	return pairs
+}
+
+// dbSize the keys num of the db index from the keyspace stats
+func (s *RespCmdService) dbSize(idx int) int64 {
+	db := "db" + strconv.Itoa(idx)
+	for _, pair := range s.keyspaceStats() {
+		if pair.Key != db {
+			continue
+		}
+		for _, field := range strings.Split(fmt.Sprint(pair.Value), ",") {
+			if v, ok := strings.CutPrefix(field, "keys="); ok {
+				n, _ := strconv.ParseInt(v, 10, 64)
+				return n
+			}
+		}
+	}
+	return 0
+}
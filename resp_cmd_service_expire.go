@@ -0,0 +1,232 @@
+package standalone
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/kitex/pkg/klog"
+	"github.com/weedge/pkg/driver"
+)
+
+// expired keys more detail reference:
+// https://redis.io/commands/expire/#how-redis-expires-keys
+
+const (
+	// the active expire cycle runs at the interval
+	activeExpireInterval = 100 * time.Millisecond
+	// volatile keys sampled in one round of the active expire cycle,
+	// the next round runs if more than 1/4 of them are expired like redis
+	activeExpireSampleKeys = 20
+	activeExpireMaxRounds  = 16
+)
+
+// ttlCmds write cmds which may set the key ttl, the keys ttl are indexed after the cmds
+var ttlCmds = map[string]struct{}{
+	"expire":       {},
+	"expireat":     {},
+	"pexpire":      {},
+	"pexpireat":    {},
+	"set":          {},
+	"setex":        {},
+	"psetex":       {},
+	"setnxex":      {},
+	"setxxex":      {},
+	"getex":        {},
+	"incrbyfloat":  {},
+	"rename":       {},
+	"renamenx":     {},
+	"copy":         {},
+	"lexpire":      {},
+	"lexpireat":    {},
+	"hexpire":      {},
+	"hexpireat":    {},
+	"sexpire":      {},
+	"sexpireat":    {},
+	"zexpire":      {},
+	"zexpireat":    {},
+	"slotsrestore": {},
+}
+
+// volatileKey key with ttl in the db
+type volatileKey struct {
+	dbIdx int
+	key   string
+}
+
+// volatileKeys index of the keys with ttl which are set by cmds -> expire at unix milliseconds,
+// the expired events are notified when the indexed keys are found expired in the storager,
+// lazily by the cmds which access them or actively by the expire cycle;
+// it's used only if the storager doesn't notify the expired keys
+type volatileKeys struct {
+	mu   sync.Mutex
+	keys map[volatileKey]int64
+}
+
+func newVolatileKeys() *volatileKeys {
+	return &volatileKeys{keys: map[volatileKey]int64{}}
+}
+
+func (vk *volatileKeys) set(dbIdx int, key []byte, at int64) {
+	vk.mu.Lock()
+	vk.keys[volatileKey{dbIdx, string(key)}] = at
+	vk.mu.Unlock()
+}
+
+func (vk *volatileKeys) remove(dbIdx int, key []byte) {
+	vk.mu.Lock()
+	delete(vk.keys, volatileKey{dbIdx, string(key)})
+	vk.mu.Unlock()
+}
+
+// expireAt the expire unix milliseconds of the key, 0 if it isn't indexed
+func (vk *volatileKeys) expireAt(dbIdx int, key []byte) int64 {
+	vk.mu.Lock()
+	defer vk.mu.Unlock()
+	return vk.keys[volatileKey{dbIdx, string(key)}]
+}
+
+// flush remove the keys of the db, all dbs if dbIdx < 0
+func (vk *volatileKeys) flush(dbIdx int) {
+	vk.mu.Lock()
+	defer vk.mu.Unlock()
+	for k := range vk.keys {
+		if dbIdx < 0 || k.dbIdx == dbIdx {
+			delete(vk.keys, k)
+		}
+	}
+}
+
+// swap swap the keys of the two dbs
+func (vk *volatileKeys) swap(dbIdx1, dbIdx2 int) {
+	vk.mu.Lock()
+	defer vk.mu.Unlock()
+	keys := make(map[volatileKey]int64, len(vk.keys))
+	for k, at := range vk.keys {
+		switch k.dbIdx {
+		case dbIdx1:
+			k.dbIdx = dbIdx2
+		case dbIdx2:
+			k.dbIdx = dbIdx1
+		}
+		keys[k] = at
+	}
+	vk.keys = keys
+}
+
+// sample sample n keys randomly by the map iteration, return the expired ones
+func (vk *volatileKeys) sample(now int64, n int) (expired []volatileKey, sampled int) {
+	vk.mu.Lock()
+	defer vk.mu.Unlock()
+	for k, at := range vk.keys {
+		if sampled == n {
+			break
+		}
+		sampled++
+		if at <= now {
+			expired = append(expired, k)
+		}
+	}
+	return
+}
+
+// indexKeysTTL index the keys ttl after the write cmd ok,
+// the indexed keys are updated by any write cmd since they may be deleted or persisted
+func (s *RespCmdService) indexKeysTTL(ctx context.Context, c *RespCmdConn, cmd string, keys [][]byte) {
+	if s.volatileKeys == nil {
+		return
+	}
+
+	_, ttlCmd := ttlCmds[cmd]
+	dbIdx := c.dbIdx
+	for _, key := range keys {
+		if !ttlCmd && s.volatileKeys.expireAt(dbIdx, key) == 0 {
+			continue
+		}
+		ttl := int64(-1)
+		kt, ok, err := keyTypeOf(ctx, c.Db(), key)
+		if err == nil && ok {
+			ttl, err = keyPTTL(ctx, kt.cmd, key)
+		}
+		if err != nil {
+			klog.Warnf("index key %s ttl err: %s", key, err.Error())
+			continue
+		}
+		if ttl < 0 {
+			s.volatileKeys.remove(dbIdx, key)
+			continue
+		}
+		s.volatileKeys.set(dbIdx, key, time.Now().UnixMilli()+ttl)
+	}
+}
+
+// expireIfNeeded notify the expired events of the indexed keys
+// which are expired before the cmd accesses them like redis lazy expire
+func (s *RespCmdService) expireIfNeeded(ctx context.Context, c *RespCmdConn, keys [][]byte) {
+	if s.volatileKeys == nil || len(keys) == 0 {
+		return
+	}
+
+	dbIdx := c.dbIdx
+	now := time.Now().UnixMilli()
+	for _, key := range keys {
+		if at := s.volatileKeys.expireAt(dbIdx, key); at == 0 || at > now {
+			continue
+		}
+		s.expireKey(ctx, c.Db(), dbIdx, key)
+	}
+}
+
+// expireKey notify the expired event of the indexed key if the storager has expired it,
+// the key is kept indexed if the storager hasn't, e.g. the storager ttl is in seconds
+func (s *RespCmdService) expireKey(ctx context.Context, db driver.IDB, dbIdx int, key []byte) {
+	if _, ok, err := keyTypeOf(ctx, db, key); err != nil || ok {
+		return
+	}
+	s.volatileKeys.remove(dbIdx, key)
+	s.notifyKeyspaceEvent(notifyExpired, "expired", dbIdx, key)
+	s.invalidateKeys(nil, key)
+}
+
+// flushVolatileKeys remove the indexed keys of the db, all dbs if dbIdx < 0
+func (s *RespCmdService) flushVolatileKeys(dbIdx int) {
+	if s.volatileKeys != nil {
+		s.volatileKeys.flush(dbIdx)
+	}
+}
+
+// activeExpireLoop run the active expire cycle until done is closed
+func (s *RespCmdService) activeExpireLoop(done <-chan struct{}) {
+	ticker := time.NewTicker(activeExpireInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			s.activeExpireCycle()
+		}
+	}
+}
+
+// activeExpireCycle sample the indexed keys and notify the expired ones like redis active expire,
+// it runs with srv cmd lock as a read cmd
+func (s *RespCmdService) activeExpireCycle() {
+	ctx := context.Background()
+	s.cmdLock.RLock()
+	defer s.cmdLock.RUnlock()
+	for i := 0; i < activeExpireMaxRounds; i++ {
+		expired, sampled := s.volatileKeys.sample(time.Now().UnixMilli(), activeExpireSampleKeys)
+		for _, vk := range expired {
+			db, err := s.store.Select(ctx, vk.dbIdx)
+			if err != nil {
+				klog.Warnf("active expire select db %d err: %s", vk.dbIdx, err.Error())
+				continue
+			}
+			s.expireKey(ctx, db, vk.dbIdx, []byte(vk.key))
+		}
+		if len(expired)*4 <= sampled {
+			return
+		}
+	}
+}
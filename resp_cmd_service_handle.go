@@ -1,13 +1,147 @@
 package standalone
 
 import (
+	"context"
 	"strings"
+	"time"
 
 	"github.com/cloudwego/kitex/pkg/klog"
 	"github.com/tidwall/redcon"
 	"github.com/weedge/pkg/driver"
 )
 
+// ServeRESP serve resp cmd with mux,
+// tls conn is authenticated by client cert before the first cmd,
+// the cmd is refused when srv is shutting down, otherwise counted in-flight,
+// unknown cmd in transaction flags the transaction to abort EXEC,
+// the cmd waits if clients are paused, then is fed to monitors,
+// the reply is discarded if CLIENT REPLY is OFF or SKIP
+func (s *RespCmdService) ServeRESP(conn redcon.Conn, cmd redcon.Command) {
+	respCmdConn, ok := conn.Context().(*RespCmdConn)
+	if !ok {
+		s.mux.ServeRESP(conn, cmd)
+		return
+	}
+
+	// the pushes to the conn are ordered with the replies
+	wr := redcon.BaseWriter(conn)
+	respCmdConn.beginPush(wr)
+	defer respCmdConn.endPush(wr)
+
+	if !respCmdConn.tlsChecked {
+		respCmdConn.tlsChecked = true
+		s.tlsAuthConn(respCmdConn, conn)
+	}
+
+	if !s.cmdEnter() {
+		conn.WriteError(ErrShuttingDown.Error())
+		return
+	}
+	defer func() {
+		// flush the reply before the conn is closed by shutdown
+		if s.shuttingDown.Load() {
+			if wr := redcon.BaseWriter(conn); wr != nil {
+				wr.Flush()
+			}
+		}
+		// redcon conn close flushes the reply
+		if respCmdConn.closeAfterReply && !respCmdConn.Closed() {
+			respCmdConn.Close()
+		}
+		respCmdConn.running.Store(false)
+		s.cmdExit()
+	}()
+
+	cmdOp := strings.ToLower(string(cmd.Args[0]))
+	respCmdConn.touch(cmdOp)
+	respCmdConn.running.Store(true)
+	s.stats.cmdsProcessed.Add(1)
+	s.stats.netInputBytes.Add(int64(len(cmd.Raw)))
+	if respCmdConn.InMulti() {
+		if _, ok := cmdCategory(cmdOp); !ok {
+			respCmdConn.flagMultiErr()
+		}
+	}
+	if !respCmdConn.InMulti() || cmdOp == "exec" {
+		s.pause.Wait(cmdOp)
+	}
+
+	replyMode := respCmdConn.replyMode
+	if replyMode == clientReplyOn || wr == nil {
+		s.mux.ServeRESP(conn, cmd)
+		return
+	}
+
+	n := len(wr.Buffer())
+	s.mux.ServeRESP(conn, cmd)
+	// CLIENT REPLY ON is replied
+	if respCmdConn.replyMode == clientReplyOn {
+		return
+	}
+	wr.SetBuffer(wr.Buffer()[:n])
+	if respCmdConn.replyMode == clientReplySkip {
+		respCmdConn.setReplyMode(clientReplyOn)
+	}
+}
+
+// srvCmdHandle gate the srv cmd handle which writes to conn directly,
+// refuse it with NOAUTH until the connection is authenticated,
+// with NOPERM if the acl user has no permissions,
+// and refuse it in transaction;
+// the cmd stats and output bytes are recorded
+func (s *RespCmdService) srvCmdHandle(handle redcon.HandlerFunc) redcon.HandlerFunc {
+	return func(conn redcon.Conn, cmd redcon.Command) {
+		respCmdConn, ok := conn.Context().(*RespCmdConn)
+		if !ok {
+			conn.WriteError(ErrNoInitRespConn.Error())
+			return
+		}
+		wr := redcon.BaseWriter(conn)
+		n := 0
+		if wr != nil {
+			n = len(wr.Buffer())
+			defer func() {
+				// the writer of detached conn is owned by its loops
+				if respCmdConn.detached {
+					return
+				}
+				if m := len(wr.Buffer()); m > n {
+					s.stats.netOutputBytes.Add(int64(m - n))
+				}
+			}()
+		}
+
+		cmdOp := strings.ToLower(string(cmd.Args[0]))
+		if err := respCmdConn.checkPerm(cmdOp, cmd.Args[1:]); err != nil {
+			respCmdConn.flagMultiErr()
+			s.stats.cmdRejected(cmdOp)
+			s.stats.errorReply(err.Error())
+			conn.WriteError(err.Error())
+			return
+		}
+		if respCmdConn.InMulti() {
+			respCmdConn.flagMultiErr()
+			s.stats.cmdRejected(cmdOp)
+			s.stats.errorReply(ErrNotAllowedInMulti.Error())
+			conn.WriteError(ErrNotAllowedInMulti.Error())
+			return
+		}
+		if err := checkCmdArity(cmdOp, cmd.Args[1:]); err != nil {
+			s.stats.cmdRejected(cmdOp)
+			s.stats.errorReply(err.Error())
+			conn.WriteError(err.Error())
+			return
+		}
+		s.feedMonitors(respCmdConn.dbIdx, respCmdConn.GetRemoteAddr(), cmd.Args)
+
+		_, span := s.startCmdSpan(context.Background(), conn, cmdOp, cmd.Args[1:])
+		startTime := time.Now()
+		handle(conn, cmd)
+		s.stats.cmdCall(cmdOp, time.Since(startTime), nil)
+		endCmdSpan(span, nil)
+	}
+}
+
 // QuitCmd connect is closed by srv
 func (s *RespCmdService) QuitCmd(conn redcon.Conn, cmd redcon.Command) {
 	err := conn.Close()
@@ -34,33 +168,3 @@ func (s *RespCmdService) InfoCmd(conn redcon.Conn, cmd redcon.Command) {
 	blukInfo := s.info.DumpBytes(section)
 	conn.WriteBulk(blukInfo)
 }
-
-// PublishCmd pub to all pub/sub subscribers and return the number of
-// messages that were sent.
-func (s *RespCmdService) PublishCmd(conn redcon.Conn, cmd redcon.Command) {
-	if len(cmd.Args) != 3 {
-		conn.WriteError("ERR wrong number of arguments for '" + string(cmd.Args[0]) + "' command")
-		return
-	}
-	count := s.pubSub.Publish(string(cmd.Args[1]), string(cmd.Args[2]))
-	conn.WriteInt(count)
-}
-
-// Subscribe sub from a pub/sub channel. The `Psubscribe` and
-// `Subscribe` operations will detach the connection from the
-// event handler and manage all network I/O for this connection
-// in the background.
-func (s *RespCmdService) SubscribeCmd(conn redcon.Conn, cmd redcon.Command) {
-	if len(cmd.Args) < 2 {
-		conn.WriteError("ERR wrong number of arguments for '" + string(cmd.Args[0]) + "' command")
-		return
-	}
-	command := strings.ToLower(string(cmd.Args[0]))
-	for i := 1; i < len(cmd.Args); i++ {
-		if command == "psubscribe" {
-			s.pubSub.Psubscribe(conn, string(cmd.Args[i]))
-		} else {
-			s.pubSub.Subscribe(conn, string(cmd.Args[i]))
-		}
-	}
-}
@@ -22,6 +22,9 @@ func NewSrvInfo(srv *RespCmdService) (srvInfo *SrvInfo) {
 	srvInfo.srv = srv
 
 	driver.RegisterDumpHandler("server", srvInfo.DumpServer)
+	driver.RegisterDumpHandler("clients", srvInfo.DumpClients)
+	driver.RegisterDumpHandler("stats", srvInfo.DumpStats)
+	driver.RegisterDumpHandler("commandstats", srvInfo.DumpCommandStats)
 	driver.RegisterDumpHandler("memory", srvInfo.DumpMemory)
 	driver.RegisterDumpHandler("gcstats", srvInfo.DumpGCStats)
 	driver.RegisterDumpHandler("keyspace", srvInfo.DumpKeySpace)
@@ -64,12 +67,68 @@ func (m *SrvInfo) DumpServer(w io.Writer) {
 		driver.InfoPair{Key: "os", Value: runtime.GOOS},
 		driver.InfoPair{Key: "process_id", Value: os.Getpid()},
 		driver.InfoPair{Key: "addr", Value: m.srv.opts.Addr},
+		driver.InfoPair{Key: "tls_addr", Value: m.srv.opts.TLSAddr},
+		driver.InfoPair{Key: "unixsocket", Value: m.srv.opts.UnixSocket},
 		driver.InfoPair{Key: "goroutine_num", Value: runtime.NumGoroutine()},
 		driver.InfoPair{Key: "cgo_call_num", Value: runtime.NumCgoCall()},
 		driver.InfoPair{Key: "resp_client_num", Value: m.srv.RespCmdConnectNum()},
+		driver.InfoPair{Key: "ttl_precision", Value: m.srv.ttlPrecision()},
 	)
 }
 
+func (m *SrvInfo) DumpClients(w io.Writer) {
+	_, _, _, subscribers := m.srv.pubSubNumbers()
+	m.DumpPairs(w,
+		driver.InfoPair{Key: "connected_clients", Value: m.srv.RespCmdConnectNum()},
+		driver.InfoPair{Key: "blocked_clients", Value: m.srv.stats.blockedClients.Load()},
+		driver.InfoPair{Key: "monitor_clients", Value: m.srv.monitorNum.Load()},
+		driver.InfoPair{Key: "pubsub_clients", Value: subscribers},
+	)
+}
+
+func (m *SrvInfo) DumpStats(w io.Writer) {
+	st := m.srv.stats
+	channels, patterns, shardChannels, _ := m.srv.pubSubNumbers()
+	m.DumpPairs(w,
+		driver.InfoPair{Key: "total_connections_received", Value: st.connsReceived.Load()},
+		driver.InfoPair{Key: "total_commands_processed", Value: st.cmdsProcessed.Load()},
+		driver.InfoPair{Key: "instantaneous_ops_per_sec", Value: st.InstantaneousOps()},
+		driver.InfoPair{Key: "total_net_input_bytes", Value: st.netInputBytes.Load()},
+		driver.InfoPair{Key: "total_net_output_bytes", Value: st.netOutputBytes.Load()},
+		driver.InfoPair{Key: "rejected_connections", Value: st.connsRejected.Load()},
+		driver.InfoPair{Key: "rejected_connections_maxclients", Value: st.connsRejectedMaxClients.Load()},
+		driver.InfoPair{Key: "rejected_connections_per_ip", Value: st.connsRejectedPerIP.Load()},
+		driver.InfoPair{Key: "rejected_connections_cidr", Value: st.connsRejectedCIDR.Load()},
+		driver.InfoPair{Key: "keyspace_hits", Value: st.keyspaceHits.Load()},
+		driver.InfoPair{Key: "keyspace_misses", Value: st.keyspaceMisses.Load()},
+		driver.InfoPair{Key: "pubsub_channels", Value: channels},
+		driver.InfoPair{Key: "pubsub_patterns", Value: patterns},
+		driver.InfoPair{Key: "pubsubshard_channels", Value: shardChannels},
+	)
+}
+
+// # Commandstats
+// cmdstat_get:calls=1,usec=2,usec_per_call=2.00,rejected_calls=0,failed_calls=0
+func (m *SrvInfo) DumpCommandStats(w io.Writer) {
+	st := m.srv.stats
+	names := st.cmdStatsNames()
+	pairs := make([]driver.InfoPair, 0, len(names))
+	for _, name := range names {
+		cs := st.cmds[name]
+		calls, usec := cs.calls.Load(), cs.usec.Load()
+		usecPerCall := 0.0
+		if calls > 0 {
+			usecPerCall = float64(usec) / float64(calls)
+		}
+		pairs = append(pairs, driver.InfoPair{
+			Key: "cmdstat_" + name,
+			Value: fmt.Sprintf("calls=%d,usec=%d,usec_per_call=%.2f,rejected_calls=%d,failed_calls=%d",
+				calls, usec, usecPerCall, cs.rejectedCalls.Load(), cs.failedCalls.Load()),
+		})
+	}
+	m.DumpPairs(w, pairs...)
+}
+
 func (m *SrvInfo) DumpGCStats(w io.Writer) {
 	count := 5
 	var st debug.GCStats
@@ -129,10 +188,7 @@ func (m *SrvInfo) DumpStorageStats(w io.Writer) {
 // # Keyspace
 // db0:keys=1,expires=0,avg_ttl=0
 func (m *SrvInfo) DumpKeySpace(w io.Writer) {
-	data := m.srv.store.StatsInfo("keyspace")
-	if items, ok := data["keyspace"]; ok {
-		m.DumpPairs(w, items...)
-	}
+	m.DumpPairs(w, m.srv.keyspaceStats()...)
 }
 
 func (m *SrvInfo) DumpKeySpaceNoStats(w io.Writer) {
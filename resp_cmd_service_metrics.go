@@ -0,0 +1,179 @@
+package standalone
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/kitex/pkg/klog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	metricsNamespace = "xdis"
+	metricsPath      = "/metrics"
+)
+
+func newMetricDesc(name, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", name), help, labels, nil)
+}
+
+var (
+	connectedClientsDesc = newMetricDesc("connected_clients", "Number of client connections.")
+	blockedClientsDesc   = newMetricDesc("blocked_clients", "Number of clients running blocking commands.")
+	connsReceivedDesc    = newMetricDesc("connections_received_total", "Total number of connections accepted.")
+	connsRejectedDesc    = newMetricDesc("connections_rejected_total", "Total number of connections rejected.")
+	cmdsProcessedDesc    = newMetricDesc("commands_processed_total", "Total number of commands processed.")
+	netInputBytesDesc    = newMetricDesc("net_input_bytes_total", "Total bytes read from the network.")
+	netOutputBytesDesc   = newMetricDesc("net_output_bytes_total", "Total bytes written to the network.")
+	keyspaceHitsDesc     = newMetricDesc("keyspace_hits_total", "Total number of key lookups hit.")
+	keyspaceMissesDesc   = newMetricDesc("keyspace_misses_total", "Total number of key lookups missed.")
+
+	cmdCallsDesc         = newMetricDesc("commands_total", "Total number of calls per command.", "cmd")
+	cmdRejectedCallsDesc = newMetricDesc("commands_rejected_total", "Total number of rejected calls per command.", "cmd")
+	cmdFailedCallsDesc   = newMetricDesc("commands_failed_total", "Total number of failed calls per command.", "cmd")
+	cmdDurationDesc      = newMetricDesc("command_duration_seconds", "Command run time in seconds.", "cmd")
+	errorsDesc           = newMetricDesc("errors_total", "Total number of error replies per error prefix.", "prefix")
+
+	pubSubChannelsDesc    = newMetricDesc("pubsub_channels", "Number of pub/sub channels with subscribers.")
+	pubSubPatternsDesc    = newMetricDesc("pubsub_patterns", "Number of pub/sub patterns with subscribers.")
+	pubSubShardDesc       = newMetricDesc("pubsubshard_channels", "Number of pub/sub shard channels with subscribers.")
+	pubSubSubscribersDesc = newMetricDesc("pubsub_subscribers", "Number of pub/sub subscriber connections.")
+
+	slotsMgrtCallsDesc = newMetricDesc("slots_migrate_total", "Total number of slots migrate calls ok per command.", "cmd")
+	slotsMgrtKeysDesc  = newMetricDesc("slots_migrated_keys_total", "Total number of keys migrated per command.", "cmd")
+
+	keyspaceDesc = newMetricDesc("keyspace", "Keyspace info per db, e.g. keys, expires, avg_ttl.", "db", "field")
+)
+
+// srvMetrics prometheus collector of srv, metrics are collected from
+// the srv counters on scrape, nothing is updated in cmd dispatch path
+type srvMetrics struct {
+	srv *RespCmdService
+}
+
+func (m *srvMetrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		connectedClientsDesc, blockedClientsDesc,
+		connsReceivedDesc, connsRejectedDesc, cmdsProcessedDesc,
+		netInputBytesDesc, netOutputBytesDesc, keyspaceHitsDesc, keyspaceMissesDesc,
+		cmdCallsDesc, cmdRejectedCallsDesc, cmdFailedCallsDesc, cmdDurationDesc, errorsDesc,
+		pubSubChannelsDesc, pubSubPatternsDesc, pubSubShardDesc, pubSubSubscribersDesc,
+		slotsMgrtCallsDesc, slotsMgrtKeysDesc, keyspaceDesc,
+	} {
+		ch <- desc
+	}
+}
+
+func (m *srvMetrics) Collect(ch chan<- prometheus.Metric) {
+	st := m.srv.stats
+	gauge := func(desc *prometheus.Desc, v int64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(v), labels...)
+	}
+	counter := func(desc *prometheus.Desc, v int64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(v), labels...)
+	}
+
+	gauge(connectedClientsDesc, int64(m.srv.RespCmdConnectNum()))
+	gauge(blockedClientsDesc, st.blockedClients.Load())
+	counter(connsReceivedDesc, st.connsReceived.Load())
+	counter(connsRejectedDesc, st.connsRejected.Load())
+	counter(cmdsProcessedDesc, st.cmdsProcessed.Load())
+	counter(netInputBytesDesc, st.netInputBytes.Load())
+	counter(netOutputBytesDesc, st.netOutputBytes.Load())
+	counter(keyspaceHitsDesc, st.keyspaceHits.Load())
+	counter(keyspaceMissesDesc, st.keyspaceMisses.Load())
+
+	for _, name := range st.cmdStatsNames() {
+		cs := st.cmds[name]
+		counter(cmdCallsDesc, cs.calls.Load(), name)
+		counter(cmdRejectedCallsDesc, cs.rejectedCalls.Load(), name)
+		counter(cmdFailedCallsDesc, cs.failedCalls.Load(), name)
+
+		buckets := make(map[float64]uint64, len(cmdLatencyBounds))
+		cnt := uint64(0)
+		for i, bound := range cmdLatencyBounds {
+			cnt += uint64(cs.latency[i].Load())
+			buckets[bound.Seconds()] = cnt
+		}
+		cnt += uint64(cs.latency[len(cmdLatencyBounds)].Load())
+		ch <- prometheus.MustNewConstHistogram(cmdDurationDesc, cnt, float64(cs.usec.Load())/1e6, buckets, name)
+	}
+	for prefix, cnt := range st.errorCounts() {
+		counter(errorsDesc, cnt, prefix)
+	}
+
+	channels, patterns, shardChannels, subscribers := m.srv.pubSubNumbers()
+	gauge(pubSubChannelsDesc, int64(channels))
+	gauge(pubSubPatternsDesc, int64(patterns))
+	gauge(pubSubShardDesc, int64(shardChannels))
+	gauge(pubSubSubscribersDesc, int64(subscribers))
+
+	for name, ms := range st.slotsMgrts {
+		counter(slotsMgrtCallsDesc, ms.calls.Load(), name)
+		counter(slotsMgrtKeysDesc, ms.keys.Load(), name)
+	}
+
+	// keyspace pairs are like db0:keys=1,expires=0,avg_ttl=0
+	if m.srv.store == nil {
+		return
+	}
+	for _, pair := range m.srv.keyspaceStats() {
+		for _, field := range strings.Split(fmt.Sprint(pair.Value), ",") {
+			k, v, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(keyspaceDesc, prometheus.GaugeValue, n, pair.Key, k)
+		}
+	}
+}
+
+// newMetricsRegistry prometheus registry with srv, go runtime and process metrics
+func (s *RespCmdService) newMetricsRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		&srvMetrics{srv: s},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return reg
+}
+
+// startMetrics serve prometheus metrics on the metrics http listen address
+func (s *RespCmdService) startMetrics() (err error) {
+	ln, err := net.Listen("tcp", s.opts.MetricsAddr)
+	if err != nil {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, promhttp.HandlerFor(s.newMetricsRegistry(), promhttp.HandlerOpts{}))
+	s.metricsSrv = &http.Server{Handler: mux}
+	go func(srv *http.Server) {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			klog.Errorf("metrics http server serve err: %s", err.Error())
+		}
+	}(s.metricsSrv)
+	klog.Infof("metrics http server listening on address=%s", ln.Addr())
+	return
+}
+
+// closeMetrics close the metrics http server
+func (s *RespCmdService) closeMetrics() (err error) {
+	if s.metricsSrv == nil {
+		return
+	}
+	err = s.metricsSrv.Close()
+	s.metricsSrv = nil
+	return
+}
@@ -0,0 +1,298 @@
+package standalone
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/kitex/pkg/klog"
+	"github.com/tidwall/redcon"
+	"github.com/weedge/pkg/utils"
+)
+
+// monitor more detail reference:
+// https://redis.io/commands/monitor/
+
+// buffered lines of monitor conn, the slow monitor conn is disconnected when it's full
+const monitorConnBufferSize = 1024
+
+// redactedArg the sensitive arg replacement
+var redactedArg = []byte("(redacted)")
+
+// redactArgs the cmd args with the sensitive args (passwords) redacted like redis,
+// args are returned as is if nothing is redacted
+func redactArgs(args [][]byte) [][]byte {
+	if len(args) < 2 {
+		return args
+	}
+	var redacted [][]byte
+	redact := func(i int) {
+		if i >= len(args) {
+			return
+		}
+		if redacted == nil {
+			redacted = append([][]byte{}, args...)
+		}
+		redacted[i] = redactedArg
+	}
+	redactFrom := func(from int) {
+		for i := from; i < len(args); i++ {
+			redact(i)
+		}
+	}
+
+	switch strings.ToLower(utils.Bytes2String(args[0])) {
+	case "auth", "hello":
+		redactFrom(1)
+	case "acl":
+		// ACL SETUSER username rule...
+		if strings.EqualFold(utils.Bytes2String(args[1]), "setuser") {
+			redactFrom(3)
+		}
+	case "migrate":
+		// MIGRATE host port key db timeout [COPY] [REPLACE] [AUTH password | AUTH2 username password] [KEYS key...]
+		for i := 6; i < len(args); i++ {
+			op := strings.ToLower(utils.Bytes2String(args[i]))
+			if op == "keys" {
+				break
+			}
+			switch op {
+			case "auth":
+				redact(i + 1)
+				i++
+			case "auth2":
+				redact(i + 1)
+				redact(i + 2)
+				i += 2
+			}
+		}
+	case "config":
+		// CONFIG SET parameter value [parameter value ...]
+		if !strings.EqualFold(utils.Bytes2String(args[1]), "set") {
+			break
+		}
+		for i := 2; i+1 < len(args); i += 2 {
+			switch strings.ToLower(utils.Bytes2String(args[i])) {
+			case "requirepass", "masterauth":
+				redact(i + 1)
+			}
+		}
+	}
+
+	if redacted == nil {
+		return args
+	}
+	return redacted
+}
+
+// monitorConn detached conn which streams the executed cmds
+type monitorConn struct {
+	srv   *RespCmdService
+	dconn redcon.DetachedConn
+	msgs  chan monitorMsg
+	done  chan struct{}
+	once  sync.Once
+}
+
+// monitorMsg line to write, monitor conn is closed after quit msg is written
+type monitorMsg struct {
+	line []byte
+	quit bool
+}
+
+// MonitorCmd detach the conn and stream every executed cmd to it
+func (s *RespCmdService) MonitorCmd(conn redcon.Conn, cmd redcon.Command) {
+	if len(cmd.Args) != 1 {
+		conn.WriteError("ERR wrong number of arguments for '" + string(cmd.Args[0]) + "' command")
+		return
+	}
+
+	if respCmdConn, ok := conn.Context().(*RespCmdConn); ok {
+		respCmdConn.detached = true
+	}
+	m := &monitorConn{
+		srv:   s,
+		dconn: conn.Detach(),
+		msgs:  make(chan monitorMsg, monitorConnBufferSize),
+		done:  make(chan struct{}),
+	}
+	// idle close isn't for the monitor conn
+	if netConn := m.dconn.NetConn(); netConn != nil {
+		netConn.SetReadDeadline(time.Time{})
+	}
+	m.msgs <- monitorMsg{line: []byte("+OK\r\n")}
+
+	s.mm.Lock()
+	s.monitors[m] = struct{}{}
+	s.monitorNum.Store(int64(len(s.monitors)))
+	s.mm.Unlock()
+
+	go m.writeLoop()
+	go m.readLoop()
+}
+
+// writeLoop write the buffered lines to monitor conn
+func (m *monitorConn) writeLoop() {
+	for {
+		select {
+		case <-m.done:
+			return
+		case msg := <-m.msgs:
+			m.dconn.WriteRaw(msg.line)
+			// batch the buffered lines
+			quit := msg.quit
+			for n := len(m.msgs); n > 0 && !quit; n-- {
+				msg = <-m.msgs
+				m.dconn.WriteRaw(msg.line)
+				quit = msg.quit
+			}
+			if err := m.dconn.Flush(); err != nil || quit {
+				m.close()
+				return
+			}
+		}
+	}
+}
+
+// readLoop read cmds from monitor conn until it's closed, only QUIT is allowed
+func (m *monitorConn) readLoop() {
+	for {
+		cmd, err := m.dconn.ReadCommand()
+		if err != nil {
+			m.close()
+			return
+		}
+
+		op := strings.ToLower(string(cmd.Args[0]))
+		if op == "quit" {
+			if !m.send(monitorMsg{line: []byte("+OK\r\n"), quit: true}) {
+				m.close()
+			}
+			return
+		}
+		line := redcon.AppendError(nil, fmt.Sprintf("ERR Can't execute '%s': only QUIT is allowed in this context", op))
+		if !m.send(monitorMsg{line: line}) {
+			m.close()
+			return
+		}
+	}
+}
+
+// send send msg to monitor conn without blocking,
+// return false if the monitor conn is too slow to buffer the msg
+func (m *monitorConn) send(msg monitorMsg) bool {
+	select {
+	case m.msgs <- msg:
+	case <-m.done:
+	default:
+		return false
+	}
+	return true
+}
+
+// close close the monitor conn and release the resp cmd conn,
+// the net conn is closed to stop the read loop, the conn writer is owned by write loop
+func (m *monitorConn) close() {
+	m.once.Do(func() {
+		s := m.srv
+		s.mm.Lock()
+		delete(s.monitors, m)
+		s.monitorNum.Store(int64(len(s.monitors)))
+		s.mm.Unlock()
+
+		close(m.done)
+		if netConn := m.dconn.NetConn(); netConn != nil {
+			netConn.Close()
+		}
+		if respCmdConn, ok := m.dconn.Context().(*RespCmdConn); ok {
+			s.releaseRespCmdConn(respCmdConn)
+		}
+	})
+}
+
+// closeMonitors close all monitor conns
+func (s *RespCmdService) closeMonitors() {
+	s.mm.RLock()
+	monitors := make([]*monitorConn, 0, len(s.monitors))
+	for m := range s.monitors {
+		monitors = append(monitors, m)
+	}
+	s.mm.RUnlock()
+
+	for _, m := range monitors {
+		m.close()
+	}
+}
+
+// feedMonitors stream the cmd to monitor conns,
+// it's fed after the cmd passes auth/acl/arity checks like redis call()
+func (s *RespCmdService) feedMonitors(db int, addr string, args [][]byte) {
+	if s.monitorNum.Load() == 0 || len(args) == 0 {
+		return
+	}
+
+	msg := monitorMsg{line: monitorLine(time.Now(), db, addr, args)}
+	slowMonitors := []*monitorConn{}
+	s.mm.RLock()
+	for m := range s.monitors {
+		if !m.send(msg) {
+			slowMonitors = append(slowMonitors, m)
+		}
+	}
+	s.mm.RUnlock()
+
+	// disconnect the slow monitor conns, don't block the cmd path
+	for _, m := range slowMonitors {
+		klog.Warnf("monitor conn %s is too slow, disconnect", m.dconn.RemoteAddr())
+		m.close()
+	}
+}
+
+// monitorLine format monitor line: +<unix-ts.micros> [db addr] "cmd" "arg"...
+func monitorLine(now time.Time, db int, addr string, args [][]byte) []byte {
+	b := []byte{'+'}
+	b = strconv.AppendInt(b, now.Unix(), 10)
+	b = append(b, '.')
+	b = append(b, fmt.Sprintf("%06d", now.Nanosecond()/1000)...)
+	b = append(b, " ["...)
+	b = strconv.AppendInt(b, int64(db), 10)
+	b = append(b, ' ')
+	b = append(b, addr...)
+	b = append(b, ']')
+
+	for _, arg := range redactArgs(args) {
+		b = append(b, ' ')
+		b = appendRepr(b, arg)
+	}
+	return append(b, '\r', '\n')
+}
+
+// appendRepr append quoted and escaped arg as redis sdscatrepr
+func appendRepr(b []byte, arg []byte) []byte {
+	b = append(b, '"')
+	for _, c := range arg {
+		switch c {
+		case '\\', '"':
+			b = append(b, '\\', c)
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		case '\a':
+			b = append(b, '\\', 'a')
+		case '\b':
+			b = append(b, '\\', 'b')
+		default:
+			if c < 0x20 || c > 0x7e {
+				b = append(b, fmt.Sprintf("\\x%02x", c)...)
+				continue
+			}
+			b = append(b, c)
+		}
+	}
+	return append(b, '"')
+}
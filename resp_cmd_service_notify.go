@@ -0,0 +1,174 @@
+package standalone
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/weedge/pkg/driver"
+)
+
+// keyspace notifications more detail reference:
+// https://redis.io/docs/manual/keyspace-notifications/
+
+// notifyClass keyspace event classes of notify-keyspace-events
+type notifyClass int
+
+const (
+	// K: keyspace events, published with __keyspace@<db>__ prefix
+	notifyKeyspace notifyClass = 1 << iota
+	// E: keyevent events, published with __keyevent@<db>__ prefix
+	notifyKeyevent
+	// g: generic cmds (non-type specific) like DEL, EXPIRE
+	notifyGeneric
+	// $: string cmds
+	notifyString
+	// l: list cmds
+	notifyList
+	// s: set cmds
+	notifySet
+	// h: hash cmds
+	notifyHash
+	// z: sorted set cmds
+	notifyZset
+	// x: expired events, key is expired by ttl
+	notifyExpired
+	// e: evicted events
+	notifyEvicted
+	// t: stream cmds
+	notifyStream
+	// m: key miss events
+	notifyKeyMiss
+	// d: module key type events
+	notifyModule
+	// n: new key events
+	notifyNew
+
+	// A: alias for "g$lshzxetd"
+	notifyAll = notifyGeneric | notifyString | notifyList | notifySet | notifyHash |
+		notifyZset | notifyExpired | notifyEvicted | notifyStream | notifyModule
+)
+
+// notifyClassChars class chars in canonical order of CONFIG GET like redis
+var notifyClassChars = []struct {
+	class notifyClass
+	char  byte
+}{
+	{notifyGeneric, 'g'}, {notifyString, '$'}, {notifyList, 'l'}, {notifySet, 's'},
+	{notifyHash, 'h'}, {notifyZset, 'z'}, {notifyExpired, 'x'}, {notifyEvicted, 'e'},
+	{notifyStream, 't'}, {notifyModule, 'd'}, {notifyKeyspace, 'K'}, {notifyKeyevent, 'E'},
+	{notifyKeyMiss, 'm'}, {notifyNew, 'n'},
+}
+
+// parseNotifyKeyspaceEvents parse notify-keyspace-events classes, e.g. KEA, Ex
+func parseNotifyKeyspaceEvents(events string) (flags notifyClass, err error) {
+	for i := 0; i < len(events); i++ {
+		if events[i] == 'A' {
+			flags |= notifyAll
+			continue
+		}
+		found := false
+		for _, cc := range notifyClassChars {
+			if cc.char == events[i] {
+				flags |= cc.class
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid event class character %q", events[i])
+		}
+	}
+	return
+}
+
+// notifyKeyspaceEventsString the canonical notify-keyspace-events classes of the flags
+func notifyKeyspaceEventsString(flags notifyClass) string {
+	var sb strings.Builder
+	if flags&notifyAll == notifyAll {
+		sb.WriteByte('A')
+	}
+	for _, cc := range notifyClassChars {
+		if cc.class&notifyAll != 0 && flags&notifyAll == notifyAll {
+			continue
+		}
+		if flags&cc.class != 0 {
+			sb.WriteByte(cc.char)
+		}
+	}
+	return sb.String()
+}
+
+// applyNotifyConfig apply notify-keyspace-events by CONFIG SET,
+// the option is rewritten in canonical form
+func applyNotifyConfig(s *RespCmdService) error {
+	flags, err := parseNotifyKeyspaceEvents(s.opts.NotifyKeyspaceEvents)
+	if err != nil {
+		return err
+	}
+	s.opts.NotifyKeyspaceEvents = notifyKeyspaceEventsString(flags)
+	s.notifyFlags.Store(int64(flags))
+	return nil
+}
+
+// expiredNotifyStorager storager which calls the handler with the db index and key
+// when the key is expired by ttl; if the storager doesn't implement it,
+// the srv finds the expired keys by the volatile keys index
+type expiredNotifyStorager interface {
+	SetExpiredHandler(handler func(dbIdx int, key []byte))
+}
+
+// notifyEnabled check the event class whether is notified,
+// no event is notified without K and E
+func (s *RespCmdService) notifyEnabled(class notifyClass) bool {
+	flags := notifyClass(s.notifyFlags.Load())
+	return flags&class != 0 && flags&(notifyKeyspace|notifyKeyevent) != 0
+}
+
+// notifyKeyspaceEvent publish the keyspace and keyevent notifications of the keys in db
+// if the event class is enabled by notify-keyspace-events
+func (s *RespCmdService) notifyKeyspaceEvent(class notifyClass, event string, dbIdx int, keys ...[]byte) {
+	flags := notifyClass(s.notifyFlags.Load())
+	if flags&class == 0 {
+		return
+	}
+
+	db := strconv.Itoa(dbIdx)
+	for _, key := range keys {
+		if flags&notifyKeyspace != 0 {
+			s.pubSub.publish("__keyspace@"+db+"__:"+string(key), []byte(event))
+		}
+		if flags&notifyKeyevent != 0 {
+			s.pubSub.publish("__keyevent@"+db+"__:"+event, key)
+		}
+	}
+}
+
+// notifyKeyEvent notify the event of the keys in the conn selected db
+func notifyKeyEvent(c driver.IRespConn, class notifyClass, event string, keys ...[]byte) {
+	if conn, ok := c.(*RespCmdConn); ok {
+		conn.srv.notifyKeyspaceEvent(class, event, conn.dbIdx, keys...)
+	}
+}
+
+// delKeys del the keys and notify the del events of the deleted ones,
+// the keys are deleted one by one to know which ones are deleted if the del events are enabled
+func delKeys(ctx context.Context, c driver.IRespConn, delFn func(ctx context.Context, keys ...[]byte) (int64, error), keys [][]byte) (n int64, err error) {
+	conn, ok := c.(*RespCmdConn)
+	if !ok || !conn.srv.notifyEnabled(notifyGeneric) {
+		return delFn(ctx, keys...)
+	}
+
+	for _, key := range keys {
+		cn, err := delFn(ctx, key)
+		if err != nil {
+			return n, err
+		}
+		if cn > 0 {
+			n += cn
+			notifyKeyEvent(c, notifyGeneric, "del", key)
+		}
+	}
+	return
+}
@@ -0,0 +1,550 @@
+package standalone
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/kitex/pkg/klog"
+	"github.com/tidwall/match"
+	"github.com/tidwall/redcon"
+	"github.com/weedge/pkg/driver"
+)
+
+// pub/sub more detail reference:
+// https://redis.io/docs/interactive/pubsub/
+
+// buffered msgs of subscriber conn, the slow subscriber is disconnected when it's full
+const subscriberBufferSize = 1024
+
+// pub/sub kinds which are subscribed by the kind cmds
+const (
+	pubSubChannel = iota
+	pubSubPattern
+	// shard channel is bound to the slot which the channel is hashed to
+	pubSubShard
+	pubSubKindNum
+)
+
+// pubSubCmds subscribe, unsubscribe cmds and message type of pub/sub kinds
+var pubSubCmds = [pubSubKindNum]struct {
+	subscribe   string
+	unsubscribe string
+	message     string
+}{
+	pubSubChannel: {"subscribe", "unsubscribe", "message"},
+	pubSubPattern: {"psubscribe", "punsubscribe", "pmessage"},
+	pubSubShard:   {"ssubscribe", "sunsubscribe", "smessage"},
+}
+
+// pubSubKind pub/sub kind of the (un)subscribe cmd
+func pubSubKind(cmd string) (kind int, subscribe bool, ok bool) {
+	for kind, cmds := range pubSubCmds {
+		switch cmd {
+		case cmds.subscribe:
+			return kind, true, true
+		case cmds.unsubscribe:
+			return kind, false, true
+		}
+	}
+	return
+}
+
+// pubSub srv pub/sub registry of the subscribed channels and patterns,
+// redcon.PubSub doesn't expose them for PUBSUB and INFO
+type pubSub struct {
+	mu sync.RWMutex
+	// kind -> channel or pattern -> subscribers
+	subs [pubSubKindNum]map[string]map[*subscriber]struct{}
+	// shard channel -> slot
+	shardSlots map[string]uint64
+	// all subscriber conns
+	subscribers map[*subscriber]struct{}
+}
+
+func newPubSub() *pubSub {
+	ps := &pubSub{shardSlots: map[string]uint64{}, subscribers: map[*subscriber]struct{}{}}
+	for kind := range ps.subs {
+		ps.subs[kind] = map[string]map[*subscriber]struct{}{}
+	}
+	return ps
+}
+
+// subscriber detached conn in pub/sub state which receives the published msgs,
+// only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT are allowed;
+// the conn keeps in pub/sub state after unsubscribed all
+type subscriber struct {
+	srv   *RespCmdService
+	dconn redcon.DetachedConn
+	// client id of the detached conn
+	id int64
+	// RESP3 conn gets msgs as push type
+	protoVer int
+	// replies and msgs are written by write loop only
+	msgs chan []byte
+	done chan struct{}
+	once sync.Once
+
+	// kind -> subscribed channels or patterns, guarded by pubSub mu
+	names [pubSubKindNum]map[string]struct{}
+}
+
+func newSubscriber(srv *RespCmdService, dconn redcon.DetachedConn, id int64, protoVer int) *subscriber {
+	sub := &subscriber{
+		srv:      srv,
+		dconn:    dconn,
+		id:       id,
+		protoVer: protoVer,
+		msgs:     make(chan []byte, subscriberBufferSize),
+		done:     make(chan struct{}),
+	}
+	for kind := range sub.names {
+		sub.names[kind] = map[string]struct{}{}
+	}
+	return sub
+}
+
+// count the subscribed num of the kind, guarded by pubSub mu;
+// channels and patterns are counted together, shard channels are counted alone like redis
+func (sub *subscriber) count(kind int) int {
+	if kind == pubSubShard {
+		return len(sub.names[pubSubShard])
+	}
+	return len(sub.names[pubSubChannel]) + len(sub.names[pubSubPattern])
+}
+
+// send send the reply or msg to subscriber without blocking,
+// return false if the subscriber is too slow to buffer it
+func (sub *subscriber) send(b []byte) bool {
+	select {
+	case sub.msgs <- b:
+	case <-sub.done:
+	default:
+		return false
+	}
+	return true
+}
+
+// writeLoop write the buffered replies and msgs to subscriber conn,
+// nil msg is quit, the conn is closed after the buffered ones are written
+func (sub *subscriber) writeLoop() {
+	for {
+		select {
+		case <-sub.done:
+			return
+		case b := <-sub.msgs:
+			quit := b == nil
+			sub.dconn.WriteRaw(b)
+			// batch the buffered msgs
+			for n := len(sub.msgs); n > 0 && !quit; n-- {
+				b = <-sub.msgs
+				quit = b == nil
+				sub.dconn.WriteRaw(b)
+			}
+			if err := sub.dconn.Flush(); err != nil || quit {
+				sub.close()
+				return
+			}
+		}
+	}
+}
+
+// readLoop read cmds from subscriber conn until it's closed
+func (sub *subscriber) readLoop() {
+	for {
+		cmd, err := sub.dconn.ReadCommand()
+		if err != nil {
+			sub.close()
+			return
+		}
+		if len(cmd.Args) == 0 {
+			continue
+		}
+		b, quit := sub.handle(cmd)
+		if !sub.send(b) {
+			sub.close()
+			return
+		}
+		if quit && !sub.send(nil) {
+			sub.close()
+			return
+		}
+		if quit {
+			return
+		}
+	}
+}
+
+// handle handle the cmd in pub/sub state, return the reply and whether quit
+func (sub *subscriber) handle(cmd redcon.Command) (b []byte, quit bool) {
+	op := strings.ToLower(string(cmd.Args[0]))
+	if respCmdConn, ok := sub.dconn.Context().(*RespCmdConn); ok {
+		if err := respCmdConn.checkPerm(op, cmd.Args[1:]); err != nil {
+			return redcon.AppendError(nil, err.Error()), false
+		}
+	}
+
+	if kind, subscribe, ok := pubSubKind(op); ok {
+		if subscribe && len(cmd.Args) < 2 {
+			return redcon.AppendError(nil, fmt.Sprintf("ERR wrong number of arguments for '%s' command", op)), false
+		}
+		names := make([]string, 0, len(cmd.Args)-1)
+		for _, arg := range cmd.Args[1:] {
+			names = append(names, string(arg))
+		}
+		if subscribe {
+			var slots []uint64
+			if kind == pubSubShard {
+				var err error
+				respCmdConn, _ := sub.dconn.Context().(*RespCmdConn)
+				if slots, err = channelSlots(respCmdConn, cmd.Args[1:]); err != nil {
+					return redcon.AppendError(nil, err.Error()), false
+				}
+			}
+			return sub.srv.pubSub.subscribe(sub, kind, names, slots), false
+		}
+		return sub.srv.pubSub.unsubscribe(sub, sub.protoVer, kind, names), false
+	}
+
+	switch op {
+	case "ping":
+		if len(cmd.Args) > 2 {
+			return redcon.AppendError(nil, "ERR wrong number of arguments for 'ping' command"), false
+		}
+		msg := []byte{}
+		if len(cmd.Args) == 2 {
+			msg = cmd.Args[1]
+		}
+		// RESP3 conn gets the normal PING reply
+		if sub.protoVer >= RespProtoVer3 {
+			if len(cmd.Args) == 1 {
+				return redcon.AppendString(nil, string(PONG)), false
+			}
+			return redcon.AppendBulk(nil, msg), false
+		}
+		return AppendReply(nil, sub.protoVer, []any{[]byte("pong"), msg}), false
+	case "quit":
+		return redcon.AppendOK(nil), true
+	}
+	return redcon.AppendError(nil, fmt.Sprintf("ERR Can't execute '%s': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT are allowed in this context", op)), false
+}
+
+// channelSlots hash the shard channels to slots by the storager like keys,
+// a proxy routes the shard channels to the slot owner instance
+func channelSlots(respCmdConn *RespCmdConn, channels [][]byte) ([]uint64, error) {
+	if respCmdConn == nil {
+		return nil, ErrNoInitRespConn
+	}
+	dbSlots, ok := respCmdConn.Db().(driver.IDBSlots)
+	if !ok {
+		return nil, ErrSlotsNotSupported
+	}
+	return dbSlots.DBSlot().SlotsHashKey(context.Background(), channels...)
+}
+
+// close unsubscribe all, close the subscriber conn and release the resp cmd conn,
+// the net conn is closed to stop the read loop, the conn writer is owned by write loop
+func (sub *subscriber) close() {
+	sub.once.Do(func() {
+		close(sub.done)
+		sub.srv.pubSub.remove(sub)
+		if netConn := sub.dconn.NetConn(); netConn != nil {
+			netConn.Close()
+		}
+		if respCmdConn, ok := sub.dconn.Context().(*RespCmdConn); ok {
+			sub.srv.releaseRespCmdConn(respCmdConn)
+		}
+	})
+}
+
+// subscribe subscribe the channels or patterns, slots are the shard channels hashed to,
+// return the subscribe replies with the subscribed num after each one;
+// the closed subscriber isn't registered
+func (ps *pubSub) subscribe(sub *subscriber, kind int, names []string, slots []uint64) (b []byte) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	select {
+	case <-sub.done:
+		return
+	default:
+	}
+	ps.subscribers[sub] = struct{}{}
+	for i, name := range names {
+		if _, ok := sub.names[kind][name]; !ok {
+			sub.names[kind][name] = struct{}{}
+			if ps.subs[kind][name] == nil {
+				ps.subs[kind][name] = map[*subscriber]struct{}{}
+				if kind == pubSubShard {
+					ps.shardSlots[name] = slots[i]
+				}
+			}
+			ps.subs[kind][name][sub] = struct{}{}
+		}
+		b = AppendReply(b, sub.protoVer, RespPush{[]byte(pubSubCmds[kind].subscribe), []byte(name), redcon.SimpleInt(sub.count(kind))})
+	}
+	return
+}
+
+// unsubscribe unsubscribe the channels or patterns, all of the kind if names is empty,
+// return the unsubscribe replies with the subscribed num after each one;
+// nil subscriber is the conn not in pub/sub state
+func (ps *pubSub) unsubscribe(sub *subscriber, protoVer int, kind int, names []string) (b []byte) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if len(names) == 0 && sub != nil {
+		for name := range sub.names[kind] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	if len(names) == 0 {
+		count := redcon.SimpleInt(0)
+		if sub != nil {
+			count = redcon.SimpleInt(sub.count(kind))
+		}
+		return AppendReply(b, protoVer, RespPush{[]byte(pubSubCmds[kind].unsubscribe), nil, count})
+	}
+
+	for _, name := range names {
+		count := redcon.SimpleInt(0)
+		if sub != nil {
+			ps.del(sub, kind, name)
+			count = redcon.SimpleInt(sub.count(kind))
+		}
+		b = AppendReply(b, protoVer, RespPush{[]byte(pubSubCmds[kind].unsubscribe), []byte(name), count})
+	}
+	return
+}
+
+// del delete the subscribed channel or pattern of subscriber, guarded by mu
+func (ps *pubSub) del(sub *subscriber, kind int, name string) {
+	delete(sub.names[kind], name)
+	if subs, ok := ps.subs[kind][name]; ok {
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(ps.subs[kind], name)
+			if kind == pubSubShard {
+				delete(ps.shardSlots, name)
+			}
+		}
+	}
+}
+
+// remove unsubscribe all of the subscriber
+func (ps *pubSub) remove(sub *subscriber) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for kind, names := range sub.names {
+		for name := range names {
+			ps.del(sub, kind, name)
+		}
+	}
+	delete(ps.subscribers, sub)
+}
+
+// publish publish the msg to the channel subscribers and the matched pattern subscribers,
+// return the num of subscribers which received the msg;
+// the slow subscribers are disconnected
+func (ps *pubSub) publish(channel string, msg []byte) (n int64) {
+	slowSubs := []*subscriber{}
+	ps.mu.RLock()
+	n += ps.deliver(ps.subs[pubSubChannel][channel], &slowSubs,
+		RespPush{[]byte(pubSubCmds[pubSubChannel].message), []byte(channel), msg})
+	for pattern, subs := range ps.subs[pubSubPattern] {
+		if match.Match(channel, pattern) {
+			n += ps.deliver(subs, &slowSubs,
+				RespPush{[]byte(pubSubCmds[pubSubPattern].message), []byte(pattern), []byte(channel), msg})
+		}
+	}
+	ps.mu.RUnlock()
+
+	closeSlowSubscribers(slowSubs)
+	return
+}
+
+// spublish publish the msg to the shard channel subscribers,
+// return the num of subscribers which received the msg
+func (ps *pubSub) spublish(channel string, msg []byte) (n int64) {
+	slowSubs := []*subscriber{}
+	ps.mu.RLock()
+	n = ps.deliver(ps.subs[pubSubShard][channel], &slowSubs,
+		RespPush{[]byte(pubSubCmds[pubSubShard].message), []byte(channel), msg})
+	ps.mu.RUnlock()
+
+	closeSlowSubscribers(slowSubs)
+	return
+}
+
+// subscriberByID the subscriber of the client id, nil if it's not in pub/sub state
+func (ps *pubSub) subscriberByID(id int64) *subscriber {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	for sub := range ps.subscribers {
+		if sub.id == id {
+			return sub
+		}
+	}
+	return nil
+}
+
+// closeSlowSubscribers disconnect the slow subscribers out of mu, don't block the publisher
+func closeSlowSubscribers(slowSubs []*subscriber) {
+	for _, sub := range slowSubs {
+		klog.Warnf("subscriber conn %s is too slow, disconnect", sub.dconn.RemoteAddr())
+		sub.close()
+	}
+}
+
+// unsubscribeSlots unsubscribe the shard channels of the slots which are moved out,
+// the subscribers get the sunsubscribe msgs to resubscribe from the new slot owner;
+// return the num of unsubscribed shard channels
+func (ps *pubSub) unsubscribeSlots(slots ...uint64) (n int) {
+	slotSet := make(map[uint64]struct{}, len(slots))
+	for _, slot := range slots {
+		slotSet[slot] = struct{}{}
+	}
+
+	slowSubs := []*subscriber{}
+	ps.mu.Lock()
+	channels := []string{}
+	for channel, slot := range ps.shardSlots {
+		if _, ok := slotSet[slot]; ok {
+			channels = append(channels, channel)
+		}
+	}
+	sort.Strings(channels)
+	for _, channel := range channels {
+		subs := ps.subs[pubSubShard][channel]
+		for sub := range subs {
+			ps.del(sub, pubSubShard, channel)
+			b := AppendReply(nil, sub.protoVer,
+				RespPush{[]byte(pubSubCmds[pubSubShard].unsubscribe), []byte(channel), redcon.SimpleInt(sub.count(pubSubShard))})
+			if !sub.send(b) {
+				slowSubs = append(slowSubs, sub)
+			}
+		}
+	}
+	ps.mu.Unlock()
+
+	closeSlowSubscribers(slowSubs)
+	return len(channels)
+}
+
+// deliver send the msg to the subscribers, guarded by mu,
+// the msg is encoded once for each resp protocol version;
+// return the num of subscribers which received the msg
+func (ps *pubSub) deliver(subs map[*subscriber]struct{}, slowSubs *[]*subscriber, msg RespPush) (n int64) {
+	encoded := [RespProtoVer3 + 1][]byte{}
+	for sub := range subs {
+		b := encoded[sub.protoVer]
+		if b == nil {
+			b = AppendReply(nil, sub.protoVer, msg)
+			encoded[sub.protoVer] = b
+		}
+		if !sub.send(b) {
+			*slowSubs = append(*slowSubs, sub)
+			continue
+		}
+		n++
+	}
+	return
+}
+
+// names the active channels or patterns of the kind which match the pattern, sorted
+func (ps *pubSub) names(kind int, pattern string) []string {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	names := make([]string, 0, len(ps.subs[kind]))
+	for name := range ps.subs[kind] {
+		if len(pattern) == 0 || match.Match(name, pattern) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// numSub the subscribers num of the channel or pattern of the kind
+func (ps *pubSub) numSub(kind int, name string) int {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return len(ps.subs[kind][name])
+}
+
+// numbers the active channels, patterns, shard channels num and the subscriber conns num
+func (ps *pubSub) numbers() (channels, patterns, shardChannels, subscribers int) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return len(ps.subs[pubSubChannel]), len(ps.subs[pubSubPattern]), len(ps.subs[pubSubShard]), len(ps.subscribers)
+}
+
+// closeSubscribers close all subscriber conns
+func (s *RespCmdService) closeSubscribers() {
+	s.pubSub.mu.RLock()
+	subs := make([]*subscriber, 0, len(s.pubSub.subscribers))
+	for sub := range s.pubSub.subscribers {
+		subs = append(subs, sub)
+	}
+	s.pubSub.mu.RUnlock()
+
+	for _, sub := range subs {
+		sub.close()
+	}
+}
+
+// pubSubNumbers the active channels, patterns and shard channels num, and the subscriber conns num
+func (s *RespCmdService) pubSubNumbers() (channels, patterns, shardChannels, subscribers int) {
+	return s.pubSub.numbers()
+}
+
+// SubscribeCmd detach the conn to pub/sub state and subscribe the channels or patterns,
+// the subscriber conn reads cmds and writes msgs in background
+func (s *RespCmdService) SubscribeCmd(conn redcon.Conn, cmd redcon.Command) {
+	op := strings.ToLower(string(cmd.Args[0]))
+	if len(cmd.Args) < 2 {
+		conn.WriteError("ERR wrong number of arguments for '" + op + "' command")
+		return
+	}
+
+	// the conn keeps in normal state if the shard channels can't be hashed to slots
+	respCmdConn, _ := conn.Context().(*RespCmdConn)
+	if kind, _, _ := pubSubKind(op); kind == pubSubShard {
+		if _, err := channelSlots(respCmdConn, cmd.Args[1:]); err != nil {
+			conn.WriteError(err.Error())
+			return
+		}
+	}
+
+	id, protoVer := int64(0), RespProtoVer2
+	if respCmdConn != nil {
+		id, protoVer = respCmdConn.id, respCmdConn.RespProtoVer()
+		respCmdConn.detached = true
+	}
+	sub := newSubscriber(s, conn.Detach(), id, protoVer)
+	// idle close isn't for the subscriber conn
+	if netConn := sub.dconn.NetConn(); netConn != nil {
+		netConn.SetReadDeadline(time.Time{})
+	}
+	b, _ := sub.handle(cmd)
+	sub.send(b)
+
+	go sub.writeLoop()
+	go sub.readLoop()
+}
+
+// UnsubscribeCmd the conn not in pub/sub state replies the unsubscribe with 0 subscribed
+func (s *RespCmdService) UnsubscribeCmd(conn redcon.Conn, cmd redcon.Command) {
+	protoVer := RespProtoVer2
+	if respCmdConn, ok := conn.Context().(*RespCmdConn); ok {
+		protoVer = respCmdConn.RespProtoVer()
+	}
+	kind, _, _ := pubSubKind(strings.ToLower(string(cmd.Args[0])))
+	names := make([]string, 0, len(cmd.Args)-1)
+	for _, arg := range cmd.Args[1:] {
+		names = append(names, string(arg))
+	}
+	conn.WriteRaw(s.pubSub.unsubscribe(nil, protoVer, kind, names))
+}
@@ -0,0 +1,138 @@
+package standalone
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/weedge/pkg/driver"
+)
+
+// cursor dict size, the oldest cursor is overwritten by the new one in the same slot
+const scanCursorDictSize = 16384
+
+// keyScanner type store which scans its keys after the cursor key in order,
+// SCAN is supported only if the type stores implement it
+type keyScanner interface {
+	Scan(ctx context.Context, cursor []byte, count int, inclusive bool, match string) ([][]byte, error)
+}
+
+// hashScanner hash store which scans the fields after the cursor field of the key in order
+type hashScanner interface {
+	HScan(ctx context.Context, key []byte, cursor []byte, count int, inclusive bool, match string) ([]driver.FVPair, error)
+}
+
+// setScanner set store which scans the members after the cursor member of the key in order
+type setScanner interface {
+	SScan(ctx context.Context, key []byte, cursor []byte, count int, inclusive bool, match string) ([][]byte, error)
+}
+
+// zsetScanner zset store which scans the members after the cursor member of the key in order
+type zsetScanner interface {
+	ZScan(ctx context.Context, key []byte, cursor []byte, count int, inclusive bool, match string) ([]driver.ScorePair, error)
+}
+
+// scanCursor the scan position which the cursor number refers to,
+// the scan is resumed after the last scanned key, so it survives the concurrent writes
+type scanCursor struct {
+	id uint64
+	// scan cmd with its args which the cursor is for, e.g. SCAN type, HSCAN key
+	owner string
+	// type store index of SCAN
+	stage int
+	last  []byte
+}
+
+// scanCursors srv cursor dict of the opaque cursor numbers like kvrocks,
+// cursor 0 is the start and end of the scan
+type scanCursors struct {
+	mu     sync.Mutex
+	lastID uint64
+	dict   [scanCursorDictSize]scanCursor
+}
+
+// new the cursor number of the scan position
+func (sc *scanCursors) new(owner string, stage int, last []byte) uint64 {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.lastID++
+	if sc.lastID == 0 {
+		sc.lastID++
+	}
+	sc.dict[sc.lastID%scanCursorDictSize] = scanCursor{
+		id:    sc.lastID,
+		owner: owner,
+		stage: stage,
+		last:  append([]byte{}, last...),
+	}
+	return sc.lastID
+}
+
+// get the scan position of the cursor number, ok is false if it's unknown or overwritten
+func (sc *scanCursors) get(id uint64, owner string) (stage int, last []byte, ok bool) {
+	if id == 0 {
+		return 0, nil, true
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	cursor := sc.dict[id%scanCursorDictSize]
+	if cursor.id != id || cursor.owner != owner {
+		return 0, nil, false
+	}
+	return cursor.stage, cursor.last, true
+}
+
+// scanArgs the cursor number and the [MATCH pattern] [COUNT count] [TYPE type] args
+type scanArgs struct {
+	cursor  uint64
+	match   string
+	count   int
+	keyType string
+}
+
+// parseScanArgs parse cursor [MATCH pattern] [COUNT count] args,
+// [TYPE type] is allowed for SCAN only
+func parseScanArgs(args [][]byte, typeAllowed bool) (sa scanArgs, err error) {
+	if len(args) == 0 {
+		return sa, ErrCmdParams
+	}
+	if sa.cursor, err = strconv.ParseUint(string(args[0]), 10, 64); err != nil {
+		return sa, ErrScanCursor
+	}
+
+	sa.count = 10
+	args = args[1:]
+	if len(args)%2 != 0 {
+		return sa, ErrSyntax
+	}
+	for i := 0; i < len(args); i += 2 {
+		switch strings.ToLower(string(args[i])) {
+		case "match":
+			sa.match = string(args[i+1])
+			if sa.match == "*" {
+				sa.match = ""
+			}
+		case "count":
+			if sa.count, err = strconv.Atoi(string(args[i+1])); err != nil {
+				return sa, ErrValue
+			}
+			if sa.count < 1 {
+				return sa, ErrSyntax
+			}
+		case "type":
+			if !typeAllowed {
+				return sa, ErrSyntax
+			}
+			sa.keyType = strings.ToLower(string(args[i+1]))
+		default:
+			return sa, ErrSyntax
+		}
+	}
+	return
+}
+
+// scanReply the cursor number and the scanned items reply
+func scanReply(cursor uint64, items []any) []any {
+	return []any{[]byte(strconv.FormatUint(cursor, 10)), items}
+}
@@ -0,0 +1,191 @@
+package standalone
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/kitex/pkg/klog"
+)
+
+// in-flight cmds are checked at the interval while draining
+const shutdownDrainInterval = 10 * time.Millisecond
+
+// shutdownFlags SHUTDOWN [NOSAVE|SAVE] [NOW] [FORCE]
+type shutdownFlags struct {
+	// don't flush the storage
+	noSave bool
+	// don't wait for the in-flight cmds, they're canceled
+	now bool
+	// shutdown even if the storage flush failed
+	force bool
+}
+
+// flushStorager storager which flushes the written data to disk on shutdown,
+// the storage is flushed only if the storager implements it
+type flushStorager interface {
+	Flush(ctx context.Context) error
+}
+
+// cmdEnter count the cmd in-flight, return false if srv is shutting down,
+// the in-flight cmd must exit by cmdExit
+func (s *RespCmdService) cmdEnter() bool {
+	s.inflightCmds.Add(1)
+	if s.shuttingDown.Load() {
+		s.inflightCmds.Add(-1)
+		return false
+	}
+	return true
+}
+
+func (s *RespCmdService) cmdExit() {
+	s.inflightCmds.Add(-1)
+}
+
+// blockConn the blocking cmd ctx which is canceled to wake up the blocked conn on shutdown,
+// the returned unblock func must be called after the blocking cmd returned
+func (s *RespCmdService) blockConn(ctx context.Context, c *RespCmdConn) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	s.blockedMu.Lock()
+	s.blockedConns[c] = cancel
+	s.blockedMu.Unlock()
+	if s.shuttingDown.Load() {
+		cancel()
+	}
+
+	return ctx, func() {
+		s.blockedMu.Lock()
+		delete(s.blockedConns, c)
+		s.blockedMu.Unlock()
+		cancel()
+	}
+}
+
+// unblockConns wake up all blocked conns, return the num of them
+func (s *RespCmdService) unblockConns() int {
+	s.blockedMu.Lock()
+	defer s.blockedMu.Unlock()
+	for _, cancel := range s.blockedConns {
+		cancel()
+	}
+	return len(s.blockedConns)
+}
+
+// drainCmds wait until the in-flight cmds except the running ones finished or ctx done,
+// return the num of the in-flight cmds which are not finished
+func (s *RespCmdService) drainCmds(ctx context.Context, running int64) int64 {
+	ticker := time.NewTicker(shutdownDrainInterval)
+	defer ticker.Stop()
+	for {
+		n := s.inflightCmds.Load() - running
+		if n <= 0 {
+			return 0
+		}
+		select {
+		case <-ctx.Done():
+			return n
+		case <-ticker.C:
+		}
+	}
+}
+
+// runningCmds the running cmds of the conns, e.g. id=1 cmd=blpop
+func (s *RespCmdService) runningCmds() (cmds []string) {
+	for _, c := range s.RespCmdConns() {
+		if !c.running.Load() {
+			continue
+		}
+		cmd, _ := c.lastCmd.Load().(string)
+		cmds = append(cmds, fmt.Sprintf("id=%d cmd=%s", c.id, cmd))
+	}
+	return
+}
+
+// Shutdown shutdown the srv gracefully: stop accepting conns and cmds,
+// wake up the blocked conns, wait for the in-flight cmds until ctx done
+// (shutdownTimeout if ctx has no deadline), cancel the unfinished ones and wait them within shutdownTimeout,
+// flush the storage within shutdownTimeout, then close the srv;
+// it's signal friendly, call it on SIGTERM/SIGINT and exit after Done
+func (s *RespCmdService) Shutdown(ctx context.Context) error {
+	return s.shutdown(ctx, shutdownFlags{}, 0)
+}
+
+// shutdown shutdown the srv with the flags,
+// running is the num of in-flight cmds which run the shutdown, e.g. SHUTDOWN cmd;
+// the srv keeps running if the storage flush failed without FORCE,
+// unless the paused, blocked or in-flight cmds have been woken up or canceled
+func (s *RespCmdService) shutdown(ctx context.Context, flags shutdownFlags, running int64) (err error) {
+	s.shutdownMu.Lock()
+	defer s.shutdownMu.Unlock()
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	klog.Infof("resp cmd service shutdown, nosave=%v now=%v force=%v", flags.noSave, flags.now, flags.force)
+	s.shuttingDown.Store(true)
+	// the srv can't go back to serving once the conns are woken up
+	tornDown := s.pause.Unpause()
+	if s.unblockConns() > 0 {
+		tornDown = true
+	}
+
+	timeout := time.Duration(s.opts.ShutdownTimeout) * time.Second
+	if !flags.now {
+		drainCtx := ctx
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			drainCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		s.drainCmds(drainCtx, running)
+	}
+	// the storage isn't flushed and closed under the running cmds,
+	// the cmds which ignore the cancel are left running after shutdownTimeout
+	if n := s.inflightCmds.Load() - running; n > 0 {
+		klog.Warnf("resp cmd service shutdown with %d in-flight cmds canceled", n)
+		tornDown = true
+		s.cmdCancel()
+		cancelCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if n = s.drainCmds(cancelCtx, running); n > 0 {
+			klog.Errorf("resp cmd service shutdown with %d canceled cmds still running: %s",
+				n, strings.Join(s.runningCmds(), ", "))
+		}
+	}
+
+	if store, ok := s.store.(flushStorager); ok && !flags.noSave {
+		// the flush isn't bounded by ctx which may be used up by draining
+		flushCtx := context.Background()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			flushCtx, cancel = context.WithTimeout(flushCtx, timeout)
+			defer cancel()
+		}
+		if err = store.Flush(flushCtx); err != nil {
+			klog.Errorf("shutdown flush storage err: %s", err.Error())
+			switch {
+			case flags.force:
+				err = nil
+			case !tornDown:
+				s.shuttingDown.Store(false)
+				return ErrShutdownFailed
+			default:
+				// the srv is closed with the flush err
+				err = ErrShutdownFailed
+			}
+		}
+	}
+
+	if closeErr := s.Close(); closeErr != nil {
+		return closeErr
+	}
+	return
+}
+
+// Done closed after the srv is closed by Close/Shutdown or SHUTDOWN cmd
+func (s *RespCmdService) Done() <-chan struct{} {
+	return s.done
+}
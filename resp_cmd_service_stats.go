@@ -0,0 +1,304 @@
+package standalone
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// instantaneous ops/sec sample interval and samples num
+	statsSampleInterval = 100 * time.Millisecond
+	statsSampleNum      = 16
+)
+
+// cmdLatencyBounds upper bounds of cmd latency histogram buckets
+var cmdLatencyBounds = [...]time.Duration{
+	10 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// cmdStats cmd counters shown by INFO commandstats
+type cmdStats struct {
+	calls atomic.Int64
+	// total run time in microseconds
+	usec atomic.Int64
+	// rejected before run, e.g. NOPERM, arity err
+	rejectedCalls atomic.Int64
+	// failed in run
+	failedCalls atomic.Int64
+	// calls num of each latency bucket, not cumulative,
+	// the last one is for the calls slower than all bounds
+	latency [len(cmdLatencyBounds) + 1]atomic.Int64
+}
+
+func (st *cmdStats) Reset() {
+	st.calls.Store(0)
+	st.usec.Store(0)
+	st.rejectedCalls.Store(0)
+	st.failedCalls.Store(0)
+	for i := range st.latency {
+		st.latency[i].Store(0)
+	}
+}
+
+// slotsMgrtStats slots migrate cmd counters
+type slotsMgrtStats struct {
+	// migrate calls ok
+	calls atomic.Int64
+	// migrated keys
+	keys atomic.Int64
+}
+
+// srvStats srv counters shown by INFO stats, reset by CONFIG RESETSTAT,
+// counters are updated lock-free in the cmd dispatch path
+type srvStats struct {
+	// total connections accepted
+	connsReceived atomic.Int64
+	// total connections rejected, e.g. over max clients
+	connsRejected atomic.Int64
+	// connections rejected by max clients, max clients per ip, cidr list
+	connsRejectedMaxClients atomic.Int64
+	connsRejectedPerIP      atomic.Int64
+	connsRejectedCIDR       atomic.Int64
+	// total cmds processed
+	cmdsProcessed atomic.Int64
+	// total bytes read from / written to conns
+	netInputBytes  atomic.Int64
+	netOutputBytes atomic.Int64
+	// key lookups of read cmds
+	keyspaceHits   atomic.Int64
+	keyspaceMisses atomic.Int64
+	// conns which are running blocking cmds
+	blockedClients atomic.Int64
+
+	// cmd name -> cmd stats, the map is read only after created
+	cmds map[string]*cmdStats
+	// slotsmgrt* cmd name -> slots migrate stats, read only after created
+	slotsMgrts map[string]*slotsMgrtStats
+	// error reply prefix -> *atomic.Int64 count, e.g. ERR, WRONGTYPE
+	errors sync.Map
+
+	// cmds processed samples for instantaneous ops/sec
+	opsSamples    [statsSampleNum]atomic.Int64
+	opsSampleIdx  int
+	lastSampleCmd int64
+}
+
+func newSrvStats(cmds []string) *srvStats {
+	st := &srvStats{
+		cmds:       make(map[string]*cmdStats, len(cmds)),
+		slotsMgrts: map[string]*slotsMgrtStats{},
+	}
+	for _, cmd := range cmds {
+		st.cmds[cmd] = &cmdStats{}
+		if strings.HasPrefix(cmd, "slotsmgrt") {
+			st.slotsMgrts[cmd] = &slotsMgrtStats{}
+		}
+	}
+	return st
+}
+
+// Reset reset all counters
+func (st *srvStats) Reset() {
+	st.connsReceived.Store(0)
+	st.connsRejected.Store(0)
+	st.connsRejectedMaxClients.Store(0)
+	st.connsRejectedPerIP.Store(0)
+	st.connsRejectedCIDR.Store(0)
+	st.cmdsProcessed.Store(0)
+	st.netInputBytes.Store(0)
+	st.netOutputBytes.Store(0)
+	st.keyspaceHits.Store(0)
+	st.keyspaceMisses.Store(0)
+	for _, cs := range st.cmds {
+		cs.Reset()
+	}
+	for _, ms := range st.slotsMgrts {
+		ms.calls.Store(0)
+		ms.keys.Store(0)
+	}
+	st.errors.Range(func(key, _ any) bool {
+		st.errors.Delete(key)
+		return true
+	})
+}
+
+// cmdCall record the cmd run time and result
+func (st *srvStats) cmdCall(cmd string, duration time.Duration, err error) {
+	cs, ok := st.cmds[cmd]
+	if !ok {
+		return
+	}
+	cs.calls.Add(1)
+	cs.usec.Add(duration.Microseconds())
+	i := sort.Search(len(cmdLatencyBounds), func(i int) bool { return duration <= cmdLatencyBounds[i] })
+	cs.latency[i].Add(1)
+	if err != nil && err != ErrNoops {
+		cs.failedCalls.Add(1)
+	}
+}
+
+// cmdRejected record the cmd rejected before run
+func (st *srvStats) cmdRejected(cmd string) {
+	if cs, ok := st.cmds[cmd]; ok {
+		cs.rejectedCalls.Add(1)
+	}
+}
+
+// errorReply record the error reply by the prefix,
+// the prefix is the first word if it's upper case, otherwise ERR
+func (st *srvStats) errorReply(msg string) {
+	prefix, _, _ := strings.Cut(msg, " ")
+	if len(prefix) == 0 || strings.ToUpper(prefix) != prefix {
+		prefix = "ERR"
+	}
+	cnt, ok := st.errors.Load(prefix)
+	if !ok {
+		cnt, _ = st.errors.LoadOrStore(prefix, &atomic.Int64{})
+	}
+	cnt.(*atomic.Int64).Add(1)
+}
+
+// errorCounts error reply prefix -> count
+func (st *srvStats) errorCounts() map[string]int64 {
+	counts := map[string]int64{}
+	st.errors.Range(func(key, cnt any) bool {
+		counts[key.(string)] = cnt.(*atomic.Int64).Load()
+		return true
+	})
+	return counts
+}
+
+// slotsMgrt record the slots migrate cmd ok with the migrated keys num
+func (st *srvStats) slotsMgrt(cmd string, keys int64) {
+	if ms, ok := st.slotsMgrts[cmd]; ok {
+		ms.calls.Add(1)
+		ms.keys.Add(keys)
+	}
+}
+
+// read cmds which reply the key values, keyspace hits/misses are counted by the reply;
+// the other read cmds (e.g. EXISTS, TTL) reply integers which aren't lookups
+var keyspaceLookupCmds = map[string]struct{}{
+	"get":              {},
+	"mget":             {},
+	"hget":             {},
+	"hgetall":          {},
+	"hkeys":            {},
+	"hvals":            {},
+	"lindex":           {},
+	"lrange":           {},
+	"smembers":         {},
+	"zscore":           {},
+	"zrange":           {},
+	"zrevrange":        {},
+	"zrangebyscore":    {},
+	"zrevrangebyscore": {},
+	"zrangebylex":      {},
+}
+
+// keyspaceLookup record keyspace hits/misses of lookup cmd,
+// nil or empty reply is miss, MGET is counted per key
+func (st *srvStats) keyspaceLookup(cmd string, res interface{}) {
+	if _, ok := keyspaceLookupCmds[cmd]; !ok {
+		return
+	}
+	if cmd == "mget" {
+		switch items := res.(type) {
+		case [][]byte:
+			for _, item := range items {
+				st.lookup(item != nil)
+			}
+		case []interface{}:
+			for _, item := range items {
+				st.lookup(lookupHit(item))
+			}
+		}
+		return
+	}
+	st.lookup(lookupHit(res))
+}
+
+// lookup record a keyspace hit or miss
+func (st *srvStats) lookup(hit bool) {
+	if hit {
+		st.keyspaceHits.Add(1)
+		return
+	}
+	st.keyspaceMisses.Add(1)
+}
+
+// lookupHit check the lookup reply whether is hit, nil or empty reply is miss
+func lookupHit(res interface{}) bool {
+	if res == nil {
+		return false
+	}
+	rv := reflect.ValueOf(res)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return rv.Len() > 0
+	}
+	return true
+}
+
+// sample sample cmds processed for instantaneous ops/sec,
+// run by the srv sample loop only
+func (st *srvStats) sample() {
+	cmds := st.cmdsProcessed.Load()
+	ops := (cmds - st.lastSampleCmd) * int64(time.Second/statsSampleInterval)
+	if ops < 0 {
+		// counters are reset
+		ops = 0
+	}
+	st.opsSamples[st.opsSampleIdx].Store(ops)
+	st.opsSampleIdx = (st.opsSampleIdx + 1) % statsSampleNum
+	st.lastSampleCmd = cmds
+}
+
+// sampleLoop sample the counters until done is closed
+func (st *srvStats) sampleLoop(done <-chan struct{}) {
+	ticker := time.NewTicker(statsSampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			st.sample()
+		}
+	}
+}
+
+// InstantaneousOps average ops/sec of the samples
+func (st *srvStats) InstantaneousOps() int64 {
+	sum := int64(0)
+	for i := range st.opsSamples {
+		sum += st.opsSamples[i].Load()
+	}
+	return sum / statsSampleNum
+}
+
+// cmdStatsNames cmd names which have been called, sorted
+func (st *srvStats) cmdStatsNames() []string {
+	names := []string{}
+	for cmd, cs := range st.cmds {
+		if cs.calls.Load() > 0 || cs.rejectedCalls.Load() > 0 {
+			names = append(names, cmd)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
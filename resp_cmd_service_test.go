@@ -1,9 +1,21 @@
 package standalone
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/tidwall/redcon"
 	"github.com/weedge/pkg/driver"
+	"github.com/weedge/xdis-standalone/config"
 )
 
 func TestRespCmdSrv_Implements(t *testing.T) {
@@ -19,3 +31,647 @@ func TestRespCmdSrvConn_Implements(t *testing.T) {
 		t.Fatalf("does not implement driver.IRespConn")
 	}
 }
+
+func TestRespCmdSrvConfig(t *testing.T) {
+	srv := New(config.DefaultRespCmdServiceOptions())
+
+	if err := srv.ConfigSet("requirepass", "pwd", "timeout", "10"); err != nil {
+		t.Fatalf("config set err: %v", err)
+	}
+	if u := srv.acl.GetUser(DefaultAclUserName); u.NoPass || !u.CheckPassword("pwd") {
+		t.Fatalf("default user password isn't changed")
+	}
+	if err := srv.ConfigSet("timeout", "20", "addr", "127.0.0.1:6380"); err == nil || srv.opts.ConnKeepaliveInterval != 10 {
+		t.Fatalf("config set immutable addr err: %v", err)
+	}
+	pairs := srv.ConfigGet("conn*", "requirepass")
+	if strings.Join(pairs, " ") != "connKeepaliveInterval 10 requirepass pwd" {
+		t.Fatalf("config get: %v", pairs)
+	}
+
+	file := filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(file, []byte("# auth password\nauthPassword = \"\"\n\n[other]\naddr = \"x\"\n"), 0600)
+	srv.SetConfigFile(file)
+	if err := srv.ConfigRewrite(); err != nil {
+		t.Fatalf("config rewrite err: %v", err)
+	}
+	data, _ := os.ReadFile(file)
+	if !strings.HasPrefix(string(data), "# auth password\nauthPassword = \"pwd\"\n\naddr = \"127.0.0.1:6666\"\nconnKeepaliveInterval = 10\n") ||
+		!strings.HasSuffix(string(data), "\n\n[other]\naddr = \"x\"\n") {
+		t.Fatalf("config rewrite file: %s", data)
+	}
+}
+
+func TestMonitorLine(t *testing.T) {
+	now := time.Unix(1339518083, 107412000)
+	line := monitorLine(now, 0, "127.0.0.1:60866", [][]byte{[]byte("set"), []byte("k"), []byte("a\"b\n\x01")})
+	if string(line) != "+1339518083.107412 [0 127.0.0.1:60866] \"set\" \"k\" \"a\\\"b\\n\\x01\"\r\n" {
+		t.Fatalf("monitor line: %q", line)
+	}
+
+	line = monitorLine(now, 1, "lua", [][]byte{[]byte("AUTH"), []byte("pwd")})
+	if string(line) != "+1339518083.107412 [1 lua] \"AUTH\" \"(redacted)\"\r\n" {
+		t.Fatalf("monitor redacted line: %q", line)
+	}
+}
+
+func TestRedactArgs(t *testing.T) {
+	args := func(s ...string) [][]byte {
+		b := make([][]byte, 0, len(s))
+		for _, v := range s {
+			b = append(b, []byte(v))
+		}
+		return b
+	}
+	for _, tc := range []struct {
+		args, redacted []string
+	}{
+		{[]string{"get", "k"}, []string{"get", "k"}},
+		{[]string{"ACL", "SETUSER", "alice", "on", ">secret"}, []string{"ACL", "SETUSER", "alice", "(redacted)", "(redacted)"}},
+		{[]string{"config", "set", "maxclients", "10", "requirepass", "pwd"}, []string{"config", "set", "maxclients", "10", "requirepass", "(redacted)"}},
+		{[]string{"migrate", "h", "6379", "", "0", "100", "AUTH2", "u", "pwd", "KEYS", "k"},
+			[]string{"migrate", "h", "6379", "", "0", "100", "AUTH2", "(redacted)", "(redacted)", "KEYS", "k"}},
+	} {
+		redacted := redactArgs(args(tc.args...))
+		if fmt.Sprintf("%s", redacted) != fmt.Sprintf("%s", tc.redacted) {
+			t.Fatalf("redact %v: %s", tc.args, redacted)
+		}
+	}
+}
+
+// testRedConn redcon conn with the remote addr only
+type testRedConn struct{ redcon.Conn }
+
+func (c testRedConn) RemoteAddr() string { return "127.0.0.1:6000" }
+
+func (c testRedConn) NetConn() net.Conn { return nil }
+
+// testRejectConn redcon conn which records the err replies
+type testRejectConn struct {
+	testRedConn
+	netConn net.Conn
+	errs    []string
+}
+
+func (c *testRejectConn) NetConn() net.Conn { return c.netConn }
+
+func (c *testRejectConn) WriteError(msg string) { c.errs = append(c.errs, msg) }
+
+func TestRejectConn(t *testing.T) {
+	srv := New(config.DefaultRespCmdServiceOptions())
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	conn := &testRejectConn{netConn: c1}
+	srv.rejectConn(conn, ErrMaxClients)
+	if len(conn.errs) != 1 || conn.errs[0] != ErrMaxClients.Error() {
+		t.Fatalf("rejected conn errs: %v", conn.errs)
+	}
+	tlsConn := &testRejectConn{netConn: tls.Server(c2, &tls.Config{})}
+	srv.rejectConn(tlsConn, ErrConnAddrNotAllowed)
+	if len(tlsConn.errs) != 0 || srv.stats.connsRejected.Load() != 2 {
+		t.Fatalf("rejected tls conn errs: %v", tlsConn.errs)
+	}
+}
+
+// testDetachedConn detached conn of the resp cmd conn
+type testDetachedConn struct {
+	redcon.DetachedConn
+	ctx     interface{}
+	netConn net.Conn
+}
+
+func (c *testDetachedConn) Context() interface{} { return c.ctx }
+
+func (c *testDetachedConn) NetConn() net.Conn { return c.netConn }
+
+func (c *testDetachedConn) RemoteAddr() string { return "127.0.0.1:6000" }
+
+func TestDetachedConnRelease(t *testing.T) {
+	srv := New(config.DefaultRespCmdServiceOptions())
+	srv.admission.admit("127.0.0.1")
+	conn := &RespCmdConn{RespConnBase: &driver.RespConnBase{}, srv: srv, Conn: testRedConn{}, detached: true}
+	conn.admitted, conn.admitIP = true, "127.0.0.1"
+	srv.AddRespCmdConn(conn)
+
+	// redcon calls closed on detach, the detached conn is still open
+	srv.OnClosed(&testDetachedConn{ctx: conn}, nil)
+	if srv.RespCmdConnectNum() != 1 || srv.admission.conns != 1 {
+		t.Fatalf("detached conn is released, conns %d, admitted %d", srv.RespCmdConnectNum(), srv.admission.conns)
+	}
+
+	c1, c2 := net.Pipe()
+	defer c2.Close()
+	sub := newSubscriber(srv, &testDetachedConn{ctx: conn, netConn: c1}, conn.id, RespProtoVer2)
+	sub.close()
+	if srv.RespCmdConnectNum() != 0 || srv.admission.conns != 0 || srv.admission.ipConns["127.0.0.1"] != 0 {
+		t.Fatalf("closed subscriber conn isn't released, conns %d, admitted %d", srv.RespCmdConnectNum(), srv.admission.conns)
+	}
+}
+
+func TestMonitorFeed(t *testing.T) {
+	opts := config.DefaultRespCmdServiceOptions()
+	opts.AuthPassword = "pwd"
+	srv := New(opts)
+	m := &monitorConn{srv: srv, msgs: make(chan monitorMsg, 8), done: make(chan struct{})}
+	srv.monitors[m] = struct{}{}
+	srv.monitorNum.Store(1)
+	conn := &RespCmdConn{RespConnBase: &driver.RespConnBase{}, srv: srv, Conn: testRedConn{}}
+	ctx := context.WithValue(context.Background(), RespCmdCtxKey, conn)
+
+	conn.DoCmd(ctx, "ping", nil)
+	conn.DoCmd(ctx, "nocmd", nil)
+	conn.DoCmd(ctx, "auth", nil)
+	if len(m.msgs) != 0 {
+		t.Fatalf("rejected cmds are fed to monitor: %d", len(m.msgs))
+	}
+	if _, err := conn.DoCmd(ctx, "auth", [][]byte{[]byte("pwd")}); err != nil {
+		t.Fatalf("auth err: %v", err)
+	}
+	if msg := <-m.msgs; !strings.HasSuffix(string(msg.line), " [0 127.0.0.1:6000] \"auth\" \"(redacted)\"\r\n") {
+		t.Fatalf("monitor line: %q", msg.line)
+	}
+}
+
+func TestSrvStats(t *testing.T) {
+	st := newSrvStats([]string{"get", "mget"})
+
+	st.cmdCall("get", 3*time.Microsecond, nil)
+	st.cmdCall("get", time.Microsecond, ErrCmdParams)
+	st.cmdRejected("get")
+	st.cmdCall("unknown", time.Microsecond, nil)
+	if names := st.cmdStatsNames(); len(names) != 1 || names[0] != "get" {
+		t.Fatalf("cmd stats names %v", names)
+	}
+	cs := st.cmds["get"]
+	if cs.calls.Load() != 2 || cs.usec.Load() != 4 || cs.failedCalls.Load() != 1 || cs.rejectedCalls.Load() != 1 {
+		t.Fatalf("get stats calls %d usec %d failed %d rejected %d",
+			cs.calls.Load(), cs.usec.Load(), cs.failedCalls.Load(), cs.rejectedCalls.Load())
+	}
+
+	st.keyspaceLookup("get", nil)
+	st.keyspaceLookup("get", []byte("v"))
+	st.keyspaceLookup("mget", []interface{}{[]byte("v"), nil, nil})
+	st.keyspaceLookup("mget", [][]byte{nil, nil})
+	st.keyspaceLookup("exists", int64(0))
+	st.keyspaceLookup("ttl", int64(-2))
+	if st.keyspaceHits.Load() != 2 || st.keyspaceMisses.Load() != 5 {
+		t.Fatalf("keyspace hits %d misses %d", st.keyspaceHits.Load(), st.keyspaceMisses.Load())
+	}
+
+	st.cmdsProcessed.Add(statsSampleNum)
+	st.sample()
+	if ops := st.InstantaneousOps(); ops != int64(time.Second/statsSampleInterval) {
+		t.Fatalf("instantaneous ops %d", ops)
+	}
+
+	st.Reset()
+	if cs.calls.Load() != 0 || st.keyspaceHits.Load() != 0 || len(st.cmdStatsNames()) != 0 {
+		t.Fatalf("stats are not reset")
+	}
+}
+
+func TestSrvMetrics(t *testing.T) {
+	srv := New(config.DefaultRespCmdServiceOptions())
+	srv.stats.cmdCall("get", 20*time.Microsecond, nil)
+	srv.stats.errorReply("WRONGTYPE Operation against a key holding the wrong kind of value")
+	srv.stats.errorReply("unknown command")
+	srv.stats.slotsMgrt("slotsmgrtone", 1)
+
+	mfs, err := srv.newMetricsRegistry().Gather()
+	if err != nil {
+		t.Fatalf("gather metrics err: %v", err)
+	}
+	metrics := map[string]string{}
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			labels := []string{}
+			for _, l := range m.GetLabel() {
+				labels = append(labels, l.GetValue())
+			}
+			metrics[mf.GetName()+"{"+strings.Join(labels, ",")+"}"] = m.String()
+		}
+	}
+	for _, name := range []string{
+		"xdis_commands_total{get}",
+		"xdis_command_duration_seconds{get}",
+		"xdis_errors_total{WRONGTYPE}",
+		"xdis_errors_total{ERR}",
+		"xdis_slots_migrated_keys_total{slotsmgrtone}",
+		"xdis_pubsub_channels{}",
+		"xdis_connected_clients{}",
+	} {
+		if _, ok := metrics[name]; !ok {
+			t.Fatalf("metric %s is not gathered", name)
+		}
+	}
+	if h := metrics["xdis_command_duration_seconds{get}"]; !strings.Contains(h, "sample_count:1") {
+		t.Fatalf("get duration histogram %s", h)
+	}
+}
+
+func TestCmdTracer(t *testing.T) {
+	opts := config.DefaultRespCmdServiceOptions()
+	opts.TraceExporter = TraceExporterFile
+	opts.TraceEndpoint = filepath.Join(t.TempDir(), "trace.log")
+	srv := New(opts)
+	if err := srv.startTracer(context.Background()); err != nil {
+		t.Fatalf("start tracer err: %v", err)
+	}
+
+	_, span := srv.tracer.Start(context.Background(), "get")
+	endCmdSpan(span, ErrCmdParams)
+	if err := srv.ConfigSet("traceSampleRatio", "2"); err == nil {
+		t.Fatalf("config set trace sample ratio out of range")
+	}
+	if err := srv.ConfigSet("traceSampleRatio", "0"); err != nil {
+		t.Fatalf("config set trace sample ratio err: %v", err)
+	}
+	_, span = srv.tracer.Start(context.Background(), "set")
+	if span.IsRecording() {
+		t.Fatalf("span is sampled with ratio 0")
+	}
+	endCmdSpan(span, nil)
+	if err := srv.closeTracer(); err != nil {
+		t.Fatalf("close tracer err: %v", err)
+	}
+
+	data, err := os.ReadFile(opts.TraceEndpoint)
+	if err != nil {
+		t.Fatalf("read trace file err: %v", err)
+	}
+	if !strings.Contains(string(data), `"Name":"get"`) || !strings.Contains(string(data), ErrCmdParams.Error()) {
+		t.Fatalf("trace file %s", data)
+	}
+	if strings.Contains(string(data), `"Name":"set"`) {
+		t.Fatalf("not sampled span is exported")
+	}
+}
+
+func TestParseUnixSocketPerm(t *testing.T) {
+	perm, err := parseUnixSocketPerm("770")
+	if err != nil || perm != 0770 {
+		t.Fatalf("parse unix socket perm %v err: %v", perm, err)
+	}
+	for _, p := range []string{"789", "1777", "rwx"} {
+		if _, err = parseUnixSocketPerm(p); err == nil {
+			t.Fatalf("invalid unix socket perm %s is parsed", p)
+		}
+	}
+}
+
+func TestConnAdmission(t *testing.T) {
+	a := newConnAdmission()
+	if err := a.SetConfig(3, 2, "10.0.0.0/8,127.0.0.1", "10.1.0.0/16"); err != nil {
+		t.Fatalf("set admission config err: %v", err)
+	}
+	if err := a.SetConfig(0, 0, "10.0.0.0/33", ""); err == nil {
+		t.Fatalf("invalid cidr is parsed")
+	}
+
+	for ip, want := range map[string]error{
+		"127.0.0.2":   ErrConnAddrNotAllowed,
+		"10.1.2.3":    ErrConnAddrNotAllowed,
+		"192.168.0.1": ErrConnAddrNotAllowed,
+	} {
+		if err := a.admit(ip); err != want {
+			t.Fatalf("admit %s err: %v, want %v", ip, err, want)
+		}
+	}
+	for _, ip := range []string{"10.0.0.1", "10.0.0.1"} {
+		if err := a.admit(ip); err != nil {
+			t.Fatalf("admit %s err: %v", ip, err)
+		}
+	}
+	if err := a.admit("10.0.0.1"); err != ErrMaxClientsPerIP {
+		t.Fatalf("admit over max clients per ip err: %v", err)
+	}
+	// unix socket conn is only limited by max clients
+	if err := a.admit(""); err != nil {
+		t.Fatalf("admit unix socket conn err: %v", err)
+	}
+	if err := a.admit("127.0.0.1"); err != ErrMaxClients {
+		t.Fatalf("admit over max clients err: %v", err)
+	}
+	a.release("10.0.0.1")
+	if err := a.admit("127.0.0.1"); err != nil {
+		t.Fatalf("admit after release err: %v", err)
+	}
+}
+
+func TestSrvShutdown(t *testing.T) {
+	srv := New(config.DefaultRespCmdServiceOptions())
+	conn := &RespCmdConn{RespConnBase: &driver.RespConnBase{}, srv: srv}
+	if !srv.cmdEnter() {
+		t.Fatalf("cmd is refused before shutdown")
+	}
+	blockCtx, unblock := srv.blockConn(context.Background(), conn)
+	defer unblock()
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- srv.Shutdown(context.Background()) }()
+	select {
+	case <-blockCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("blocked conn isn't woken up on shutdown")
+	}
+	if srv.cmdEnter() {
+		t.Fatalf("cmd is accepted when shutting down")
+	}
+	select {
+	case <-srv.Done():
+		t.Fatalf("srv is closed with in-flight cmd")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	srv.cmdExit()
+	if err := <-shutdownErr; err != nil {
+		t.Fatalf("shutdown err: %v", err)
+	}
+	select {
+	case <-srv.Done():
+	default:
+		t.Fatalf("srv isn't done after shutdown")
+	}
+}
+
+// testFlushStorager storager which records the flush ctx err
+type testFlushStorager struct {
+	*testStorager
+	flushErr error
+	ctxErr   error
+}
+
+func (s *testFlushStorager) Flush(ctx context.Context) error {
+	s.ctxErr = ctx.Err()
+	return s.flushErr
+}
+
+func TestSrvShutdownFlush(t *testing.T) {
+	srv := New(config.DefaultRespCmdServiceOptions())
+	store := &testFlushStorager{testStorager: &testStorager{}, flushErr: errors.New("ERR flush")}
+	srv.SetStorager(store)
+	if err := srv.Shutdown(context.Background()); err != ErrShutdownFailed || srv.shuttingDown.Load() {
+		t.Fatalf("shutdown with flush err: %v", err)
+	}
+
+	// the in-flight cmd is canceled after draining timeout, the flush isn't bounded by the used up ctx
+	store.flushErr = nil
+	srv.cmdEnter()
+	go func() {
+		<-srv.cmdCtx.Done()
+		srv.cmdExit()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil || store.ctxErr != nil {
+		t.Fatalf("shutdown err: %v, flush ctx err: %v", err, store.ctxErr)
+	}
+	select {
+	case <-srv.Done():
+	default:
+		t.Fatalf("srv isn't done after shutdown")
+	}
+
+	// the canceled cmd which ignores the cancel doesn't hang the shutdown
+	opts := config.DefaultRespCmdServiceOptions()
+	opts.ShutdownTimeout = 0
+	srv = New(opts)
+	srv.SetStorager(&testFlushStorager{testStorager: &testStorager{}})
+	c1, c2 := net.Pipe()
+	defer c2.Close()
+	conn := &RespCmdConn{RespConnBase: &driver.RespConnBase{}, srv: srv, id: 1, Conn: &testRejectConn{netConn: c1}}
+	conn.touch("get")
+	conn.running.Store(true)
+	srv.AddRespCmdConn(conn)
+	srv.cmdEnter()
+	if cmds := srv.runningCmds(); len(cmds) != 1 || cmds[0] != "id=1 cmd=get" {
+		t.Fatalf("running cmds %v", cmds)
+	}
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown with running cmd err: %v", err)
+	}
+	select {
+	case <-srv.Done():
+	default:
+		t.Fatalf("srv isn't done after shutdown with running cmd")
+	}
+}
+
+func TestPubSub(t *testing.T) {
+	srv := New(config.DefaultRespCmdServiceOptions())
+	ps := srv.pubSub
+	sub := newSubscriber(srv, nil, 0, RespProtoVer2)
+	b := ps.subscribe(sub, pubSubChannel, []string{"news", "news"}, nil)
+	if string(b) != "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n" {
+		t.Fatalf("subscribe reply: %q", b)
+	}
+	ps.subscribe(sub, pubSubPattern, []string{"n*"}, nil)
+	if channels, patterns, _, subscribers := ps.numbers(); channels != 1 || patterns != 1 || subscribers != 1 {
+		t.Fatalf("pubsub numbers %d %d %d", channels, patterns, subscribers)
+	}
+
+	if n := ps.publish("news", []byte("hi")); n != 2 || len(sub.msgs) != 2 {
+		t.Fatalf("publish received %d, msgs %d", n, len(sub.msgs))
+	}
+	if b = <-sub.msgs; string(b) != "*3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$2\r\nhi\r\n" {
+		t.Fatalf("message: %q", b)
+	}
+	if b = <-sub.msgs; string(b) != "*4\r\n$8\r\npmessage\r\n$2\r\nn*\r\n$4\r\nnews\r\n$2\r\nhi\r\n" {
+		t.Fatalf("pmessage: %q", b)
+	}
+	if names := ps.names(pubSubChannel, "x*"); len(names) != 0 {
+		t.Fatalf("channels %v", names)
+	}
+
+	b = ps.unsubscribe(sub, RespProtoVer2, pubSubChannel, nil)
+	if string(b) != "*3\r\n$11\r\nunsubscribe\r\n$4\r\nnews\r\n:1\r\n" || ps.numSub(pubSubChannel, "news") != 0 {
+		t.Fatalf("unsubscribe all reply: %q", b)
+	}
+	b = ps.unsubscribe(nil, RespProtoVer2, pubSubPattern, nil)
+	if string(b) != "*3\r\n$12\r\npunsubscribe\r\n$-1\r\n:0\r\n" {
+		t.Fatalf("punsubscribe not subscribed reply: %q", b)
+	}
+
+	b = ps.subscribe(sub, pubSubShard, []string{"news", "{u1}.msg"}, []uint64{1, 2})
+	if !strings.HasSuffix(string(b), "*3\r\n$10\r\nssubscribe\r\n$8\r\n{u1}.msg\r\n:2\r\n") {
+		t.Fatalf("ssubscribe reply: %q", b)
+	}
+	if n := ps.spublish("news", []byte("hi")); n != 1 || string(<-sub.msgs) != "*3\r\n$8\r\nsmessage\r\n$4\r\nnews\r\n$2\r\nhi\r\n" {
+		t.Fatalf("spublish received %d", n)
+	}
+	if n := ps.unsubscribeSlots(2, 3); n != 1 || ps.numSub(pubSubShard, "{u1}.msg") != 0 {
+		t.Fatalf("unsubscribe slots %d", n)
+	}
+	if b = <-sub.msgs; string(b) != "*3\r\n$12\r\nsunsubscribe\r\n$8\r\n{u1}.msg\r\n:1\r\n" {
+		t.Fatalf("slot moved sunsubscribe: %q", b)
+	}
+}
+
+func TestNotifyKeyspaceEvents(t *testing.T) {
+	srv := New(config.DefaultRespCmdServiceOptions())
+	if err := srv.ConfigSet("notify-keyspace-events", "Kb"); err == nil {
+		t.Fatalf("invalid notify keyspace events class is set")
+	}
+	if err := srv.ConfigSet("notify-keyspace-events", "EKxg$lshzetd"); err != nil {
+		t.Fatalf("config set notify keyspace events err: %v", err)
+	}
+	if pairs := srv.ConfigGet("notify-keyspace-events"); pairs[1] != "AKE" {
+		t.Fatalf("notify keyspace events %v", pairs)
+	}
+	srv.ConfigSet("notify-keyspace-events", "Elx")
+
+	sub := newSubscriber(srv, nil, 0, RespProtoVer2)
+	srv.pubSub.subscribe(sub, pubSubPattern, []string{"__key*__:*"}, nil)
+	srv.notifyKeyspaceEvent(notifyList, "lpush", 1, []byte("mylist"))
+	srv.notifyKeyspaceEvent(notifyString, "set", 1, []byte("mykey"))
+	srv.notifyKeyspaceEvent(notifyExpired, "expired", 0, []byte("mykey"))
+	if len(sub.msgs) != 2 {
+		t.Fatalf("notified msgs %d", len(sub.msgs))
+	}
+	if b := <-sub.msgs; !strings.Contains(string(b), "__keyevent@1__:lpush\r\n$6\r\nmylist") {
+		t.Fatalf("lpush keyevent: %q", b)
+	}
+	if b := <-sub.msgs; !strings.Contains(string(b), "__keyevent@0__:expired\r\n$5\r\nmykey") {
+		t.Fatalf("expired keyevent: %q", b)
+	}
+}
+
+func TestClientTracking(t *testing.T) {
+	srv := New(config.DefaultRespCmdServiceOptions())
+	sub := newSubscriber(srv, nil, 2, RespProtoVer2)
+	srv.pubSub.subscribe(sub, pubSubChannel, []string{trackingInvalidateChannel}, nil)
+
+	c := &RespCmdConn{srv: srv, id: 1}
+	srv.tracking.enable(c, &clientTrackingOpts{redirect: 2})
+	srv.trackKeys(c, [][]byte{[]byte("k1"), []byte("k2")})
+	if keys, clients := srv.tracking.numbers(); keys != 2 || clients != 1 {
+		t.Fatalf("tracking numbers %d %d", keys, clients)
+	}
+	srv.invalidateKeys(nil, []byte("k1"), []byte("k3"))
+	if b := <-sub.msgs; string(b) != "*3\r\n$7\r\nmessage\r\n$20\r\n__redis__:invalidate\r\n*1\r\n$2\r\nk1\r\n" {
+		t.Fatalf("invalidate msg: %q", b)
+	}
+	srv.invalidateKeys(nil, []byte("k1"))
+	if len(sub.msgs) != 0 {
+		t.Fatalf("invalidated key is still tracked")
+	}
+	srv.invalidateAll()
+	if b := <-sub.msgs; string(b) != "*3\r\n$7\r\nmessage\r\n$20\r\n__redis__:invalidate\r\n$-1\r\n" {
+		t.Fatalf("flush invalidate msg: %q", b)
+	}
+
+	bc := &RespCmdConn{srv: srv, id: 3}
+	srv.tracking.enable(bc, &clientTrackingOpts{redirect: 2, bcast: true, prefixes: []string{"user:"}, noloop: true})
+	srv.invalidateKeys(bc, []byte("user:1"))
+	srv.invalidateKeys(nil, []byte("item:1"))
+	if len(sub.msgs) != 0 {
+		t.Fatalf("bcast invalidate msgs %d", len(sub.msgs))
+	}
+	srv.invalidateKeys(c, []byte("user:1"))
+	if b := <-sub.msgs; !strings.HasSuffix(string(b), "*1\r\n$6\r\nuser:1\r\n") {
+		t.Fatalf("bcast invalidate msg: %q", b)
+	}
+
+	srv.tracking.enable(c, &clientTrackingOpts{redirect: 2, optin: true})
+	srv.trackKeys(c, [][]byte{[]byte("k1")})
+	c.trackingCaching = clientCachingYes
+	srv.trackKeys(c, [][]byte{[]byte("k2")})
+	if keys, _ := srv.tracking.numbers(); keys != 1 {
+		t.Fatalf("optin tracked keys %d", keys)
+	}
+	srv.tracking.disable(c)
+	if _, clients := srv.tracking.numbers(); clients != 1 || c.tracking.Load() != nil {
+		t.Fatalf("tracking clients %d after disabled", clients)
+	}
+}
+
+func TestConnPusherOrder(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	wr := redcon.NewWriter(c1)
+	p := newConnPusher(c1)
+	read := func(n int) string {
+		b := make([]byte, n)
+		c2.SetReadDeadline(time.Now().Add(time.Second))
+		if _, err := io.ReadFull(c2, b); err != nil {
+			t.Fatalf("read err: %v", err)
+		}
+		return string(b)
+	}
+	push := func(key string) []byte {
+		return AppendReply(nil, RespProtoVer3, RespPush{[]byte("invalidate"), [][]byte{[]byte(key)}})
+	}
+
+	// the push sent while the reply is buffered follows the reply
+	p.begin(wr)
+	wr.WriteBulkString("v")
+	p.send(push("k1"))
+	go p.end(wr)
+	if b, want := read(7+len(push("k1"))), "$1\r\nv\r\n"+string(push("k1")); b != want {
+		t.Fatalf("reply and push: %q, want %q", b, want)
+	}
+
+	// the push sent when the conn is idle is written asynchronously
+	p.send(push("k2"))
+	if b := read(len(push("k2"))); b != string(push("k2")) {
+		t.Fatalf("idle push: %q", b)
+	}
+
+	p.close()
+	p.send(push("k3"))
+	p.begin(wr)
+	if len(wr.Buffer()) != 0 {
+		t.Fatalf("closed pusher pushes msgs")
+	}
+}
+
+func TestExpiredEvents(t *testing.T) {
+	ctx := context.Background()
+	store := &testStorager{dbs: []*testDB{newTestDB()}}
+	srv := New(config.DefaultRespCmdServiceOptions())
+	srv.SetStorager(store)
+	c := srv.InitRespConn(ctx, 0).(*RespCmdConn)
+	ctx = context.WithValue(ctx, RespCmdCtxKey, c)
+	srv.ConfigSet("notify-keyspace-events", "Ex")
+	sub := newSubscriber(srv, nil, 0, RespProtoVer2)
+	srv.pubSub.subscribe(sub, pubSubPattern, []string{"__key*__:*"}, nil)
+
+	str := store.dbs[0].str
+	for _, key := range []string{"k1", "k2", "k3"} {
+		str.Set(ctx, []byte(key), []byte("v"))
+		if _, err := c.DoCmd(ctx, "pexpire", [][]byte{[]byte(key), []byte("100")}); err != nil {
+			t.Fatalf("pexpire err: %v", err)
+		}
+	}
+	if at := srv.volatileKeys.expireAt(0, []byte("k1")); at == 0 {
+		t.Fatalf("volatile key isn't indexed")
+	}
+	if _, err := c.DoCmd(ctx, "persist", [][]byte{[]byte("k3")}); err != nil || srv.volatileKeys.expireAt(0, []byte("k3")) != 0 {
+		t.Fatalf("persisted key is still indexed, err: %v", err)
+	}
+
+	// the storager expires the keys after the deadline
+	srv.volatileKeys.set(0, []byte("k1"), 1)
+	c.DoCmd(ctx, "ttl", [][]byte{[]byte("k1")})
+	if len(sub.msgs) != 0 {
+		t.Fatalf("key which isn't expired by the storager is notified")
+	}
+	str.Del(ctx, []byte("k1"))
+	c.DoCmd(ctx, "ttl", [][]byte{[]byte("k1")})
+	if b := <-sub.msgs; !strings.Contains(string(b), "__keyevent@0__:expired\r\n$2\r\nk1") {
+		t.Fatalf("lazy expired keyevent: %q", b)
+	}
+
+	srv.volatileKeys.set(0, []byte("k2"), 1)
+	str.Del(ctx, []byte("k2"))
+	srv.activeExpireCycle()
+	if b := <-sub.msgs; !strings.Contains(string(b), "__keyevent@0__:expired\r\n$2\r\nk2") {
+		t.Fatalf("active expired keyevent: %q", b)
+	}
+	if len(sub.msgs) != 0 || len(srv.volatileKeys.keys) != 0 {
+		t.Fatalf("expired keys are notified again or still indexed")
+	}
+}
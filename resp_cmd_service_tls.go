@@ -0,0 +1,213 @@
+package standalone
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/kitex/pkg/klog"
+	"github.com/tidwall/redcon"
+	"github.com/weedge/xdis-standalone/config"
+)
+
+// tls cert files are checked for reload at the interval
+const tlsReloadInterval = 5 * time.Second
+
+// TLSAuthClientsUserCN the client cert CN is the authenticated acl user name
+const TLSAuthClientsUserCN = "CN"
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsCerts tls config with the cert and ca files which are reloaded on change,
+// the handshake gets the current config, the established conns are kept
+type tlsCerts struct {
+	opts *config.RespCmdServiceOptions
+
+	mu     sync.RWMutex
+	config *tls.Config
+	// file -> mod time and size when loaded
+	files map[string]tlsFileStat
+}
+
+type tlsFileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+func newTLSCerts(opts *config.RespCmdServiceOptions) (t *tlsCerts, err error) {
+	t = &tlsCerts{opts: opts}
+	if err = t.load(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// tlsFiles the cert files to load and watch
+func (t *tlsCerts) tlsFiles() []string {
+	files := []string{t.opts.TLSCertFile, t.opts.TLSKeyFile}
+	if len(t.opts.TLSCACertFile) > 0 {
+		files = append(files, t.opts.TLSCACertFile)
+	}
+	return files
+}
+
+// statFiles stat the cert files, stat err file is skipped
+func (t *tlsCerts) statFiles() map[string]tlsFileStat {
+	stats := map[string]tlsFileStat{}
+	for _, file := range t.tlsFiles() {
+		if fi, err := os.Stat(file); err == nil {
+			stats[file] = tlsFileStat{modTime: fi.ModTime(), size: fi.Size()}
+		}
+	}
+	return stats
+}
+
+// load build the tls config from the options and cert files
+func (t *tlsCerts) load() (err error) {
+	files := t.statFiles()
+	cert, err := tls.LoadX509KeyPair(t.opts.TLSCertFile, t.opts.TLSKeyFile)
+	if err != nil {
+		return
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if len(t.opts.TLSMinVersion) > 0 {
+		ver, ok := tlsVersions[t.opts.TLSMinVersion]
+		if !ok {
+			return fmt.Errorf("unknown tls min version %q", t.opts.TLSMinVersion)
+		}
+		cfg.MinVersion = ver
+	}
+	if len(t.opts.TLSCipherSuites) > 0 {
+		if cfg.CipherSuites, err = parseCipherSuites(t.opts.TLSCipherSuites); err != nil {
+			return
+		}
+	}
+	if len(t.opts.TLSCACertFile) > 0 {
+		pem, err := os.ReadFile(t.opts.TLSCACertFile)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no ca certs in %s", t.opts.TLSCACertFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	if t.opts.TLSAuthClients {
+		if cfg.ClientCAs == nil {
+			return fmt.Errorf("tls auth clients needs ca cert file")
+		}
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	t.mu.Lock()
+	t.config = cfg
+	t.files = files
+	t.mu.Unlock()
+	return
+}
+
+// parseCipherSuites parse comma separated cipher suite names
+func parseCipherSuites(names string) (ids []uint16, err error) {
+	suites := map[string]uint16{}
+	for _, suite := range tls.CipherSuites() {
+		suites[suite.Name] = suite.ID
+	}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+		id, ok := suites[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure tls cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return
+}
+
+// changed check the cert files whether changed after loaded
+func (t *tlsCerts) changed() bool {
+	files := t.statFiles()
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if len(files) != len(t.files) {
+		return true
+	}
+	for file, st := range files {
+		if loaded, ok := t.files[file]; !ok || !loaded.modTime.Equal(st.modTime) || loaded.size != st.size {
+			return true
+		}
+	}
+	return false
+}
+
+// watch reload the cert files on change until done is closed,
+// the current config is kept if reload failed
+func (t *tlsCerts) watch(done <-chan struct{}) {
+	ticker := time.NewTicker(tlsReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if !t.changed() {
+				continue
+			}
+			if err := t.load(); err != nil {
+				klog.Errorf("reload tls certs err: %s", err.Error())
+				continue
+			}
+			klog.Infof("tls certs are reloaded")
+		}
+	}
+}
+
+// TLSConfig the tls listener config which gets the current config on handshake
+func (t *tlsCerts) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			t.mu.RLock()
+			defer t.mu.RUnlock()
+			return t.config, nil
+		},
+	}
+}
+
+// tlsAuthConn authenticate the tls conn as the acl user named by client cert CN,
+// the handshake is done after the first cmd is read;
+// the conn keeps the default user if no enabled acl user has the name
+func (s *RespCmdService) tlsAuthConn(c *RespCmdConn, conn redcon.Conn) {
+	if s.opts.TLSAuthClientsUser != TLSAuthClientsUserCN {
+		return
+	}
+	tlsConn, ok := conn.NetConn().(*tls.Conn)
+	if !ok {
+		return
+	}
+	state := tlsConn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return
+	}
+
+	cn := state.PeerCertificates[0].Subject.CommonName
+	if u := s.acl.GetUser(cn); u != nil && u.Enabled {
+		c.setAuthed(cn)
+	}
+}
@@ -0,0 +1,140 @@
+package standalone
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/weedge/xdis-standalone/config"
+)
+
+// genCert generate cert signed by parent, self-signed ca if parent is nil
+func genCert(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+	return cert, key,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func TestTLSCerts(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey, caPem, _ := genCert(t, "ca", nil, nil)
+	_, _, srvPem, srvKeyPem := genCert(t, "srv", ca, caKey)
+	_, _, cliPem, cliKeyPem := genCert(t, "ops", ca, caKey)
+
+	opts := config.DefaultRespCmdServiceOptions()
+	opts.TLSCertFile = filepath.Join(dir, "srv.crt")
+	opts.TLSKeyFile = filepath.Join(dir, "srv.key")
+	opts.TLSCACertFile = filepath.Join(dir, "ca.crt")
+	opts.TLSAuthClients = true
+	opts.TLSMinVersion = "1.3"
+	os.WriteFile(opts.TLSCertFile, srvPem, 0600)
+	os.WriteFile(opts.TLSKeyFile, srvKeyPem, 0600)
+	os.WriteFile(opts.TLSCACertFile, caPem, 0600)
+
+	certs, err := newTLSCerts(opts)
+	if err != nil {
+		t.Fatalf("load tls certs err: %v", err)
+	}
+	if certs.changed() {
+		t.Fatalf("tls certs changed after loaded")
+	}
+
+	// mutual tls handshake with client cert
+	handshake := func(cliCert []tls.Certificate) (*tls.ConnectionState, error) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		pool := x509.NewCertPool()
+		pool.AddCert(ca)
+		go func() {
+			cliConn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{RootCAs: pool, ServerName: "127.0.0.1", Certificates: cliCert})
+			if err != nil {
+				return
+			}
+			// read the session tickets or alert until the srv closed
+			io.Copy(io.Discard, cliConn)
+			cliConn.Close()
+		}()
+		srvConn, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer srvConn.Close()
+		srv := tls.Server(srvConn, certs.TLSConfig())
+		if err := srv.Handshake(); err != nil {
+			return nil, err
+		}
+		state := srv.ConnectionState()
+		return &state, nil
+	}
+	cliCert, _ := tls.X509KeyPair(cliPem, cliKeyPem)
+	state, err := handshake([]tls.Certificate{cliCert})
+	if err != nil {
+		t.Fatalf("mutual tls handshake err: %v", err)
+	}
+	if state.Version != tls.VersionTLS13 || state.PeerCertificates[0].Subject.CommonName != "ops" {
+		t.Fatalf("tls version %x client cert cn %s", state.Version, state.PeerCertificates[0].Subject.CommonName)
+	}
+	if _, err = handshake(nil); err == nil {
+		t.Fatalf("handshake without client cert is accepted")
+	}
+
+	// rotate server cert
+	_, _, srvPem2, srvKeyPem2 := genCert(t, "srv2", ca, caKey)
+	os.WriteFile(opts.TLSCertFile, srvPem2, 0600)
+	os.WriteFile(opts.TLSKeyFile, srvKeyPem2, 0600)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(opts.TLSCertFile, future, future)
+	if !certs.changed() {
+		t.Fatalf("rotated tls certs are not changed")
+	}
+	if err = certs.load(); err != nil {
+		t.Fatalf("reload tls certs err: %v", err)
+	}
+	leaf, _ := x509.ParseCertificate(certs.config.Certificates[0].Certificate[0])
+	if leaf.Subject.CommonName != "srv2" {
+		t.Fatalf("tls cert is not reloaded, cn %s", leaf.Subject.CommonName)
+	}
+
+	if _, err = parseCipherSuites("TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, TLS_RSA_WITH_RC4_128_SHA"); err == nil {
+		t.Fatalf("insecure cipher suite is parsed")
+	}
+}
@@ -0,0 +1,174 @@
+package standalone
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"sync/atomic"
+
+	"github.com/cloudwego/kitex/pkg/klog"
+	"github.com/tidwall/redcon"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// cmd trace exporters
+const (
+	TraceExporterOtlp   = "otlp"
+	TraceExporterStdout = "stdout"
+	TraceExporterFile   = "file"
+)
+
+const tracerName = "github.com/weedge/xdis-standalone"
+
+// cmdKeyCountKey the keys num of cmd span attribute
+var cmdKeyCountKey = attribute.Key("db.redis.key_count")
+
+// ratioSampler parent based trace id ratio sampler, the ratio can be changed at runtime
+type ratioSampler struct {
+	sampler atomic.Value
+}
+
+func newRatioSampler(ratio float64) *ratioSampler {
+	s := &ratioSampler{}
+	s.SetRatio(ratio)
+	return s
+}
+
+func (s *ratioSampler) SetRatio(ratio float64) {
+	s.sampler.Store(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)))
+}
+
+func (s *ratioSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return s.sampler.Load().(sdktrace.Sampler).ShouldSample(p)
+}
+
+func (s *ratioSampler) Description() string {
+	return s.sampler.Load().(sdktrace.Sampler).Description()
+}
+
+// newTraceExporter new spans exporter by the trace exporter option,
+// otlp endpoint is http url, e.g. http://127.0.0.1:4318; http is insecure
+func (s *RespCmdService) newTraceExporter(ctx context.Context) (exporter sdktrace.SpanExporter, err error) {
+	switch s.opts.TraceExporter {
+	case TraceExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case TraceExporterFile:
+		f, err := os.OpenFile(s.opts.TraceEndpoint, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &fileSpanExporter{SpanExporter: exporter, f: f}, nil
+	case TraceExporterOtlp:
+		opts := []otlptracehttp.Option{}
+		if len(s.opts.TraceEndpoint) > 0 {
+			u, err := url.Parse(s.opts.TraceEndpoint)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, otlptracehttp.WithEndpoint(u.Host))
+			if u.Scheme == "http" {
+				opts = append(opts, otlptracehttp.WithInsecure())
+			}
+			if len(u.Path) > 0 && u.Path != "/" {
+				opts = append(opts, otlptracehttp.WithURLPath(u.Path))
+			}
+		}
+		return otlptracehttp.New(ctx, opts...)
+	}
+	return nil, fmt.Errorf("unknown trace exporter %q", s.opts.TraceExporter)
+}
+
+// fileSpanExporter close the spans file after exporter shutdown
+type fileSpanExporter struct {
+	sdktrace.SpanExporter
+	f *os.File
+}
+
+func (e *fileSpanExporter) Shutdown(ctx context.Context) error {
+	err := e.SpanExporter.Shutdown(ctx)
+	if closeErr := e.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// startTracer start the tracer provider which exports cmd spans,
+// the tracer is noop if trace exporter is disabled
+func (s *RespCmdService) startTracer(ctx context.Context) (err error) {
+	if len(s.opts.TraceExporter) == 0 {
+		return
+	}
+
+	exporter, err := s.newTraceExporter(ctx)
+	if err != nil {
+		return
+	}
+	s.traceSampler = newRatioSampler(s.opts.TraceSampleRatio)
+	s.tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(s.traceSampler),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName("xdis-standalone"),
+			semconv.ServerAddress(s.opts.Addr),
+		)),
+	)
+	s.tracer = s.tracerProvider.Tracer(tracerName)
+	klog.Infof("cmd tracer started with %s exporter, sample ratio %v", s.opts.TraceExporter, s.opts.TraceSampleRatio)
+	return
+}
+
+// closeTracer flush the spans and shutdown the tracer provider,
+// the spans started after shutdown are dropped
+func (s *RespCmdService) closeTracer() (err error) {
+	if s.tracerProvider == nil {
+		return
+	}
+	err = s.tracerProvider.Shutdown(context.Background())
+	s.tracerProvider = nil
+	return
+}
+
+// startCmdSpan start the span of cmd from conn, the span ctx flows to storage by ctx
+func (s *RespCmdService) startCmdSpan(ctx context.Context, conn redcon.Conn, cmdOp string, params [][]byte) (context.Context, trace.Span) {
+	ctx, span := s.tracer.Start(ctx, cmdOp, trace.WithSpanKind(trace.SpanKindServer))
+	if !span.IsRecording() {
+		return ctx, span
+	}
+
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemRedis,
+		semconv.DBOperation(cmdOp),
+		cmdKeyCountKey.Int(len(cmdKeys(cmdOp, params))),
+		semconv.ClientAddress(conn.RemoteAddr()),
+	}
+	if respCmdConn, ok := conn.Context().(*RespCmdConn); ok {
+		attrs = append(attrs, semconv.DBRedisDBIndex(respCmdConn.DbIndex()))
+		if name := respCmdConn.Name(); len(name) > 0 {
+			attrs = append(attrs, attribute.String("db.redis.client_name", name))
+		}
+	}
+	span.SetAttributes(attrs...)
+	return ctx, span
+}
+
+// endCmdSpan end the span of cmd with the err
+func endCmdSpan(span trace.Span, err error) {
+	if err != nil && err != ErrNoops {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
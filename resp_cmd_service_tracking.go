@@ -0,0 +1,454 @@
+package standalone
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/kitex/pkg/klog"
+	"github.com/tidwall/redcon"
+)
+
+// client side caching more detail reference:
+// https://redis.io/docs/manual/client-side-caching/
+
+// buffered push msgs of conn, the slow conn is disconnected when it's full
+const connPusherBufferSize = 1024
+
+// RESP2 redirect conn gets the invalidation msgs from the channel
+const trackingInvalidateChannel = "__redis__:invalidate"
+
+// clientTrackingOpts CLIENT TRACKING ON options, immutable after tracking is on
+type clientTrackingOpts struct {
+	// client id which the invalidation msgs are redirected to, 0 is the conn itself
+	redirect int64
+	// broadcast the invalidation msgs of the keys matched the prefixes
+	bcast    bool
+	prefixes []string
+	// track the keys only after CLIENT CACHING yes
+	optin bool
+	// don't track the keys after CLIENT CACHING no
+	optout bool
+	// don't send the invalidation msgs of the keys modified by the conn itself
+	noloop bool
+}
+
+// clientCaching CLIENT CACHING yes|no for the next cmd
+type clientCaching int
+
+const (
+	clientCachingNone clientCaching = iota
+	clientCachingYes
+	clientCachingNo
+)
+
+// tracking srv tracking table of the keys which may be cached by the clients,
+// keys are tracked without db like redis
+type tracking struct {
+	mu sync.Mutex
+	// key -> client ids which read the key
+	keys map[string]map[int64]struct{}
+	// bcast prefix -> bcast tracking conns
+	prefixes map[string]map[*RespCmdConn]struct{}
+	// client id -> tracking conn
+	clients map[int64]*RespCmdConn
+}
+
+func newTracking() *tracking {
+	return &tracking{
+		keys:     map[string]map[int64]struct{}{},
+		prefixes: map[string]map[*RespCmdConn]struct{}{},
+		clients:  map[int64]*RespCmdConn{},
+	}
+}
+
+// enable turn on the conn tracking with the opts
+func (t *tracking) enable(c *RespCmdConn, opts *clientTrackingOpts) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.disableLocked(c)
+	c.tracking.Store(opts)
+	t.clients[c.id] = c
+	if !opts.bcast {
+		return
+	}
+	prefixes := opts.prefixes
+	if len(prefixes) == 0 {
+		prefixes = []string{""}
+	}
+	for _, prefix := range prefixes {
+		if t.prefixes[prefix] == nil {
+			t.prefixes[prefix] = map[*RespCmdConn]struct{}{}
+		}
+		t.prefixes[prefix][c] = struct{}{}
+	}
+}
+
+// disable turn off the conn tracking,
+// the tracked keys of the conn are removed lazily on invalidation
+func (t *tracking) disable(c *RespCmdConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.disableLocked(c)
+}
+
+func (t *tracking) disableLocked(c *RespCmdConn) {
+	opts := c.tracking.Swap(nil)
+	if opts == nil {
+		return
+	}
+	delete(t.clients, c.id)
+	for prefix, conns := range t.prefixes {
+		delete(conns, c)
+		if len(conns) == 0 {
+			delete(t.prefixes, prefix)
+		}
+	}
+}
+
+// track remember the keys read by the conn
+func (t *tracking) track(c *RespCmdConn, keys [][]byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, key := range keys {
+		ids, ok := t.keys[string(key)]
+		if !ok {
+			ids = map[int64]struct{}{}
+			t.keys[string(key)] = ids
+		}
+		ids[c.id] = struct{}{}
+	}
+}
+
+// invalidate collect the conns which tracked the keys modified by src conn and forget the keys,
+// return the tracking conn -> the invalidated keys
+func (t *tracking) invalidate(src *RespCmdConn, keys [][]byte) map[*RespCmdConn][][]byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.keys) == 0 && len(t.prefixes) == 0 {
+		return nil
+	}
+
+	invalidated := map[*RespCmdConn][][]byte{}
+	add := func(c *RespCmdConn, key []byte) {
+		if opts := c.tracking.Load(); opts == nil || (opts.noloop && c == src) {
+			return
+		}
+		invalidated[c] = append(invalidated[c], key)
+	}
+	for _, key := range keys {
+		if ids, ok := t.keys[string(key)]; ok {
+			delete(t.keys, string(key))
+			for id := range ids {
+				if c, ok := t.clients[id]; ok {
+					add(c, key)
+				}
+			}
+		}
+		for prefix, conns := range t.prefixes {
+			if !strings.HasPrefix(string(key), prefix) {
+				continue
+			}
+			for c := range conns {
+				add(c, key)
+			}
+		}
+	}
+	return invalidated
+}
+
+// flush forget all tracked keys, return all tracking conns
+func (t *tracking) flush() []*RespCmdConn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.keys = map[string]map[int64]struct{}{}
+	conns := make([]*RespCmdConn, 0, len(t.clients))
+	for _, c := range t.clients {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// numbers the tracked keys num and the tracking conns num
+func (t *tracking) numbers() (keys, clients int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.keys), len(t.clients)
+}
+
+// trackKeys remember the keys read by the conn if the conn is tracking them
+func (s *RespCmdService) trackKeys(c *RespCmdConn, keys [][]byte) {
+	opts := c.tracking.Load()
+	if opts == nil || opts.bcast || len(keys) == 0 {
+		return
+	}
+	if (opts.optin && c.trackingCaching != clientCachingYes) || (opts.optout && c.trackingCaching == clientCachingNo) {
+		return
+	}
+	s.tracking.track(c, keys)
+}
+
+// invalidateKeys send the invalidation msgs of the keys modified by src conn
+// to the conns which tracked them, src conn is nil if the keys are expired
+func (s *RespCmdService) invalidateKeys(src *RespCmdConn, keys ...[]byte) {
+	if len(keys) == 0 {
+		return
+	}
+	for c, keys := range s.tracking.invalidate(src, keys) {
+		data := make([]any, 0, len(keys))
+		for _, key := range keys {
+			data = append(data, key)
+		}
+		s.sendInvalidation(c, data)
+	}
+}
+
+// invalidateAll send the invalidation msg with null keys to all tracking conns on db flushed
+func (s *RespCmdService) invalidateAll() {
+	for _, c := range s.tracking.flush() {
+		s.sendInvalidation(c, nil)
+	}
+}
+
+// sendInvalidation send the invalidation msg of the keys (nil for all) to the tracking conn or its redirect conn,
+// RESP3 conn gets invalidate push, RESP2 redirect subscriber conn gets __redis__:invalidate msg;
+// the tracking conn gets tracking-redir-broken push if the redirect conn is gone
+func (s *RespCmdService) sendInvalidation(c *RespCmdConn, keys any) {
+	opts := c.tracking.Load()
+	if opts == nil {
+		return
+	}
+	if opts.redirect == 0 {
+		if c.RespProtoVer() >= RespProtoVer3 {
+			c.push(AppendReply(nil, RespProtoVer3, RespPush{[]byte("invalidate"), keys}))
+		}
+		return
+	}
+
+	if sub := s.pubSub.subscriberByID(opts.redirect); sub != nil {
+		msg := RespPush{[]byte("invalidate"), keys}
+		if sub.protoVer < RespProtoVer3 {
+			msg = RespPush{[]byte(pubSubCmds[pubSubChannel].message), []byte(trackingInvalidateChannel), keys}
+		}
+		if !sub.send(AppendReply(nil, sub.protoVer, msg)) {
+			closeSlowSubscribers([]*subscriber{sub})
+		}
+		return
+	}
+	if target := s.respCmdConnByID(opts.redirect); target != nil {
+		if target.RespProtoVer() >= RespProtoVer3 {
+			target.push(AppendReply(nil, RespProtoVer3, RespPush{[]byte("invalidate"), keys}))
+		}
+		return
+	}
+	if c.RespProtoVer() >= RespProtoVer3 {
+		c.push(AppendReply(nil, RespProtoVer3, RespPush{[]byte("tracking-redir-broken"), redcon.SimpleInt(opts.redirect)}))
+	}
+}
+
+// trackingRedirectExists check the redirect client whether exists
+func (s *RespCmdService) trackingRedirectExists(id int64) bool {
+	return s.pubSub.subscriberByID(id) != nil || s.respCmdConnByID(id) != nil
+}
+
+// connPusher writer of the push msgs (e.g. invalidation) to the conn out of the cmd reply flow,
+// the msgs are ordered with the replies: they're appended to the conn writer while the conn serves cmds,
+// otherwise written to the net conn asynchronously
+type connPusher struct {
+	netConn net.Conn
+	mu      sync.Mutex
+	cond    *sync.Cond
+	msgs    [][]byte
+	// the conn serves cmds, the replies are buffered in the conn writer
+	serving bool
+	// the msgs are written to the net conn out of the lock
+	writing bool
+	closed  bool
+}
+
+func newConnPusher(netConn net.Conn) *connPusher {
+	p := &connPusher{netConn: netConn}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// send queue the msg without blocking, the slow conn is disconnected
+func (p *connPusher) send(b []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	if len(p.msgs) >= connPusherBufferSize {
+		klog.Warnf("conn %s is too slow to push msgs, disconnect", p.netConn.RemoteAddr())
+		p.netConn.Close()
+		p.closed = true
+		p.msgs = nil
+		return
+	}
+	p.msgs = append(p.msgs, b)
+	if !p.serving && !p.writing {
+		p.writing = true
+		go p.writeLoop()
+	}
+}
+
+// writeLoop write the queued msgs to the net conn until the conn serves cmds
+func (p *connPusher) writeLoop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for !p.closed && !p.serving && len(p.msgs) > 0 {
+		msgs := p.msgs
+		p.msgs = nil
+		p.mu.Unlock()
+		_, err := p.netConn.Write(bytes.Join(msgs, nil))
+		p.mu.Lock()
+		if err != nil {
+			p.closed = true
+			p.msgs = nil
+		}
+	}
+	p.writing = false
+	p.cond.Broadcast()
+}
+
+// begin the conn serves the cmd, the queued msgs are written to the conn writer before the reply
+func (p *connPusher) begin(wr *redcon.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.serving = true
+	for p.writing {
+		p.cond.Wait()
+	}
+	p.writeTo(wr)
+}
+
+// end the conn has served the pipelined cmds, the queued msgs are flushed after the replies
+func (p *connPusher) end(wr *redcon.Writer) {
+	p.mu.Lock()
+	p.writeTo(wr)
+	p.mu.Unlock()
+	// the msgs sent while flushing are queued after the replies
+	wr.Flush()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.serving = false
+	if !p.closed && !p.writing && len(p.msgs) > 0 {
+		p.writing = true
+		go p.writeLoop()
+	}
+}
+
+func (p *connPusher) writeTo(wr *redcon.Writer) {
+	for _, b := range p.msgs {
+		wr.WriteRaw(b)
+	}
+	p.msgs = nil
+}
+
+func (p *connPusher) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.msgs = nil
+}
+
+// push push the msg to the conn in order with the replies,
+// the msg is dropped if the conn hasn't served any cmd
+func (c *RespCmdConn) push(b []byte) {
+	c.pusherMu.Lock()
+	p := c.pusher
+	c.pusherMu.Unlock()
+	if p != nil {
+		p.send(b)
+	}
+}
+
+// beginPush the conn serves the cmd with the writer, the pusher is started on the first cmd
+func (c *RespCmdConn) beginPush(wr *redcon.Writer) {
+	if wr == nil {
+		return
+	}
+	c.pusherMu.Lock()
+	if c.pusher == nil && !c.pusherClosed && c.Conn != nil && c.Conn.NetConn() != nil {
+		c.pusher = newConnPusher(c.Conn.NetConn())
+	}
+	p := c.pusher
+	c.pusherMu.Unlock()
+	if p != nil {
+		p.begin(wr)
+	}
+}
+
+// endPush flush the replies and pushes after the last cmd of the pipeline,
+// the writer of detached or closed conn isn't owned by the cmd flow
+func (c *RespCmdConn) endPush(wr *redcon.Writer) {
+	if wr == nil || c.detached || c.Closed() || len(c.Conn.PeekPipeline()) > 0 {
+		return
+	}
+	c.pusherMu.Lock()
+	p := c.pusher
+	c.pusherMu.Unlock()
+	if p != nil {
+		p.end(wr)
+	}
+}
+
+// closePusher stop pushing msgs to the conn which is closed or detached
+func (c *RespCmdConn) closePusher() {
+	c.pusherMu.Lock()
+	defer c.pusherMu.Unlock()
+	c.pusherClosed = true
+	if c.pusher != nil {
+		c.pusher.close()
+	}
+}
+
+// trackingInfo CLIENT TRACKINGINFO reply
+func (c *RespCmdConn) trackingInfo() RespMap {
+	opts := c.tracking.Load()
+	if opts == nil {
+		return RespMap{
+			[]byte("flags"), RespSet{[]byte("off")},
+			[]byte("redirect"), redcon.SimpleInt(-1),
+			[]byte("prefixes"), []any{},
+		}
+	}
+
+	flags := RespSet{[]byte("on")}
+	if opts.bcast {
+		flags = append(flags, []byte("bcast"))
+	}
+	if opts.optin {
+		flags = append(flags, []byte("optin"))
+		if c.trackingCaching == clientCachingYes {
+			flags = append(flags, []byte("caching-yes"))
+		}
+	}
+	if opts.optout {
+		flags = append(flags, []byte("optout"))
+		if c.trackingCaching == clientCachingNo {
+			flags = append(flags, []byte("caching-no"))
+		}
+	}
+	if opts.noloop {
+		flags = append(flags, []byte("noloop"))
+	}
+	if opts.redirect > 0 && !c.srv.trackingRedirectExists(opts.redirect) {
+		flags = append(flags, []byte("broken_redirect"))
+	}
+	prefixes := append([]string{}, opts.prefixes...)
+	sort.Strings(prefixes)
+	data := make([]any, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		data = append(data, []byte(prefix))
+	}
+	return RespMap{
+		[]byte("flags"), flags,
+		[]byte("redirect"), redcon.SimpleInt(opts.redirect),
+		[]byte("prefixes"), data,
+	}
+}
@@ -0,0 +1,74 @@
+package standalone
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/tidwall/redcon"
+)
+
+// parseUnixSocketPerm parse octal permission of unix socket file, e.g. 770
+func parseUnixSocketPerm(perm string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(perm, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid unix socket perm %q", perm)
+	}
+	return os.FileMode(mode), nil
+}
+
+// startUnixServer serve the RESP cmds on unix socket,
+// the stale socket file left by the last run is removed before listen
+func (s *RespCmdService) startUnixServer() (err error) {
+	perm := os.FileMode(0)
+	if len(s.opts.UnixSocketPerm) > 0 {
+		if perm, err = parseUnixSocketPerm(s.opts.UnixSocketPerm); err != nil {
+			return
+		}
+	}
+	if fi, statErr := os.Lstat(s.opts.UnixSocket); statErr == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("unix socket path %s is not a socket", s.opts.UnixSocket)
+		}
+		if err = os.Remove(s.opts.UnixSocket); err != nil {
+			return
+		}
+	}
+
+	s.redconUnixSrv = redcon.NewServerNetwork("unix", s.opts.UnixSocket, s.ServeRESP, s.onAccept, s.onClosed)
+	if idleClose := s.idleClose(); idleClose > 0 {
+		s.redconUnixSrv.SetIdleClose(idleClose)
+	}
+	if err = listenServe(s.redconUnixSrv); err != nil {
+		return
+	}
+	if perm > 0 {
+		if err = os.Chmod(s.opts.UnixSocket, perm); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// closeUnixServer close the unix socket server and remove the socket file
+func (s *RespCmdService) closeUnixServer() (err error) {
+	if s.redconUnixSrv == nil {
+		return
+	}
+	err = s.redconUnixSrv.Close()
+	s.redconUnixSrv = nil
+	if rmErr := os.Remove(s.opts.UnixSocket); rmErr != nil && !os.IsNotExist(rmErr) && err == nil {
+		err = rmErr
+	}
+	return
+}
+
+// isUnixConn check the conn whether is from unix socket
+func isUnixConn(conn redcon.Conn) bool {
+	if conn == nil || conn.NetConn() == nil {
+		return false
+	}
+	_, ok := conn.NetConn().LocalAddr().(*net.UnixAddr)
+	return ok
+}
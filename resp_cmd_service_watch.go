@@ -0,0 +1,85 @@
+package standalone
+
+type watchedKey struct {
+	db  int
+	key string
+}
+
+// watchKeys conn watch keys for transaction check-and-set
+func (s *RespCmdService) watchKeys(c *RespCmdConn, db int, keys ...[]byte) {
+	s.wkm.Lock()
+	defer s.wkm.Unlock()
+
+	for _, key := range keys {
+		wk := watchedKey{db: db, key: string(key)}
+		conns, ok := s.watchedKeys[wk]
+		if !ok {
+			conns = map[*RespCmdConn]struct{}{}
+			s.watchedKeys[wk] = conns
+		}
+		if _, ok := conns[c]; ok {
+			continue
+		}
+		conns[c] = struct{}{}
+		c.watchedKeys = append(c.watchedKeys, wk)
+	}
+}
+
+// unwatchKeys conn unwatch all watched keys, reset dirty cas flag
+func (s *RespCmdService) unwatchKeys(c *RespCmdConn) {
+	s.wkm.Lock()
+	defer s.wkm.Unlock()
+
+	for _, wk := range c.watchedKeys {
+		conns, ok := s.watchedKeys[wk]
+		if !ok {
+			continue
+		}
+		delete(conns, c)
+		if len(conns) == 0 {
+			delete(s.watchedKeys, wk)
+		}
+	}
+	c.watchedKeys = nil
+	c.dirtyCAS.Store(false)
+}
+
+// touchWatchedKeys keys are modified by conn c,
+// flag the other conns which watched the keys as dirty
+func (s *RespCmdService) touchWatchedKeys(c *RespCmdConn, db int, keys ...[]byte) {
+	if len(keys) == 0 {
+		return
+	}
+
+	s.wkm.Lock()
+	defer s.wkm.Unlock()
+
+	if len(s.watchedKeys) == 0 {
+		return
+	}
+	for _, key := range keys {
+		for conn := range s.watchedKeys[watchedKey{db: db, key: string(key)}] {
+			if conn != c {
+				conn.dirtyCAS.Store(true)
+			}
+		}
+	}
+}
+
+// touchWatchedDb db is flushed by conn c, db<0 means all dbs,
+// flag the other conns which watched the keys in db as dirty
+func (s *RespCmdService) touchWatchedDb(c *RespCmdConn, db int) {
+	s.wkm.Lock()
+	defer s.wkm.Unlock()
+
+	for wk, conns := range s.watchedKeys {
+		if db >= 0 && wk.db != db {
+			continue
+		}
+		for conn := range conns {
+			if conn != c {
+				conn.dirtyCAS.Store(true)
+			}
+		}
+	}
+}
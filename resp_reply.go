@@ -0,0 +1,204 @@
+package standalone
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/tidwall/redcon"
+)
+
+// resp protocol more detail reference:
+// https://github.com/redis/redis-specification/blob/master/protocol/RESP3.md
+
+// resp protocol versions, negotiated by HELLO
+const (
+	RespProtoVer2 = 2
+	RespProtoVer3 = 3
+)
+
+// reply types which are written as RESP3 types to RESP3 connection,
+// and are downgraded to RESP2 types for RESP2 connection
+type (
+	// RespMap map reply with ordered pairs k1, v1, k2, v2 ...; RESP2 array
+	RespMap []any
+	// RespSet set reply; RESP2 array
+	RespSet []any
+	// RespDouble double reply; RESP2 bulk string
+	RespDouble float64
+	// RespBool boolean reply; RESP2 integer 1 or 0
+	RespBool bool
+	// RespPush out of band push reply, e.g. pub/sub message; RESP2 array
+	RespPush []any
+)
+
+// bytesSet members to set reply
+func bytesSet(members [][]byte) RespSet {
+	set := make(RespSet, 0, len(members))
+	for _, member := range members {
+		set = append(set, member)
+	}
+	return set
+}
+
+// writeReply write cmd handle reply to conn with the resp protocol version,
+// top level int64 is written as integer, return the written bytes num
+func writeReply(conn redcon.Conn, protoVer int, res interface{}) int {
+	var b []byte
+	switch {
+	case isInt64(res):
+		b = redcon.AppendInt(nil, res.(int64))
+	case protoVer < RespProtoVer3 && !hasRespType(res):
+		b = redcon.AppendAny(nil, res)
+	default:
+		b = AppendReply(nil, protoVer, res)
+	}
+	conn.WriteRaw(b)
+	return len(b)
+}
+
+func isInt64(v interface{}) bool {
+	_, ok := v.(int64)
+	return ok
+}
+
+// hasRespType check the reply whether is (or has) RESP3 reply type
+func hasRespType(v interface{}) bool {
+	switch v := v.(type) {
+	case RespMap, RespSet, RespDouble, RespBool, RespPush:
+		return true
+	case []any:
+		for _, item := range v {
+			if hasRespType(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AppendReply append reply to b with the resp protocol version,
+// RESP2 output is the same as redcon.AppendAny except RESP3 reply types
+func AppendReply(b []byte, protoVer int, v interface{}) []byte {
+	resp3 := protoVer >= RespProtoVer3
+	switch v := v.(type) {
+	case RespMap:
+		if resp3 {
+			b = appendPrefix(b, '%', len(v)/2)
+		} else {
+			b = redcon.AppendArray(b, len(v))
+		}
+		for _, item := range v {
+			b = AppendReply(b, protoVer, item)
+		}
+		return b
+	case RespSet:
+		if resp3 {
+			b = appendPrefix(b, '~', len(v))
+		} else {
+			b = redcon.AppendArray(b, len(v))
+		}
+		for _, item := range v {
+			b = AppendReply(b, protoVer, item)
+		}
+		return b
+	case RespPush:
+		if resp3 {
+			b = appendPrefix(b, '>', len(v))
+		} else {
+			b = redcon.AppendArray(b, len(v))
+		}
+		for _, item := range v {
+			b = AppendReply(b, protoVer, item)
+		}
+		return b
+	case RespDouble:
+		if resp3 {
+			b = append(b, ',')
+			b = append(b, formatDouble(float64(v))...)
+			return append(b, '\r', '\n')
+		}
+		return redcon.AppendBulkString(b, formatDouble(float64(v)))
+	case RespBool:
+		if resp3 {
+			if v {
+				return append(b, "#t\r\n"...)
+			}
+			return append(b, "#f\r\n"...)
+		}
+		if v {
+			return redcon.AppendInt(b, 1)
+		}
+		return redcon.AppendInt(b, 0)
+	case []any:
+		b = redcon.AppendArray(b, len(v))
+		for _, item := range v {
+			b = AppendReply(b, protoVer, item)
+		}
+		return b
+	}
+
+	if !resp3 {
+		return redcon.AppendAny(b, v)
+	}
+
+	// RESP3 null, double and map
+	switch v := v.(type) {
+	case nil:
+		return append(b, "_\r\n"...)
+	case []byte:
+		if v == nil {
+			return append(b, "_\r\n"...)
+		}
+	case float32:
+		return AppendReply(b, protoVer, RespDouble(v))
+	case float64:
+		return AppendReply(b, protoVer, RespDouble(v))
+	case redcon.Marshaler:
+		return redcon.AppendAny(b, v)
+	default:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Slice:
+			n := rv.Len()
+			b = redcon.AppendArray(b, n)
+			for i := 0; i < n; i++ {
+				b = AppendReply(b, protoVer, rv.Index(i).Interface())
+			}
+			return b
+		case reflect.Map:
+			// string keys are sorted as redcon.AppendAny
+			keys := rv.MapKeys()
+			if len(keys) > 0 && keys[0].Kind() == reflect.String {
+				sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+			}
+			b = appendPrefix(b, '%', len(keys))
+			for _, key := range keys {
+				b = AppendReply(b, protoVer, key.Interface())
+				b = AppendReply(b, protoVer, rv.MapIndex(key).Interface())
+			}
+			return b
+		}
+	}
+
+	return redcon.AppendAny(b, v)
+}
+
+func appendPrefix(b []byte, c byte, n int) []byte {
+	b = append(b, c)
+	b = strconv.AppendInt(b, int64(n), 10)
+	return append(b, '\r', '\n')
+}
+
+func formatDouble(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
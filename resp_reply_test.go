@@ -0,0 +1,31 @@
+package standalone
+
+import (
+	"testing"
+
+	"github.com/tidwall/redcon"
+)
+
+func TestAppendReply(t *testing.T) {
+	tests := []struct {
+		res   interface{}
+		resp2 string
+		resp3 string
+	}{
+		{nil, "$-1\r\n", "_\r\n"},
+		{RespMap{"k", []byte("v")}, "*2\r\n$1\r\nk\r\n$1\r\nv\r\n", "%1\r\n$1\r\nk\r\n$1\r\nv\r\n"},
+		{RespSet{"m"}, "*1\r\n$1\r\nm\r\n", "~1\r\n$1\r\nm\r\n"},
+		{RespDouble(1.5), "$3\r\n1.5\r\n", ",1.5\r\n"},
+		{RespBool(true), ":1\r\n", "#t\r\n"},
+		{RespPush{"message"}, "*1\r\n$7\r\nmessage\r\n", ">1\r\n$7\r\nmessage\r\n"},
+		{[]any{redcon.SimpleInt(1), nil}, "*2\r\n:1\r\n$-1\r\n", "*2\r\n:1\r\n_\r\n"},
+	}
+	for _, tt := range tests {
+		if resp := string(AppendReply(nil, RespProtoVer2, tt.res)); resp != tt.resp2 {
+			t.Errorf("RESP2 reply %v: %q, want %q", tt.res, resp, tt.resp2)
+		}
+		if resp := string(AppendReply(nil, RespProtoVer3, tt.res)); resp != tt.resp3 {
+			t.Errorf("RESP3 reply %v: %q, want %q", tt.res, resp, tt.resp3)
+		}
+	}
+}
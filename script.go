@@ -0,0 +1,423 @@
+package standalone
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/kitex/pkg/klog"
+	"github.com/tidwall/redcon"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// lua script more detail reference:
+// https://redis.io/docs/interact/programmability/eval-intro/
+// https://redis.io/docs/interact/programmability/lua-api/
+
+// lua libs which are opened in script lua state,
+// io/os/package/debug are not opened for safety
+var scriptLuaLibs = []struct {
+	name string
+	open lua.LGFunction
+}{
+	{lua.BaseLibName, lua.OpenBase},
+	{lua.TabLibName, lua.OpenTable},
+	{lua.StringLibName, lua.OpenString},
+	{lua.MathLibName, lua.OpenMath},
+}
+
+// Scripts lua script cache keyed by sha1 and the running script state
+type Scripts struct {
+	// mutex lock for cache
+	mu sync.RWMutex
+	// sha1 hex -> compiled script
+	cache map[string]*lua.FunctionProto
+
+	// mutex lock for running script state
+	rm sync.Mutex
+	// running script cancel func, nil if no script is running
+	cancel context.CancelFunc
+	// running script has called write cmd
+	wrote bool
+	// running script is killed by SCRIPT KILL
+	killed bool
+}
+
+func NewScripts() *Scripts {
+	return &Scripts{cache: map[string]*lua.FunctionProto{}}
+}
+
+// ScriptSha1 return sha1 hex of script body
+func ScriptSha1(body string) string {
+	sum := sha1.Sum([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// Load compile the script and add to cache, return sha1 hex
+func (s *Scripts) Load(body string) (sha string, proto *lua.FunctionProto, err error) {
+	sha = ScriptSha1(body)
+	if proto = s.Get(sha); proto != nil {
+		return
+	}
+
+	chunk, err := parse.Parse(strings.NewReader(body), "user_script")
+	if err != nil {
+		err = fmt.Errorf("ERR Error compiling script (new function): %s", strings.ReplaceAll(err.Error(), "\n", " "))
+		return
+	}
+	proto, err = lua.Compile(chunk, "user_script")
+	if err != nil {
+		err = fmt.Errorf("ERR Error compiling script (new function): %s", strings.ReplaceAll(err.Error(), "\n", " "))
+		return
+	}
+
+	s.mu.Lock()
+	s.cache[sha] = proto
+	s.mu.Unlock()
+	return
+}
+
+func (s *Scripts) Get(sha string) *lua.FunctionProto {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cache[strings.ToLower(sha)]
+}
+
+func (s *Scripts) Exists(sha string) bool {
+	return s.Get(sha) != nil
+}
+
+func (s *Scripts) Flush() {
+	s.mu.Lock()
+	s.cache = map[string]*lua.FunctionProto{}
+	s.mu.Unlock()
+}
+
+// Kill kill the running script which hasn't called write cmd
+func (s *Scripts) Kill() error {
+	s.rm.Lock()
+	defer s.rm.Unlock()
+
+	if s.cancel == nil {
+		return ErrScriptNotBusy
+	}
+	if s.wrote {
+		return ErrScriptUnkillable
+	}
+	s.killed = true
+	s.cancel()
+	return nil
+}
+
+func (s *Scripts) setRunning(cancel context.CancelFunc) {
+	s.rm.Lock()
+	s.cancel, s.wrote, s.killed = cancel, false, false
+	s.rm.Unlock()
+}
+
+func (s *Scripts) resetRunning() (killed bool) {
+	s.rm.Lock()
+	killed = s.killed
+	s.cancel, s.wrote, s.killed = nil, false, false
+	s.rm.Unlock()
+	return
+}
+
+func (s *Scripts) setWrote() {
+	s.rm.Lock()
+	s.wrote = true
+	s.rm.Unlock()
+}
+
+// runScript run the compiled script with keys and args in a new lua state,
+// redis.call/redis.pcall dispatch to the registered cmd handles on conn c.
+// the caller must hold srv cmd write lock for atomicity
+func (c *RespCmdConn) runScript(ctx context.Context, proto *lua.FunctionProto, keys, args [][]byte) (res interface{}, err error) {
+	scripts := c.srv.scripts
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	scripts.setRunning(cancel)
+	defer func() {
+		if scripts.resetRunning() {
+			res, err = nil, ErrScriptKilled
+		}
+	}()
+
+	// cmd SELECT in script doesn't affect the caller connection
+	db, dbIdx, dbSwaps := c.Db(), c.dbIdx, c.dbSwaps
+	defer func() {
+		c.SetDb(db)
+		c.setDbIndex(dbIdx)
+		// the caller db is reselected if the dbs are swapped by the script
+		c.dbSwaps = dbSwaps
+		c.reselectDB(ctx)
+	}()
+
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer L.Close()
+	for _, lib := range scriptLuaLibs {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range []string{"dofile", "loadfile", "load", "loadstring", "require", "module"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+	L.SetGlobal("KEYS", luaStringArray(L, keys))
+	L.SetGlobal("ARGV", luaStringArray(L, args))
+	L.SetGlobal("redis", c.luaRedisLib(ctx, L))
+	L.SetContext(ctx)
+
+	L.Push(L.NewFunctionFromProto(proto))
+	if err = L.PCall(0, 1, nil); err != nil {
+		var apiErr *lua.ApiError
+		if errors.As(err, &apiErr) {
+			if errTbl, ok := apiErr.Object.(*lua.LTable); ok {
+				if e, ok := errTbl.RawGetString("err").(lua.LString); ok {
+					return nil, errors.New(string(e))
+				}
+			}
+			return nil, fmt.Errorf("ERR Error running script: %s", strings.ReplaceAll(apiErr.Object.String(), "\n", " "))
+		}
+		return nil, fmt.Errorf("ERR Error running script: %s", err.Error())
+	}
+
+	res = luaToReply(L.Get(-1))
+	L.Pop(1)
+	return
+}
+
+// luaRedisLib new redis lib table for script lua state
+func (c *RespCmdConn) luaRedisLib(ctx context.Context, L *lua.LState) *lua.LTable {
+	lib := L.NewTable()
+	L.SetFuncs(lib, map[string]lua.LGFunction{
+		"call": func(L *lua.LState) int {
+			v, err := c.luaCall(ctx, L)
+			if err != nil {
+				L.Error(luaErrorTable(L, err.Error()), 1)
+				return 0
+			}
+			L.Push(v)
+			return 1
+		},
+		"pcall": func(L *lua.LState) int {
+			v, err := c.luaCall(ctx, L)
+			if err != nil {
+				L.Push(luaErrorTable(L, err.Error()))
+				return 1
+			}
+			L.Push(v)
+			return 1
+		},
+		"error_reply": func(L *lua.LState) int {
+			msg := L.CheckString(1)
+			if !strings.HasPrefix(msg, "-") {
+				msg = "-" + msg
+			}
+			L.Push(luaErrorTable(L, msg[1:]))
+			return 1
+		},
+		"status_reply": func(L *lua.LState) int {
+			tbl := L.NewTable()
+			tbl.RawSetString("ok", lua.LString(L.CheckString(1)))
+			L.Push(tbl)
+			return 1
+		},
+		"sha1hex": func(L *lua.LState) int {
+			L.Push(lua.LString(ScriptSha1(L.CheckString(1))))
+			return 1
+		},
+		"log": func(L *lua.LState) int {
+			level := L.CheckInt(1)
+			msgs := make([]string, 0, L.GetTop()-1)
+			for i := 2; i <= L.GetTop(); i++ {
+				msgs = append(msgs, L.ToStringMeta(L.Get(i)).String())
+			}
+			msg := strings.Join(msgs, " ")
+			switch level {
+			case 0:
+				klog.Debugf("script: %s", msg)
+			case 1, 2:
+				klog.Infof("script: %s", msg)
+			default:
+				klog.Warnf("script: %s", msg)
+			}
+			return 0
+		},
+	})
+	lib.RawSetString("LOG_DEBUG", lua.LNumber(0))
+	lib.RawSetString("LOG_VERBOSE", lua.LNumber(1))
+	lib.RawSetString("LOG_NOTICE", lua.LNumber(2))
+	lib.RawSetString("LOG_WARNING", lua.LNumber(3))
+
+	return lib
+}
+
+// luaCall run the cmd of redis.call/redis.pcall args in lua state
+func (c *RespCmdConn) luaCall(ctx context.Context, L *lua.LState) (v lua.LValue, err error) {
+	argc := L.GetTop()
+	if argc == 0 {
+		return nil, errors.New("ERR Please specify at least one argument for this redis lib call")
+	}
+	args := make([][]byte, 0, argc)
+	for i := 1; i <= argc; i++ {
+		switch arg := L.Get(i).(type) {
+		case lua.LString:
+			args = append(args, []byte(arg))
+		case lua.LNumber:
+			// the same as %.17g of redis, e.g. 1.5 isn't truncated to 1
+			args = append(args, []byte(strconv.FormatFloat(float64(arg), 'g', 17, 64)))
+		default:
+			return nil, errors.New("ERR Lua redis lib command arguments must be strings or integers")
+		}
+	}
+
+	cmd := strings.ToLower(string(args[0]))
+	params := args[1:]
+	f, ok := c.srv.handles[cmd]
+	if !ok || f == nil {
+		return nil, errors.New("ERR Unknown Redis command called from script")
+	}
+	if cmdHasFlag(cmd, cmdFlagNoScript|cmdFlagBlocking) {
+		return nil, errors.New("ERR This Redis command is not allowed from script")
+	}
+	if err = checkCmdArity(cmd, params); err != nil {
+		c.srv.stats.cmdRejected(cmd)
+		return
+	}
+	if err = c.checkPerm(cmd, params); err != nil {
+		c.srv.stats.cmdRejected(cmd)
+		return
+	}
+
+	if cmdHasFlag(cmd, cmdFlagWrite) {
+		c.srv.scripts.setWrote()
+	}
+	c.srv.feedMonitors(c.dbIdx, "lua", args)
+	res, err := c.runCmd(ctx, c, cmd, f, params)
+	if err != nil {
+		return
+	}
+
+	v = replyToLua(L, res)
+	return
+}
+
+func luaErrorTable(L *lua.LState, msg string) *lua.LTable {
+	tbl := L.NewTable()
+	tbl.RawSetString("err", lua.LString(msg))
+	return tbl
+}
+
+func luaStringArray(L *lua.LState, items [][]byte) *lua.LTable {
+	tbl := L.CreateTable(len(items), 0)
+	for i, item := range items {
+		tbl.RawSetInt(i+1, lua.LString(item))
+	}
+	return tbl
+}
+
+// replyToLua convert cmd reply to lua value:
+// integer -> number, bulk string -> string, status -> {ok=...},
+// error -> {err=...}, array -> table, nil -> false
+func replyToLua(L *lua.LState, res interface{}) lua.LValue {
+	switch v := res.(type) {
+	case nil:
+		return lua.LFalse
+	case error:
+		return luaErrorTable(L, v.Error())
+	case redcon.SimpleString:
+		tbl := L.NewTable()
+		tbl.RawSetString("ok", lua.LString(v))
+		return tbl
+	case redcon.SimpleInt:
+		return lua.LNumber(v)
+	case RespBool:
+		if v {
+			return lua.LNumber(1)
+		}
+		return lua.LFalse
+	case RespDouble:
+		return lua.LString(formatDouble(float64(v)))
+	case []byte:
+		if v == nil {
+			return lua.LFalse
+		}
+		return lua.LString(v)
+	case string:
+		return lua.LString(v)
+	case bool:
+		if v {
+			return lua.LNumber(1)
+		}
+		return lua.LFalse
+	}
+
+	rv := reflect.ValueOf(res)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return lua.LNumber(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return lua.LNumber(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return lua.LString(strconv.FormatFloat(rv.Float(), 'f', -1, 64))
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return lua.LFalse
+		}
+		tbl := L.CreateTable(rv.Len(), 0)
+		for i := 0; i < rv.Len(); i++ {
+			tbl.RawSetInt(i+1, replyToLua(L, rv.Index(i).Interface()))
+		}
+		return tbl
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return lua.LFalse
+		}
+		return replyToLua(L, rv.Elem().Interface())
+	}
+
+	return lua.LString(fmt.Sprint(res))
+}
+
+// luaToReply convert lua value to cmd reply:
+// number -> integer (truncated), string -> bulk string, true -> boolean, false/nil -> nil,
+// {ok=...} -> status, {err=...} -> error, table -> array (stop at the first nil)
+func luaToReply(v lua.LValue) interface{} {
+	switch lv := v.(type) {
+	case lua.LNumber:
+		return redcon.SimpleInt(int64(lv))
+	case lua.LString:
+		return []byte(lv)
+	case lua.LBool:
+		if lv {
+			return RespBool(true)
+		}
+		return nil
+	case *lua.LTable:
+		if ok, isStr := lv.RawGetString("ok").(lua.LString); isStr {
+			return redcon.SimpleString(ok)
+		}
+		if e, isStr := lv.RawGetString("err").(lua.LString); isStr {
+			return errors.New(string(e))
+		}
+		data := []any{}
+		for i := 1; ; i++ {
+			item := lv.RawGetInt(i)
+			if item == lua.LNil {
+				break
+			}
+			data = append(data, luaToReply(item))
+		}
+		return data
+	}
+
+	return nil
+}
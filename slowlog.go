@@ -0,0 +1,141 @@
+package standalone
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// slow log more detail reference:
+// https://redis.io/commands/slowlog-get/
+
+const (
+	// max args num of slow log entry, the last arg is replaced with the more args num
+	SlowLogEntryMaxArgc = 32
+	// max arg len of slow log entry, the arg is truncated with the more bytes num
+	SlowLogEntryMaxArgLen = 128
+)
+
+// SlowLogEntry slow cmd entry
+type SlowLogEntry struct {
+	ID        int64
+	Timestamp time.Time
+	// cmd duration in microseconds
+	Duration   int64
+	Args       []string
+	ClientAddr string
+	ClientName string
+}
+
+// SlowLog bounded slow log, the oldest entry is dropped when it's full
+type SlowLog struct {
+	// log cmds slower than the microseconds, negative disables the slow log
+	slowerThan atomic.Int64
+	maxLen     atomic.Int64
+
+	mu     sync.Mutex
+	lastID int64
+	// entries from the oldest to the newest
+	entries []*SlowLogEntry
+}
+
+func NewSlowLog(slowerThan int64, maxLen int) *SlowLog {
+	sl := &SlowLog{}
+	sl.SetConfig(slowerThan, maxLen)
+	return sl
+}
+
+// SetConfig set slow log threshold in microseconds and max len
+func (sl *SlowLog) SetConfig(slowerThan int64, maxLen int) {
+	sl.slowerThan.Store(slowerThan)
+	sl.maxLen.Store(int64(maxLen))
+
+	sl.mu.Lock()
+	sl.trim()
+	sl.mu.Unlock()
+}
+
+func (sl *SlowLog) trim() {
+	maxLen := int(sl.maxLen.Load())
+	if maxLen < 0 {
+		maxLen = 0
+	}
+	if over := len(sl.entries) - maxLen; over > 0 {
+		sl.entries = append(sl.entries[:0], sl.entries[over:]...)
+	}
+}
+
+// IsSlow check the cmd duration whether is over the threshold
+func (sl *SlowLog) IsSlow(duration time.Duration) bool {
+	slowerThan := sl.slowerThan.Load()
+	return slowerThan >= 0 && duration.Microseconds() >= slowerThan
+}
+
+// Add add the cmd to slow log if the cmd duration is over the threshold,
+// the sensitive args are redacted
+func (sl *SlowLog) Add(startTime time.Time, duration time.Duration, args [][]byte, clientAddr, clientName string) {
+	if !sl.IsSlow(duration) {
+		return
+	}
+
+	args = redactArgs(args)
+	argc := len(args)
+	if argc > SlowLogEntryMaxArgc {
+		argc = SlowLogEntryMaxArgc
+	}
+	entryArgs := make([]string, 0, argc)
+	for i := 0; i < argc; i++ {
+		if i == SlowLogEntryMaxArgc-1 && len(args) > SlowLogEntryMaxArgc {
+			entryArgs = append(entryArgs, fmt.Sprintf("... (%d more arguments)", len(args)-SlowLogEntryMaxArgc+1))
+			break
+		}
+		arg := args[i]
+		if len(arg) > SlowLogEntryMaxArgLen {
+			entryArgs = append(entryArgs, fmt.Sprintf("%s... (%d more bytes)", arg[:SlowLogEntryMaxArgLen], len(arg)-SlowLogEntryMaxArgLen))
+			continue
+		}
+		entryArgs = append(entryArgs, string(arg))
+	}
+
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	entry := &SlowLogEntry{
+		ID:         sl.lastID,
+		Timestamp:  startTime,
+		Duration:   duration.Microseconds(),
+		Args:       entryArgs,
+		ClientAddr: clientAddr,
+		ClientName: clientName,
+	}
+	sl.lastID++
+	sl.entries = append(sl.entries, entry)
+	sl.trim()
+}
+
+// Get get the newest n entries, n < 0 means all
+func (sl *SlowLog) Get(n int) []*SlowLogEntry {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	if n < 0 || n > len(sl.entries) {
+		n = len(sl.entries)
+	}
+	entries := make([]*SlowLogEntry, 0, n)
+	for i := len(sl.entries) - 1; i >= len(sl.entries)-n; i-- {
+		entries = append(entries, sl.entries[i])
+	}
+	return entries
+}
+
+func (sl *SlowLog) Len() int {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	return len(sl.entries)
+}
+
+func (sl *SlowLog) Reset() {
+	sl.mu.Lock()
+	sl.entries = nil
+	sl.mu.Unlock()
+}
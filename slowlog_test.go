@@ -0,0 +1,45 @@
+package standalone
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSlowLog(t *testing.T) {
+	sl := NewSlowLog(1000, 2)
+	now := time.Now()
+
+	sl.Add(now, time.Microsecond, [][]byte{[]byte("get"), []byte("k")}, "", "")
+	if sl.Len() != 0 {
+		t.Fatalf("fast cmd is logged")
+	}
+
+	args := [][]byte{[]byte("mset"), bytes.Repeat([]byte("k"), SlowLogEntryMaxArgLen+1)}
+	for i := 0; i < SlowLogEntryMaxArgc; i++ {
+		args = append(args, []byte("v"))
+	}
+	for i := 0; i < 3; i++ {
+		sl.Add(now, time.Second, args, "127.0.0.1:6666", "conn1")
+	}
+	entries := sl.Get(-1)
+	if len(entries) != 2 || entries[0].ID != 2 || entries[1].ID != 1 {
+		t.Fatalf("slow log entries len: %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Duration != 1000000 || len(entry.Args) != SlowLogEntryMaxArgc ||
+		entry.Args[1] != string(args[1][:SlowLogEntryMaxArgLen])+"... (1 more bytes)" ||
+		entry.Args[SlowLogEntryMaxArgc-1] != "... (3 more arguments)" {
+		t.Fatalf("slow log entry: %+v", entry)
+	}
+
+	sl.Add(now, time.Second, [][]byte{[]byte("auth"), []byte("user"), []byte("pwd")}, "", "")
+	if entry := sl.Get(1)[0]; entry.Args[1] != "(redacted)" || entry.Args[2] != "(redacted)" {
+		t.Fatalf("slow log auth entry args: %v", entry.Args)
+	}
+
+	sl.Reset()
+	if sl.Len() != 0 {
+		t.Fatalf("slow log reset len: %d", sl.Len())
+	}
+}